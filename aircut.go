@@ -0,0 +1,67 @@
+package main
+
+import "fmt"
+
+// airCutMoves is the planned program traced at height z above the stock
+// instead of cut: every move is kept, with the tool never going below z,
+// so the operator can watch where the job will go without touching
+// material. Straight moves the clamping leaves going nowhere, such as
+// plunges, are dropped.
+func airCutMoves(moves []Move, z float64) []Move {
+	out := make([]Move, 0, len(moves))
+	var last Move
+	haveLast := false
+	for _, m := range moves {
+		m.Z = max(m.Z, z)
+		if m.Kind == MoveRapid || m.Kind == MoveFeed {
+			if haveLast && m.X == last.X && m.Y == last.Y && m.Z == last.Z {
+				continue
+			}
+		}
+		if m.isMotion() {
+			last, haveLast = m, true
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// withAirCut puts an air cut of body in front of moves, the program that
+// cuts it, with a stop in between. The real cut starts back over X0 Y0
+// at safe Z, where body was planned to start from.
+func withAirCut(body, moves []Move, cfg Config) []Move {
+	prog := newProgram(cfg.SafeZ)
+	prog.Raw("")
+	prog.Comment(cfg.Msg.T("aircut", cfg.AirCut))
+	prog.Moves = append(prog.Moves, airCutMoves(body, cfg.AirCut)...)
+	if cfg.AirCutOnly {
+		return prog.Moves
+	}
+	prog.Raw("")
+	prog.z = max(cfg.SafeZ, cfg.AirCut) // every program ends at safe Z
+	prog.Raw(fmt.Sprintf("M0  (%s)", cfg.Msg.T("aircut_done")))
+	if prog.z > cfg.SafeZ {
+		prog.RapidZ(cfg.SafeZ)
+	}
+	prog.RapidXY(0, 0)
+	return append(prog.Moves, moves...)
+}
+
+// traceBounds runs the tool around the XY extent of moves at safe Z, at
+// the plunge feed so it is easy to follow, and stops: the operator checks
+// that the stock covers the job and clears the clamps. The job then
+// starts back over X0 Y0, where it was planned to start from.
+func traceBounds(prog *Program, moves []Move, cfg Config) {
+	b := estimateMoves(moves, 0).Bounds
+	if b.Width() == 0 && b.Height() == 0 {
+		return
+	}
+	prog.Raw("")
+	prog.Comment(cfg.Msg.T("bounds", b.MinX, b.MinY, b.MaxX, b.MaxY))
+	prog.RapidXY(b.MinX, b.MinY)
+	for _, p := range []Point{{b.MaxX, b.MinY}, {b.MaxX, b.MaxY}, {b.MinX, b.MaxY}, {b.MinX, b.MinY}} {
+		prog.FeedXY(p.X, p.Y, cfg.PlungeFeed)
+	}
+	prog.Raw(fmt.Sprintf("M0  (%s)", cfg.Msg.T("bounds_done")))
+	prog.RapidXY(0, 0)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"slices"
+)
+
+// Circles and arcs reach the planner as polylines: a <circle> flattened
+// evenly, but a circle drawn as four Béziers, imported from DXF or
+// traced by an editor comes flattened unevenly, starting anywhere. The
+// classifier tells whether such a polyline still is a circle or an arc,
+// and which, so holes are found however the circle was drawn.
+
+// Arc is a circle, or part of one, that a path follows.
+type Arc struct {
+	Center Point
+	R      float64
+	Start  float64 // angle of the first point, radians
+	Sweep  float64 // signed angle turned, counter-clockwise positive; ±2π for a circle
+}
+
+// full reports whether the arc goes all the way around.
+func (a Arc) full() bool { return math.Abs(a.Sweep) > 2*math.Pi-1e-6 }
+
+// maxArcStep is the most a single edge of an arc may turn: coarser
+// polylines are polygons, whatever their vertices lie on.
+const maxArcStep = math.Pi / 4
+
+// fitArc classifies points as a circular arc, or with closed as a full
+// circle. The circle is fitted by least squares, so unevenly spaced
+// vertices don't pull it off centre; every vertex must lie within tol of
+// it, every edge stray no further than flat from it (the flattening
+// tolerance the polyline was made with), and the points must turn one
+// way only.
+func fitArc(points []Point, closed bool, tol, flat float64) (Arc, bool) {
+	pts := points
+	if closed && len(pts) > 1 && almostEqualPoint(pts[0], pts[len(pts)-1]) {
+		pts = pts[:len(pts)-1]
+	}
+	if len(pts) < 3 || closed && len(pts) < 6 {
+		return Arc{}, false
+	}
+	c, r, ok := leastSquaresCircle(pts)
+	if !ok {
+		return Arc{}, false
+	}
+	for _, p := range pts {
+		if math.Abs(math.Hypot(p.X-c.X, p.Y-c.Y)-r) > tol {
+			return Arc{}, false
+		}
+	}
+
+	edges := len(pts) - 1
+	if closed {
+		edges = len(pts)
+	}
+	start := math.Atan2(pts[0].Y-c.Y, pts[0].X-c.X)
+	sweep, prev := 0.0, start
+	for i := 1; i <= edges; i++ {
+		p := pts[i%len(pts)]
+		a := math.Atan2(p.Y-c.Y, p.X-c.X)
+		step := math.Remainder(a-prev, 2*math.Pi)
+		if math.Abs(step) > maxArcStep || step == 0 || sweep != 0 && (step > 0) != (sweep > 0) {
+			return Arc{}, false
+		}
+		chord := math.Hypot(p.X-pts[i-1].X, p.Y-pts[i-1].Y)
+		if sag := r - math.Sqrt(max(r*r-chord*chord/4, 0)); sag > max(flat, tol) {
+			return Arc{}, false
+		}
+		sweep += step
+		prev = a
+	}
+	if closed && math.Abs(math.Abs(sweep)-2*math.Pi) > 1e-6 {
+		return Arc{}, false // turned round more than once
+	}
+	if !closed && math.Abs(sweep) > 2*math.Pi {
+		return Arc{}, false
+	}
+	return Arc{Center: c, R: r, Start: start, Sweep: sweep}, true
+}
+
+// leastSquaresCircle fits the circle x² + y² + Dx + Ey + F = 0 that best
+// matches pts algebraically, working about their mean for precision.
+func leastSquaresCircle(pts []Point) (Point, float64, bool) {
+	var m Point
+	for _, p := range pts {
+		m.X += p.X
+		m.Y += p.Y
+	}
+	m.X /= float64(len(pts))
+	m.Y /= float64(len(pts))
+
+	// normal equations of the linear system in D, E, F
+	var sxx, sxy, syy, sx, sy, sxz, syz, sz float64
+	for _, p := range pts {
+		x, y := p.X-m.X, p.Y-m.Y
+		z := x*x + y*y
+		sxx += x * x
+		sxy += x * y
+		syy += y * y
+		sx += x
+		sy += y
+		sxz += x * z
+		syz += y * z
+		sz += z
+	}
+	n := float64(len(pts))
+	a := [3][4]float64{
+		{sxx, sxy, sx, -sxz},
+		{sxy, syy, sy, -syz},
+		{sx, sy, n, -sz},
+	}
+	for col := range 3 {
+		piv := col
+		for row := col + 1; row < 3; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[piv][col]) {
+				piv = row
+			}
+		}
+		if math.Abs(a[piv][col]) < 1e-12 {
+			return Point{}, 0, false // collinear
+		}
+		a[col], a[piv] = a[piv], a[col]
+		for row := range 3 {
+			if row != col {
+				f := a[row][col] / a[col][col]
+				for k := col; k < 4; k++ {
+					a[row][k] -= f * a[col][k]
+				}
+			}
+		}
+	}
+	d, e, f := a[0][3]/a[0][0], a[1][3]/a[1][1], a[2][3]/a[2][2]
+	cx, cy := -d/2, -e/2
+	r2 := cx*cx + cy*cy - f
+	if r2 <= 0 || math.IsNaN(r2) {
+		return Point{}, 0, false
+	}
+	return Point{X: cx + m.X, Y: cy + m.Y}, math.Sqrt(r2), true
+}
+
+// arcRun finds the longest stretch of pts from pts[i] that is one arc of
+// a radius in radii, each within tol. It returns the index of the arc's
+// last point, at least i+2, with the arc, or i if no arc starts there.
+func arcRun(pts []Point, i int, radii []float64, tol float64) (int, Arc) {
+	end, best := i, Arc{}
+	for j := i + 2; j < len(pts); j++ {
+		a, ok := fitArc(pts[i:j+1], false, tol, 2*tol)
+		if ok {
+			ok = slices.ContainsFunc(radii, func(r float64) bool { return math.Abs(a.R-r) <= tol })
+		}
+		if !ok {
+			break
+		}
+		end, best = j, a
+	}
+	return end, best
+}
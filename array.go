@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cutting many parts in one run: -array repeats the job in a grid, and
+// -nest packs several drawings side by side on one sheet of stock.
+
+// parseGrid parses "CxR" (e.g. "3x2") into positive column and row
+// counts.
+func parseGrid(s string) (cols, rows int, err error) {
+	c, r, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if ok {
+		cols, err = strconv.Atoi(strings.TrimSpace(c))
+		if err == nil {
+			rows, err = strconv.Atoi(strings.TrimSpace(r))
+		}
+	}
+	if !ok || err != nil || cols < 1 || rows < 1 {
+		return 0, 0, fmt.Errorf("invalid grid %q (want CxR, e.g. 3x2)", s)
+	}
+	return cols, rows, nil
+}
+
+// arrayPaths repeats the job cols times to the right and rows times up,
+// gap mm apart between the bounding boxes of the copies. Each copy's
+// shapes get their own numbers, so compensation and fills see the
+// copies as separate parts.
+func arrayPaths(paths []Path, cols, rows int, gap float64) []Path {
+	b, ok := pathBounds(paths)
+	if !ok || cols*rows <= 1 {
+		return paths
+	}
+	shapes := 0
+	for _, p := range paths {
+		shapes = max(shapes, p.Shape)
+	}
+	out := make([]Path, 0, len(paths)*cols*rows)
+	for r := range rows {
+		for c := range cols {
+			k := r*cols + c
+			dx, dy := float64(c)*(b.Width()+gap), float64(r)*(b.Height()+gap)
+			for _, p := range paths {
+				pts := make([]Point, len(p.Points))
+				for i, pt := range p.Points {
+					pts[i] = Point{X: pt.X + dx, Y: pt.Y + dy}
+				}
+				p.Points = pts
+				if p.Shape != 0 {
+					p.Shape += k * shapes
+				}
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// nestOffsets loads every input once to measure its job and returns how
+// far to move each so they sit side by side on a w x h sheet from the
+// origin, gap mm apart: tallest first, in rows along X, a new row when
+// one is full. An input that doesn't fit is an error.
+func (b Batch) nestOffsets(cfg Config) ([]Point, error) {
+	type part struct {
+		i int
+		r Rect
+	}
+	parts := make([]part, len(b.Inputs))
+	for i, in := range b.Inputs {
+		if in == "-" {
+			return nil, fmt.Errorf("-nest reads every input twice; it cannot read stdin")
+		}
+		f, err := openInput(in)
+		if err != nil {
+			return nil, err
+		}
+		c := cfg
+		c.Warn = &Warnings{Out: io.Discard} // reported when the file is converted
+		paths, c, err := loadInput(f, in, filepath.Dir(in), c, b.InFormat, b.FlipY)
+		f.Close()
+		if err == nil {
+			paths, err = preparePaths(paths, c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", in, err)
+		}
+		r, ok := pathBounds(paths)
+		if !ok {
+			r = Rect{} // nothing to cut takes no room
+		}
+		parts[i] = part{i, r}
+	}
+	sort.SliceStable(parts, func(a, b int) bool { return parts[a].r.Height() > parts[b].r.Height() })
+
+	offsets := make([]Point, len(parts))
+	x, y, rowH := 0.0, 0.0, 0.0
+	for _, p := range parts {
+		w, h := p.r.Width(), p.r.Height()
+		if x > 0 && x+w > b.NestW {
+			x, y, rowH = 0, y+rowH+cfg.Spacing, 0
+		}
+		if x+w > b.NestW || y+h > b.NestH {
+			return nil, fmt.Errorf("%s: does not fit on the %gx%g mm stock with the parts before it", b.Inputs[p.i], b.NestW, b.NestH)
+		}
+		offsets[p.i] = Point{X: x - p.r.MinX, Y: y - p.r.MinY}
+		x += w + cfg.Spacing
+		rowH = max(rowH, h)
+	}
+	return offsets, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandInputs turns -in and the remaining command-line arguments into
+// input files. Arguments with glob characters are expanded (sorted); a
+// pattern matching nothing is an error, not an empty job.
+func expandInputs(in string, args []string) ([]string, error) {
+	var files []string
+	for _, a := range append([]string{in}, args...) {
+		if a == "" {
+			continue
+		}
+		if !strings.ContainsAny(a, "*?[") {
+			files = append(files, a)
+			continue
+		}
+		m, err := filepath.Glob(a)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", a, err)
+		}
+		if len(m) == 0 {
+			return nil, fmt.Errorf("no files match %q", a)
+		}
+		files = append(files, m...)
+	}
+	return files, nil
+}
+
+// Batch converts many drawings with the same settings: one program per
+// input in OutDir, and/or all of them in one Combined program with a
+// stop for a tool or stock change between files. With NestW and NestH
+// set the drawings are laid out side by side on one sheet instead, and
+// the combined program runs through without stopping.
+type Batch struct {
+	Inputs   []string
+	InFormat string // -informat
+	FlipY    string // -flip-y
+	OutDir   string // "" = no per-file programs
+	Ext      string // extension of per-file programs, e.g. ".nc"
+	Write    func(io.Writer, []Path, Config) error
+	Combined string // "" = no combined program
+
+	NestW, NestH float64 // stock sheet to nest the inputs on, mm; 0 = no nesting
+}
+
+func (b Batch) Run(cfg Config) error {
+	if b.OutDir != "" {
+		if err := os.MkdirAll(b.OutDir, 0o755); err != nil {
+			return err
+		}
+	}
+	var offsets []Point
+	if b.NestW > 0 && b.NestH > 0 {
+		var err error
+		if offsets, err = b.nestOffsets(cfg); err != nil {
+			return err
+		}
+	}
+	all := &Warnings{Out: io.Discard} // every file's warnings, for the combined header
+	var combined []Move
+	for i, in := range b.Inputs {
+		fmt.Fprintf(os.Stderr, "%s\n", in)
+		c := cfg
+		c.Warn = &Warnings{}
+		if offsets != nil {
+			c.OffsetX += offsets[i].X
+			c.OffsetY += offsets[i].Y
+		}
+		f, err := openInput(in)
+		if err != nil {
+			return err
+		}
+		paths, c, err := loadInput(f, in, filepath.Dir(in), c, b.InFormat, b.FlipY)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", in, err)
+		}
+
+		if b.OutDir != "" {
+			base := strings.TrimSuffix(filepath.Base(in), filepath.Ext(in))
+			if err := writeFile(filepath.Join(b.OutDir, base+b.Ext), paths, c, b.Write); err != nil {
+				return fmt.Errorf("%s: %w", in, err)
+			}
+		}
+
+		if b.Combined != "" {
+			quiet := c
+			quiet.Warn = nil // already collected above
+			body, err := planJob(paths, quiet)
+			if err != nil {
+				return fmt.Errorf("%s: %w", in, err)
+			}
+			prog := newProgram(cfg.SafeZ)
+			prog.Raw("")
+			prog.Comment(cfg.Msg.T("file", filepath.Base(in)))
+			if i > 0 && offsets == nil {
+				// every program ends at safe Z, so this is only a stop
+				prog.Raw(fmt.Sprintf("M5  (%s)", cfg.Msg.T("spindle_off")))
+				prog.Raw(fmt.Sprintf("M0  (%s)", cfg.Msg.T("next_file", filepath.Base(in))))
+				if cfg.SpindleRPM > 0 {
+					prog.Raw(fmt.Sprintf("M3 S%.0f  (%s)", cfg.SpindleRPM, cfg.Msg.T("spindle_on")))
+				}
+			}
+			combined = append(append(combined, prog.Moves...), body.Moves...)
+		}
+		for _, w := range c.Warn.List {
+			w.Message = filepath.Base(in) + ": " + w.Message
+			all.List = append(all.List, w)
+		}
+	}
+
+	if b.Combined != "" {
+		c := cfg
+		c.Warn = all
+		return writeFile(b.Combined, nil, c, func(w io.Writer, _ []Path, c Config) error {
+			return emitProgram(w, combined, c)
+		})
+	}
+	return nil
+}
+
+// writeFile creates name and writes a program into it.
+func writeFile(name string, paths []Path, cfg Config, write func(io.Writer, []Path, Config) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f, paths, cfg); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
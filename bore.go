@@ -0,0 +1,86 @@
+package main
+
+import "math"
+
+// Hole is a circular path recognised for -bore, in machine mm.
+type Hole struct {
+	Center Point
+	R      float64
+}
+
+// findHoles marks closed paths that are circles, within -circle-tol of
+// one after flattening, as holes. Holes the tool cannot fit in are
+// dropped with a warning; everything else passes through.
+func findHoles(paths []Path, cfg Config) []Path {
+	out := paths[:0]
+	for _, p := range paths {
+		if !p.Closed || cfg.ColorMap.rule(p.Stroke).Op != "cut" {
+			out = append(out, p)
+			continue
+		}
+		arc, ok := fitArc(p.Points, true, cfg.CircleTol, 0.1*cfg.Scale)
+		if !ok {
+			out = append(out, p)
+			continue
+		}
+		c, r := arc.Center, arc.R
+		if 2*r < cfg.ToolDia-boreTol {
+			cfg.Warn.Add(WHoleTooSmall, p.Index,
+				"hole diameter %.3f mm is smaller than the %.3f mm tool; path skipped", 2*r, cfg.ToolDia)
+			continue
+		}
+		p.Hole = &Hole{Center: c, R: r}
+		out = append(out, p)
+	}
+	return out
+}
+
+// boreTol is how far a hole may be from the tool diameter and still be
+// drilled straight down instead of helix-bored.
+const boreTol = 0.05
+
+// planBore machines a hole. A hole the size of the tool is peck drilled;
+// a larger one is helix-bored down to targetZ, finished with a full
+// circle at depth, and left through the centre so the retract doesn't
+// mark the wall.
+func planBore(prog *Program, h Hole, targetZ float64, cfg Config) {
+	c := h.Center
+	rp := h.R - cfg.ToolDia/2
+	if rp < boreTol/2 {
+		prog.op = "drill"
+		prog.Comment(cfg.Msg.T("drill", 2*h.R))
+		peck := cfg.StepDown
+		if peck <= 0 {
+			peck = cfg.ToolDia
+		}
+		hop(prog, c.X, c.Y, cfg)
+		prev := 0.0
+		for _, z := range passDepths(targetZ, peck) {
+			if prev < 0 {
+				// back down to just above the last peck to save air time
+				prog.RapidZ(math.Min(prev+0.5, cfg.retractZ()))
+			}
+			prog.FeedZ(z, cfg.PlungeFeed)
+			prog.RapidZ(cfg.retractZ()) // clear chips
+			prev = z
+		}
+		return
+	}
+
+	prog.op = "bore"
+	prog.Comment(cfg.Msg.T("bore", 2*h.R))
+	pitch := cfg.StepDown
+	if pitch <= 0 {
+		pitch = cfg.ToolDia / 4
+	}
+	// Inside a hole, climb milling runs counter-clockwise.
+	cw := cfg.Direction == "conventional"
+	x0, y0 := c.X+rp, c.Y
+	hop(prog, x0, y0, cfg)
+	prog.FeedZ(0, cfg.PlungeFeed)
+	for _, z := range passDepths(targetZ, pitch) {
+		prog.ArcXY(cw, x0, y0, z, c.X, c.Y, cfg.CutFeed)
+	}
+	prog.ArcXY(cw, x0, y0, targetZ, c.X, c.Y, cfg.CutFeed)
+	prog.FeedXY(c.X, c.Y, cfg.CutFeed)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"slices"
+)
+
+// chainPaths merges open paths whose endpoints meet within tol into
+// continuous polylines, so a drawing exported as one path per segment is
+// cut without a retract and plunge between every piece. Paths are only
+// joined with others cut the same way (see sameCut), may be reversed to make
+// them fit, and a chain whose two ends meet is marked closed. Closed
+// input paths pass through untouched. Output keeps document order, with
+// each chain taking the position of its first member.
+func chainPaths(paths []Path, tol float64) []Path {
+	if tol <= 0 {
+		return paths
+	}
+	near := func(a, b Point) bool {
+		return math.Hypot(a.X-b.X, a.Y-b.Y) <= tol
+	}
+
+	used := make([]bool, len(paths))
+	out := make([]Path, 0, len(paths))
+	for i, p := range paths {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		if p.Closed || len(p.Points) < 2 {
+			out = append(out, p)
+			continue
+		}
+
+		chain := append([]Point(nil), p.Points...)
+		for {
+			grew := false
+			for j := i + 1; j < len(paths); j++ {
+				q := paths[j]
+				if used[j] || q.Closed || len(q.Points) < 2 || !sameCut(p, q) {
+					continue
+				}
+				head, tail := chain[0], chain[len(chain)-1]
+				qs, qe := q.Points[0], q.Points[len(q.Points)-1]
+				switch {
+				case near(tail, qs):
+					chain = append(chain, q.Points[1:]...)
+				case near(tail, qe):
+					chain = append(chain, reversePoints(q.Points)[1:]...)
+				case near(head, qe):
+					chain = append(append([]Point(nil), q.Points[:len(q.Points)-1]...), chain...)
+				case near(head, qs):
+					chain = append(reversePoints(q.Points[1:]), chain...)
+				default:
+					continue
+				}
+				used[j] = true
+				grew = true
+			}
+			if !grew {
+				break
+			}
+		}
+
+		if len(chain) > 3 && near(chain[0], chain[len(chain)-1]) {
+			chain[len(chain)-1] = chain[0]
+			p.Closed = true
+		}
+		p.Points = chain
+		out = append(out, p)
+	}
+	return out
+}
+
+// sameCut reports whether p and q are cut alike: same stroke, depth
+// override, dashes, width, layer and fill. A chain keeps its first
+// member's attributes, so paths that differ in any must not be joined.
+func sameCut(p, q Path) bool {
+	sameOverride := p.Override == q.Override ||
+		p.Override != nil && q.Override != nil && *p.Override == *q.Override
+	return p.Stroke == q.Stroke && sameOverride &&
+		slices.Equal(p.Dash, q.Dash) && p.DashOffset == q.DashOffset &&
+		p.Width == q.Width && p.Layer == q.Layer &&
+		p.Fill == q.Fill && p.FillRule == q.FillRule
+}
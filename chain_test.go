@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSameCut(t *testing.T) {
+	base := Path{Stroke: "#000000", Dash: []float64{2, 1}, Width: 1, Layer: "cut", Fill: "#ff0000", FillRule: "nonzero",
+		Override: &DepthOverride{Rule: ColorRule{Depth: -2}}}
+	for _, tc := range []struct {
+		name string
+		edit func(q *Path)
+		same bool
+	}{
+		{"identical", func(q *Path) {}, true},
+		{"equal override, another pointer", func(q *Path) { q.Override = &DepthOverride{Rule: ColorRule{Depth: -2}} }, true},
+		{"equal dashes, another slice", func(q *Path) { q.Dash = []float64{2, 1} }, true},
+		{"other points and index", func(q *Path) { q.Points, q.Index = []Point{{X: 5}}, 9 }, true},
+		{"stroke", func(q *Path) { q.Stroke = "#0000ff" }, false},
+		{"depth", func(q *Path) { q.Override = &DepthOverride{Rule: ColorRule{Depth: -3}} }, false},
+		{"passes", func(q *Path) { q.Override = &DepthOverride{Rule: ColorRule{Depth: -2}, Passes: 2} }, false},
+		{"no override", func(q *Path) { q.Override = nil }, false},
+		{"dashes", func(q *Path) { q.Dash = []float64{3, 1} }, false},
+		{"solid", func(q *Path) { q.Dash = nil }, false},
+		{"dash offset", func(q *Path) { q.DashOffset = 1 }, false},
+		{"width", func(q *Path) { q.Width = 2 }, false},
+		{"layer", func(q *Path) { q.Layer = "score" }, false},
+		{"fill", func(q *Path) { q.Fill = "" }, false},
+		{"fill rule", func(q *Path) { q.FillRule = "evenodd" }, false},
+	} {
+		q := base
+		tc.edit(&q)
+		if got := sameCut(base, q); got != tc.same {
+			t.Errorf("%s: sameCut = %v, want %v", tc.name, got, tc.same)
+		}
+		if got := sameCut(q, base); got != tc.same {
+			t.Errorf("%s: sameCut reversed = %v, want %v", tc.name, got, tc.same)
+		}
+	}
+}
+
+func TestChainPaths(t *testing.T) {
+	line := func(stroke string, pts ...Point) Path { return Path{Stroke: stroke, Points: pts} }
+	for _, tc := range []struct {
+		name   string
+		in     []Path
+		want   [][]Point
+		closed []bool
+	}{
+		{
+			name: "end to start, and reversed to fit",
+			in: []Path{
+				line("#000000", Point{0, 0}, Point{10, 0}),
+				line("#000000", Point{20, 0}, Point{10, 0.05}),
+				line("#000000", Point{-10, 0}, Point{0, 0}),
+			},
+			want:   [][]Point{{{-10, 0}, {0, 0}, {10, 0}, {20, 0}}},
+			closed: []bool{false},
+		},
+		{
+			name: "ends that meet close the chain",
+			in: []Path{
+				line("#000000", Point{0, 0}, Point{10, 0}, Point{10, 10}),
+				line("#000000", Point{10, 10}, Point{0, 10}, Point{0, 0.05}),
+			},
+			want:   [][]Point{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}},
+			closed: []bool{true},
+		},
+		{
+			name: "different cuts stay apart",
+			in: []Path{
+				line("#000000", Point{0, 0}, Point{10, 0}),
+				line("#ff0000", Point{10, 0}, Point{20, 0}),
+				{Stroke: "#000000", Layer: "score", Points: []Point{{10, 0}, {10, 10}}},
+			},
+			want:   [][]Point{{{0, 0}, {10, 0}}, {{10, 0}, {20, 0}}, {{10, 0}, {10, 10}}},
+			closed: []bool{false, false, false},
+		},
+		{
+			name: "closed paths and far ends are left alone",
+			in: []Path{
+				{Stroke: "#000000", Closed: true, Points: []Point{{0, 0}, {10, 0}, {10, 10}, {0, 0}}},
+				line("#000000", Point{0, 0}, Point{-10, 0}),
+				line("#000000", Point{-10, 1}, Point{-20, 0}),
+			},
+			want:   [][]Point{{{0, 0}, {10, 0}, {10, 10}, {0, 0}}, {{0, 0}, {-10, 0}}, {{-10, 1}, {-20, 0}}},
+			closed: []bool{true, false, false},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := chainPaths(tc.in, 0.1)
+			if len(out) != len(tc.want) {
+				t.Fatalf("%d paths, want %d: %+v", len(out), len(tc.want), out)
+			}
+			for i, p := range out {
+				if !slices.Equal(p.Points, tc.want[i]) || p.Closed != tc.closed[i] {
+					t.Errorf("path %d: %v closed %v, want %v closed %v", i, p.Points, p.Closed, tc.want[i], tc.closed[i])
+				}
+			}
+		})
+	}
+}
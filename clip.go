@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// clipRef is a clip-path or mask reference, with the user space of the
+// element that made it (clipPathUnits="userSpaceOnUse").
+type clipRef struct {
+	ID string
+	T  Transform
+}
+
+type svgRect struct {
+	X         float64 `xml:"x,attr"`
+	Y         float64 `xml:"y,attr"`
+	Width     float64 `xml:"width,attr"`
+	Height    float64 `xml:"height,attr"`
+	Transform string  `xml:"transform,attr"`
+}
+
+// parseClipRefs turns clip-path and mask attribute values into
+// references. Only local url(#id) references are understood.
+func parseClipRefs(t Transform, values ...string) []clipRef {
+	var refs []clipRef
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		id, ok := strings.CutPrefix(v, "url(#")
+		if !ok {
+			continue
+		}
+		id = strings.TrimSuffix(strings.TrimSpace(id), ")")
+		refs = append(refs, clipRef{ID: strings.Trim(id, `"' `), T: t})
+	}
+	return refs
+}
+
+// readClipShapes consumes a <clipPath> or <mask> element and returns the
+// outlines of its shapes in its own coordinates. A mask is treated as
+// the plain union of its shapes: luminance and opacity are ignored.
+func readClipShapes(dec *xml.Decoder, start xml.StartElement, warn *Warnings) ([][]Point, error) {
+	for _, a := range start.Attr {
+		if a.Name.Local == "clipPathUnits" && a.Value == "objectBoundingBox" {
+			warn.Add(WIgnoredOption, 0, "clipPathUnits=objectBoundingBox is not supported; treated as userSpaceOnUse")
+		}
+	}
+	var shapes [][]Point
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("decode <%s>: %w", start.Name.Local, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				return shapes, nil
+			}
+		case xml.StartElement:
+			var pts []Point
+			var tr string
+			switch t.Name.Local {
+			case "rect":
+				var r svgRect
+				if err := dec.DecodeElement(&r, &t); err != nil {
+					return nil, err
+				}
+				pts = []Point{{r.X, r.Y}, {r.X + r.Width, r.Y}, {r.X + r.Width, r.Y + r.Height}, {r.X, r.Y + r.Height}, {r.X, r.Y}}
+				tr = r.Transform
+			case "circle":
+				var c svgCircle
+				if err := dec.DecodeElement(&c, &t); err != nil {
+					return nil, err
+				}
+				pts = circlePoints(Point{X: c.CX, Y: c.CY}, c.R, 0.1)
+				tr = c.Transform
+			case "polygon", "polyline":
+				var p svgPolyLine
+				if err := dec.DecodeElement(&p, &t); err != nil {
+					return nil, err
+				}
+				if pts, err = parsePointsList(p.Points); err != nil {
+					return nil, err
+				}
+				tr = p.Transform
+			case "path":
+				var p svgPath
+				if err := dec.DecodeElement(&p, &t); err != nil {
+					return nil, err
+				}
+				subs, err := parseSimplePath(p.D)
+				if err != nil {
+					return nil, newElementError(ErrMalformedPath, t, 0, truncate(p.D, 40), err)
+				}
+				// every subpath adds to the union; clip-rule is not honoured
+				m := parseTransformAttr(p.Transform)
+				for _, sp := range subs {
+					if len(sp.Points) < 3 {
+						continue
+					}
+					for i := range sp.Points {
+						sp.Points[i] = m.Apply(sp.Points[i])
+					}
+					shapes = append(shapes, sp.Points)
+				}
+				continue
+			default:
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if len(pts) < 3 {
+				continue
+			}
+			m := parseTransformAttr(tr)
+			for i := range pts {
+				pts[i] = m.Apply(pts[i])
+			}
+			shapes = append(shapes, pts)
+		}
+	}
+}
+
+// applyClips clips every path by the regions it references. refs is
+// indexed like paths. A path can be split into several open pieces; a
+// path entirely inside its regions is left alone.
+func applyClips(paths []Path, refs [][]clipRef, clips map[string][][]Point, warn *Warnings) []Path {
+	out := make([]Path, 0, len(paths))
+	for i, p := range paths {
+		pieces := []Path{p}
+		for _, ref := range refs[i] {
+			shapes, ok := clips[ref.ID]
+			if !ok {
+				warn.Add(WUnknownReference, p.Index, "clip-path or mask #%s not found; not clipped", ref.ID)
+				continue
+			}
+			region := make([][]Point, len(shapes))
+			for k, s := range shapes {
+				region[k] = make([]Point, len(s))
+				for j, pt := range s {
+					region[k][j] = ref.T.Apply(pt)
+				}
+			}
+			var next []Path
+			for _, q := range pieces {
+				cut, whole := clipPolyline(q.Points, q.Closed, region, func(pt Point) bool {
+					for _, poly := range region {
+						if pointInPolygon(pt, poly) {
+							return true
+						}
+					}
+					return false
+				})
+				if whole {
+					next = append(next, q)
+					continue
+				}
+				for _, pts := range cut {
+					r := q
+					r.Points = pts
+					r.Closed = false
+					next = append(next, r)
+				}
+			}
+			pieces = next
+		}
+		out = append(out, pieces...)
+	}
+	return out
+}
+
+// clipPolyline keeps the parts of a polyline inside a region bounded by
+// the rings in region; inside decides which side of them is in. whole
+// reports that nothing was removed.
+func clipPolyline(pts []Point, closed bool, region [][]Point, inside func(Point) bool) (pieces [][]Point, whole bool) {
+	whole = true
+	var cur []Point
+	flush := func() {
+		if len(cur) > 1 {
+			pieces = append(pieces, cur)
+		}
+		cur = nil
+	}
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		ts := []float64{0, 1}
+		for _, poly := range region {
+			for j := 1; j <= len(poly); j++ {
+				c, d := poly[j-1], poly[j%len(poly)]
+				if t, ok := segmentCrossing(a, b, c, d); ok {
+					ts = append(ts, t)
+				}
+			}
+		}
+		sort.Float64s(ts)
+		for k := 1; k < len(ts); k++ {
+			t0, t1 := ts[k-1], ts[k]
+			if t1-t0 < 1e-12 {
+				continue
+			}
+			if !inside(lerp(a, b, (t0+t1)/2)) {
+				whole = false
+				flush()
+				continue
+			}
+			p0, p1 := lerp(a, b, t0), lerp(a, b, t1)
+			if cur != nil && !almostEqualPoint(cur[len(cur)-1], p0) {
+				flush()
+			}
+			if cur == nil {
+				cur = []Point{p0}
+			}
+			cur = append(cur, p1)
+		}
+	}
+	flush()
+	if whole {
+		return nil, true
+	}
+	// a loop cut open: the piece through its start and the one through
+	// its end are really one
+	if closed && len(pieces) > 1 {
+		first, last := pieces[0], pieces[len(pieces)-1]
+		if almostEqualPoint(first[0], pts[0]) && almostEqualPoint(last[len(last)-1], pts[len(pts)-1]) {
+			pieces[0] = append(last, first[1:]...)
+			pieces = pieces[:len(pieces)-1]
+		}
+	}
+	return pieces, false
+}
+
+// segmentCrossing returns where segment ab crosses segment cd, as a
+// fraction along ab. Parallel segments don't cross.
+func segmentCrossing(a, b, c, d Point) (float64, bool) {
+	r := Point{X: b.X - a.X, Y: b.Y - a.Y}
+	s := Point{X: d.X - c.X, Y: d.Y - c.Y}
+	den := cross(r, s)
+	if den == 0 {
+		return 0, false
+	}
+	ac := Point{X: c.X - a.X, Y: c.Y - a.Y}
+	t := cross(ac, s) / den
+	u := cross(ac, r) / den
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return 0, false
+	}
+	return t, true
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string // "" = not a color
+	}{
+		// hex in every length, with and without the #
+		{"#ff0000", "#ff0000"},
+		{"#FF8000", "#ff8000"},
+		{"#f00", "#ff0000"},
+		{"#f008", "#ff0000"},
+		{"#12345678", "#123456"},
+		{"00ff00", "#00ff00"},
+		{"#ff00", "#ffff00"},
+		{"#ff000", ""},
+		{"#ggg", ""},
+		// named colors, in any case
+		{"red", "#ff0000"},
+		{" Black ", "#000000"},
+		{"rebeccapurple", "#663399"},
+		{"grey", "#808080"},
+		{"notacolor", ""},
+		// rgb() with numbers, percentages and either separator
+		{"rgb(255,0,0)", "#ff0000"},
+		{"rgb( 0 , 128 , 255 )", "#0080ff"},
+		{"rgb(0 128 255)", "#0080ff"},
+		{"rgb(100%, 50%, 0%)", "#ff8000"},
+		{"rgba(0,0,255,0.5)", "#0000ff"},
+		{"rgb(0 0 255 / 50%)", "#0000ff"},
+		{"rgb(300,-20,0)", "#ff0000"},
+		{"rgb(1,2)", ""},
+		{"rgb(a,b,c)", ""},
+		// hsl() with and without deg, hue wrapping round
+		{"hsl(0, 100%, 50%)", "#ff0000"},
+		{"hsl(120deg 100% 25%)", "#008000"},
+		{"hsl(240,100%,50%)", "#0000ff"},
+		{"hsl(-120, 100%, 50%)", "#0000ff"},
+		{"hsla(60, 100%, 50%, 0.3)", "#ffff00"},
+		{"hsl(0, 0%, 100%)", "#ffffff"},
+		{"hsl(0, 100, 50)", ""},
+		{"cmyk(0,0,0,0)", ""},
+	} {
+		got, ok := parseColor(tc.in)
+		if !ok {
+			got = ""
+		}
+		if got != tc.want {
+			t.Errorf("parseColor(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeColor(t *testing.T) {
+	// spellings of one color compare equal; other values are kept
+	for _, in := range []string{"red", "#F00", "rgb(255, 0, 0)", "hsl(360, 100%, 50%)"} {
+		if got := normalizeColor(in); got != "#ff0000" {
+			t.Errorf("normalizeColor(%q) = %q, want #ff0000", in, got)
+		}
+	}
+	for in, want := range map[string]string{"none": "none", "URL(#grad)": "url(#grad)", "currentColor": "currentcolor"} {
+		if got := normalizeColor(in); got != want {
+			t.Errorf("normalizeColor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
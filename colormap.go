@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColorRule says how paths stroked in one color are machined.
+type ColorRule struct {
+	Op string // "cut" (default) or "score"
+
+	// Depth is an absolute cut depth (negative mm). DepthPct is a depth
+	// as a percentage of the stock thickness, DepthFrac a fraction of the
+	// job's -cutz. Zero means unset.
+	Depth     float64
+	DepthPct  float64
+	DepthFrac float64
+
+	// StepDown, Feed and Plunge override the job's values for these
+	// paths. Zero means the job default; scores step down only when
+	// StepDown is set.
+	StepDown float64
+	Feed     float64
+	Plunge   float64
+
+	WCS string // work coordinate system for these paths, e.g. "G55"; "" = job default
+
+	// Operation puts these paths in one of the -operations groups
+	// ("engrave", "drill", "pocket", "profile"); "" = decided by the path.
+	Operation string
+
+	// Pause, if set, stops the program before the first path of this
+	// color with the text as the operator instruction.
+	Pause string
+}
+
+// ColorMap maps normalized stroke colors to rules.
+type ColorMap map[string]ColorRule
+
+// parseColorMap parses a -colormap spec: entries separated by ';', each
+// "COLOR:key=value,key=value". For example:
+//
+//	#00ff00:op=score,depth=30%; #ff0000:op=cut,depth=-3.2
+func parseColorMap(spec string) (ColorMap, error) {
+	cm := ColorMap{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		color, opts, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("colormap entry %q: want COLOR:key=value,...", entry)
+		}
+		color = normalizeColor(color)
+		if color == "" {
+			return nil, fmt.Errorf("colormap entry %q: missing color", entry)
+		}
+		rule := ColorRule{Op: "cut"}
+		for _, kv := range strings.Split(opts, ",") {
+			kv = strings.TrimSpace(kv)
+			if kv == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("colormap %s: %q is not key=value", color, kv)
+			}
+			key = strings.ToLower(strings.TrimSpace(key))
+			val = strings.TrimSpace(val)
+			if err := rule.set(key, val); err != nil {
+				return nil, fmt.Errorf("colormap %s: %w", color, err)
+			}
+		}
+		if rule.Op == "score" && rule.Depth == 0 && rule.DepthPct == 0 && rule.DepthFrac == 0 {
+			return nil, fmt.Errorf("colormap %s: score needs a depth", color)
+		}
+		cm[color] = rule
+	}
+	return cm, nil
+}
+
+func (r *ColorRule) set(key, val string) error {
+	switch key {
+	case "op":
+		switch strings.ToLower(val) {
+		case "cut", "score":
+			r.Op = strings.ToLower(val)
+		default:
+			return fmt.Errorf("unknown op %q (must be cut, score)", val)
+		}
+	case "depth":
+		if frac, ok := strings.CutSuffix(val, "x"); ok {
+			v, err := strconv.ParseFloat(frac, 64)
+			if err != nil || v <= 0 || v > 1 {
+				return fmt.Errorf("invalid depth %q (fraction of -cutz must be in (0,1])", val)
+			}
+			r.DepthFrac = v
+			return nil
+		}
+		if pct, ok := strings.CutSuffix(val, "%"); ok {
+			v, err := strconv.ParseFloat(pct, 64)
+			if err != nil || v <= 0 || v > 100 {
+				return fmt.Errorf("invalid depth %q (percentage must be in (0,100])", val)
+			}
+			r.DepthPct = v
+			return nil
+		}
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil || v >= 0 {
+			return fmt.Errorf("invalid depth %q (must be negative mm, N%% or a fraction like 0.3x)", val)
+		}
+		r.Depth = v
+	case "stepdown", "feed", "plunge":
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("invalid %s %q (must be > 0)", key, val)
+		}
+		switch key {
+		case "stepdown":
+			r.StepDown = v
+		case "feed":
+			r.Feed = v
+		default:
+			r.Plunge = v
+		}
+	case "pause":
+		if val == "" {
+			return fmt.Errorf("pause needs an instruction, e.g. pause=change bit")
+		}
+		r.Pause = val
+	case "operation":
+		op, err := parseOperation(val)
+		if err != nil {
+			return err
+		}
+		r.Operation = op
+	case "wcs":
+		w, err := parseWCS(val)
+		if err != nil {
+			return err
+		}
+		r.WCS = w
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// parseWCS validates a work coordinate system word (G54–G59, G59.1–G59.3).
+func parseWCS(s string) (string, error) {
+	w := strings.ToUpper(strings.TrimSpace(s))
+	switch w {
+	case "G54", "G55", "G56", "G57", "G58", "G59", "G59.1", "G59.2", "G59.3":
+		return w, nil
+	}
+	return "", fmt.Errorf("invalid work coordinate system %q (must be G54-G59 or G59.1-G59.3)", s)
+}
+
+// usesStock reports whether any rule needs the stock thickness.
+func (cm ColorMap) usesStock() bool {
+	for _, r := range cm {
+		if r.DepthPct > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rule returns the rule for a stroke color; unmapped colors get a plain cut.
+func (cm ColorMap) rule(stroke string) ColorRule {
+	if r, ok := cm[stroke]; ok {
+		return r
+	}
+	return ColorRule{Op: "cut"}
+}
+
+// feeds returns the XY and plunge feeds for the rule's paths.
+func (r ColorRule) feeds(cfg Config) (feed, plunge float64) {
+	feed, plunge = cfg.CutFeed, cfg.PlungeFeed
+	if r.Feed > 0 {
+		feed = r.Feed
+	}
+	if r.Plunge > 0 {
+		plunge = r.Plunge
+	}
+	return feed, plunge
+}
+
+// targetZ resolves the final depth for a rule against the job defaults.
+func (r ColorRule) targetZ(cfg Config) float64 {
+	switch {
+	case r.DepthPct > 0:
+		return -cfg.StockThickness * r.DepthPct / 100
+	case r.DepthFrac > 0:
+		return cfg.CutDepth * r.DepthFrac
+	case r.Depth < 0:
+		return r.Depth
+	}
+	return cfg.CutDepth
+}
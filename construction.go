@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Construction is the geometry that is drawn but never cut: guide lines,
+// dimensions, the stock outline. A path is construction when its stroke
+// is one of Colors or it was drawn on one of Layers.
+type Construction struct {
+	Colors []string // normalized "#rrggbb"
+	Layers []string // Inkscape or DXF layer names, matched ignoring case
+}
+
+// parseConstruction reads -construction: a comma-separated list of colors
+// and layer names. An entry that reads as a color is a color; anything
+// else, or an entry written layer:NAME, is a layer. "" or none disables
+// the filter.
+func parseConstruction(spec string) (Construction, error) {
+	var c Construction
+	if s := strings.TrimSpace(spec); s == "" || strings.EqualFold(s, "none") {
+		return c, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(entry, "layer:"); ok {
+			if name = strings.TrimSpace(name); name == "" {
+				return c, fmt.Errorf("-construction entry %q: missing layer name", entry)
+			}
+			c.Layers = append(c.Layers, name)
+		} else if color, ok := parseColor(entry); ok {
+			c.Colors = append(c.Colors, color)
+		} else {
+			c.Layers = append(c.Layers, entry)
+		}
+	}
+	return c, nil
+}
+
+// excludes reports whether p is construction geometry.
+func (c Construction) excludes(p Path) bool {
+	if slices.Contains(c.Colors, p.Stroke) {
+		return true
+	}
+	return p.Layer != "" && slices.ContainsFunc(c.Layers, func(l string) bool { return strings.EqualFold(l, p.Layer) })
+}
+
+// constructionPaths drops construction geometry, saying how much was
+// left out so a drawing that comes out empty is easy to explain.
+func constructionPaths(paths []Path, c Construction, warn *Warnings) []Path {
+	if len(c.Colors) == 0 && len(c.Layers) == 0 {
+		return paths
+	}
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if !c.excludes(p) {
+			out = append(out, p)
+		}
+	}
+	if n := len(paths) - len(out); n > 0 {
+		warn.Add(WConstruction, 0, "%d construction path(s) skipped", n)
+	}
+	return out
+}
+
+// parseColorList reads a comma-separated list of colors, as -only-colors
+// takes them.
+func parseColorList(spec string) ([]string, error) {
+	var colors []string
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		c, ok := parseColor(entry)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a color", entry)
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+// onlyColorPaths keeps the paths stroked in one of colors, or filled in
+// one when they have no stroke, for -only-colors: one drawing can then
+// drive several jobs, a color each.
+func onlyColorPaths(paths []Path, colors []string, warn *Warnings) []Path {
+	if len(colors) == 0 {
+		return paths
+	}
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		color := p.Stroke
+		if color == "" || color == "none" {
+			color = p.Fill
+		}
+		if slices.Contains(colors, color) {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 && len(paths) > 0 {
+		warn.Add(WNoGeometry, 0, "no paths in the -only-colors colors %s", strings.Join(colors, ", "))
+	}
+	return out
+}
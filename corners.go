@@ -0,0 +1,127 @@
+package main
+
+import "math"
+
+// Simple controllers without lookahead take every corner at full feed,
+// and the tool deflects and chatters there, which shows in acrylic as
+// bulged and melted corners. -corner-feed-factor slows the feed for a
+// short way either side of every sharp turn and restores it after.
+
+// cornerZone is how far before and after a sharp corner the feed is
+// reduced: one tool diameter, or 1 mm for a job without one.
+func cornerZone(cfg Config) float64 {
+	return math.Max(cfg.ToolDia, 1)
+}
+
+// slowCorners returns moves with the feed cut to factor of itself within
+// cornerZone of every corner where consecutive straight feed moves turn
+// by at least minAngle degrees. Moves are split where the slower feed
+// starts and ends; arcs, plunges and rapids are left alone and end a run
+// of corners. The seam where a closed path's last line meets its first
+// counts as a corner too.
+func slowCorners(moves []Move, factor, minAngle, zone float64) []Move {
+	if factor <= 0 || factor >= 1 {
+		return moves
+	}
+	// line reports whether moves[i] is a straight feed move with some
+	// length in XY, and from where
+	line := func(i int) (Move, bool) {
+		if i <= 0 || i >= len(moves) || moves[i].Kind != MoveFeed {
+			return Move{}, false
+		}
+		from := moves[i-1]
+		return from, math.Hypot(moves[i].X-from.X, moves[i].Y-from.Y) > 1e-9
+	}
+	// turn is the angle in radians between the direction a to b and
+	// the direction c to d
+	turn := func(a, b, c, d Move) float64 {
+		d1 := math.Atan2(b.Y-a.Y, b.X-a.X)
+		d2 := math.Atan2(d.Y-c.Y, d.X-c.X)
+		return math.Abs(math.Remainder(d2-d1, 2*math.Pi))
+	}
+	minTurn := minAngle*math.Pi/180 - 1e-9
+	// sharp reports whether the tool turns sharply from move i to the
+	// next motion, with only comments between
+	sharp := func(i int) bool {
+		from, ok := line(i)
+		if !ok {
+			return false
+		}
+		j := i + 1
+		for j < len(moves) && !moves[j].isMotion() {
+			j++
+		}
+		if _, ok := line(j); !ok {
+			return false
+		}
+		return turn(from, moves[i], moves[i], moves[j]) >= minTurn
+	}
+	// A run of lines that ends where it started went round a closed
+	// path, and its last line turns into its first at the seam: slow
+	// both sides of that corner too.
+	seamIn, seamOut := map[int]bool{}, map[int]bool{}
+	for i := 0; i < len(moves); i++ {
+		if _, ok := line(i); !ok {
+			continue
+		}
+		first, last := i, i
+		for j := i + 1; j < len(moves); j++ {
+			if !moves[j].isMotion() {
+				continue
+			}
+			if _, ok := line(j); !ok {
+				break
+			}
+			last = j
+		}
+		start, end := moves[first-1], moves[last]
+		if last > first && math.Hypot(end.X-start.X, end.Y-start.Y) <= 1e-9 &&
+			turn(moves[last-1], end, start, moves[first]) >= minTurn {
+			seamIn[first], seamOut[last] = true, true
+		}
+		i = last
+	}
+
+	out := make([]Move, 0, len(moves))
+	slowIn := false // the move before turned sharply into this one
+	for i, m := range moves {
+		from, ok := line(i)
+		if !ok {
+			if m.isMotion() {
+				slowIn = false
+			}
+			out = append(out, m)
+			continue
+		}
+		slowIn = slowIn || seamIn[i]
+		slowOut := sharp(i) || seamOut[i]
+		l := math.Hypot(m.X-from.X, m.Y-from.Y)
+		at := func(d float64) Move { // the point d mm along m
+			p := m
+			t := d / l
+			p.X, p.Y, p.Z = from.X+(m.X-from.X)*t, from.Y+(m.Y-from.Y)*t, from.Z+(m.Z-from.Z)*t
+			return p
+		}
+		slow := m
+		slow.F = m.F * factor
+		switch {
+		case !slowIn && !slowOut:
+			out = append(out, m)
+		case slowIn && slowOut && l <= 2*zone, slowIn != slowOut && l <= zone:
+			out = append(out, slow)
+		default:
+			if slowIn {
+				p := at(zone)
+				p.F = slow.F
+				out = append(out, p)
+			}
+			if slowOut {
+				out = append(out, at(l-zone), slow)
+			} else {
+				out = append(out, m)
+			}
+		}
+		slowIn = slowOut
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// feedsAt returns the feed of the moves arriving at and leaving (x, y).
+func feedsAt(moves []Move, x, y float64) (in, out []float64) {
+	for i := 1; i < len(moves); i++ {
+		m, from := moves[i], moves[i-1]
+		if m.Kind != MoveFeed || math.Hypot(m.X-from.X, m.Y-from.Y) < 1e-9 {
+			continue
+		}
+		if math.Hypot(m.X-x, m.Y-y) < 1e-9 {
+			in = append(in, m.F)
+		}
+		if math.Hypot(from.X-x, from.Y-y) < 1e-9 {
+			out = append(out, m.F)
+		}
+	}
+	return in, out
+}
+
+func TestSlowCornersSeam(t *testing.T) {
+	square := []Point{{X: 10, Y: 10}, {X: 50, Y: 10}, {X: 50, Y: 50}, {X: 10, Y: 50}, {X: 10, Y: 10}}
+	prog := newProgram(5)
+	hop(prog, square[0].X, square[0].Y, Config{SafeZ: 5})
+	for _, z := range passDepths(-1, 0.5) {
+		hop(prog, square[0].X, square[0].Y, Config{SafeZ: 5})
+		prog.FeedZ(z, 100)
+		for _, p := range square[1:] {
+			prog.FeedXY(p.X, p.Y, 300)
+		}
+	}
+	prog.RapidZ(5)
+	moves := slowCorners(prog.Moves, 0.5, 60, 3)
+
+	// every corner, the seam at the start included, is taken at half feed
+	for _, p := range square[:4] {
+		in, out := feedsAt(moves, p.X, p.Y)
+		if len(in) != 2 || len(out) != 2 {
+			t.Fatalf("X%g Y%g: %d moves in and %d out, want 2 of each", p.X, p.Y, len(in), len(out))
+		}
+		for _, f := range append(in, out...) {
+			if f != 150 {
+				t.Errorf("X%g Y%g: feeds in %v, out %v, want all F150", p.X, p.Y, in, out)
+				break
+			}
+		}
+	}
+}
+
+func TestSlowCornersOpenEnds(t *testing.T) {
+	// an open path's ends are not corners, even where they nearly meet
+	v := []Point{{X: 0, Y: 0}, {X: 30, Y: 0}, {X: 0, Y: 1}}
+	prog := newProgram(5)
+	hop(prog, v[0].X, v[0].Y, Config{SafeZ: 5})
+	prog.FeedZ(-1, 100)
+	for _, p := range v[1:] {
+		prog.FeedXY(p.X, p.Y, 300)
+	}
+	prog.RapidZ(5)
+	moves := slowCorners(prog.Moves, 0.5, 60, 3)
+
+	_, out := feedsAt(moves, v[0].X, v[0].Y)
+	in, _ := feedsAt(moves, v[2].X, v[2].Y)
+	if len(out) != 1 || out[0] != 300 || len(in) != 1 || in[0] != 300 {
+		t.Fatalf("open ends cut at F%v and F%v, want F300", out, in)
+	}
+	if in, out := feedsAt(moves, v[1].X, v[1].Y); len(in) != 1 || in[0] != 150 || out[0] != 150 {
+		t.Fatalf("the V's point cut at F%v in, F%v out, want F150", in, out)
+	}
+}
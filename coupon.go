@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseKerfRange parses "min:max:step" (mm) into the list of nominal
+// kerfs to test.
+func parseKerfRange(s string) ([]float64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid kerf range %q (want min:max:step)", s)
+	}
+	var v [3]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || f < 0 {
+			return nil, fmt.Errorf("invalid kerf range %q", s)
+		}
+		v[i] = f
+	}
+	lo, hi, step := v[0], v[1], v[2]
+	if step <= 0 || hi < lo {
+		return nil, fmt.Errorf("invalid kerf range %q (need min <= max and step > 0)", s)
+	}
+	var kerfs []float64
+	for k := lo; k <= hi+1e-9; k += step {
+		kerfs = append(kerfs, k)
+	}
+	if len(kerfs) > 20 {
+		return nil, fmt.Errorf("kerf range %q gives %d samples; 20 at most", s, len(kerfs))
+	}
+	return kerfs, nil
+}
+
+// kerfCoupon builds a kerf test coupon in machine coordinates (mm). For
+// every nominal kerf k there is a square peg of size w, offset outward by
+// k/2, and a matching square hole in a plate, offset inward by k/2. Cut
+// it, then test-fit peg n into hole n: the snuggest pair is the kerf to
+// use, and the tool diameter to give -tooldia. Holes come before the
+// plate outline so the plate is still held while they are cut.
+func kerfCoupon(kerfs []float64, w float64) []Path {
+	var paths []Path
+	add := func(r Rect, label string) {
+		paths = append(paths, Path{
+			Points: []Point{
+				{X: r.MinX, Y: r.MinY}, {X: r.MaxX, Y: r.MinY},
+				{X: r.MaxX, Y: r.MaxY}, {X: r.MinX, Y: r.MaxY},
+				{X: r.MinX, Y: r.MinY},
+			},
+			Closed:    true,
+			Transform: identityTransform(),
+			Index:     len(paths) + 1,
+			Label:     label,
+		})
+	}
+
+	pitch := 2 * w
+	// plate with holes along the bottom row
+	for i, k := range kerfs {
+		x := w + float64(i)*pitch
+		add(Rect{MinX: x + k/2, MinY: w + k/2, MaxX: x + w - k/2, MaxY: 2*w - k/2},
+			fmt.Sprintf("hole %d, kerf %.3f", i+1, k))
+	}
+	plateW := float64(len(kerfs))*pitch + w
+	add(Rect{MinX: 0, MinY: 0, MaxX: plateW, MaxY: 3 * w}, "plate outline")
+
+	// pegs in a row above the plate
+	for i, k := range kerfs {
+		x := w + float64(i)*pitch
+		y := 4 * w
+		add(Rect{MinX: x - k/2, MinY: y - k/2, MaxX: x + w + k/2, MaxY: y + w + k/2},
+			fmt.Sprintf("peg %d, kerf %.3f", i+1, k))
+	}
+	return paths
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"slices"
+	"strings"
+)
+
+// Optimized and exported SVGs often style their shapes from a <style>
+// element with class selectors instead of attributes. The rules that
+// match an element are folded into its attributes before it is read, so
+// stroke, fill, dashes and visibility come out the same wherever they
+// were set.
+
+// cssRule is one simple selector of a style sheet rule and its
+// declarations.
+type cssRule struct {
+	Tag     string   // element name, "" = any
+	ID      string   // "" = any
+	Classes []string // all must be present
+	Decls   string   // "prop:value;..." as written
+	Spec    int      // specificity: ids, classes, tags
+	Order   int      // position in the sheet; later rules win ties
+}
+
+// StyleSheet is the simple selectors of an SVG's <style> elements:
+// element names, .classes and #ids, alone or combined (rect.cut.thin),
+// and comma-separated lists of them. Rules with descendant, child,
+// attribute or pseudo-class selectors and at-rules are ignored.
+type StyleSheet []cssRule
+
+// readStyleSheet collects the rules of every <style> element in an SVG,
+// wherever it appears in the document.
+func readStyleSheet(data []byte) StyleSheet {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var css strings.Builder
+	in := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break // a broken document is reported by the real parse
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if foreignElement(t.Name) || unrendered[t.Name.Local] {
+				in = false
+				dec.Skip() // an HTML <style> in a foreignObject is not ours
+				continue
+			}
+			in = t.Name.Local == "style"
+		case xml.EndElement:
+			in = false
+		case xml.CharData:
+			if in {
+				css.Write(t)
+				css.WriteByte('\n')
+			}
+		}
+	}
+	return parseStyleSheet(css.String())
+}
+
+// parseStyleSheet reads CSS text into rules. Comments are dropped and
+// at-rules such as @media and @font-face are skipped whole.
+func parseStyleSheet(src string) StyleSheet {
+	for {
+		i := strings.Index(src, "/*")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(src[i+2:], "*/")
+		if j < 0 {
+			src = src[:i]
+			break
+		}
+		src = src[:i] + " " + src[i+2+j+2:]
+	}
+
+	var sheet StyleSheet
+	for src = strings.TrimSpace(src); src != ""; src = strings.TrimSpace(src) {
+		open := strings.IndexAny(src, "{;")
+		if open < 0 {
+			break
+		}
+		prelude := strings.TrimSpace(src[:open])
+		if src[open] == ';' { // @import and the like
+			src = src[open+1:]
+			continue
+		}
+		// find the matching close brace
+		depth, end := 0, len(src)
+		for k := open; k < len(src); k++ {
+			if src[k] == '{' {
+				depth++
+			} else if src[k] == '}' {
+				if depth--; depth == 0 {
+					end = k
+					break
+				}
+			}
+		}
+		body := src[open+1 : end]
+		src = src[min(end+1, len(src)):]
+		if strings.HasPrefix(prelude, "@") {
+			continue
+		}
+		for _, sel := range strings.Split(prelude, ",") {
+			if r, ok := parseSelector(strings.TrimSpace(sel)); ok {
+				r.Decls = strings.TrimSpace(strings.ReplaceAll(body, "!important", ""))
+				r.Order = len(sheet)
+				sheet = append(sheet, r)
+			}
+		}
+	}
+	return sheet
+}
+
+// parseSelector reads one compound selector such as "path.cut#outline".
+func parseSelector(sel string) (cssRule, bool) {
+	if sel == "" || strings.ContainsAny(sel, " >+~[:") {
+		return cssRule{}, false
+	}
+	var r cssRule
+	ids, classes, tags := 0, 0, 0
+	for sel != "" {
+		end := strings.IndexAny(sel[1:], ".#") + 1
+		if end == 0 {
+			end = len(sel)
+		}
+		part := sel[:end]
+		sel = sel[end:]
+		switch part[0] {
+		case '.':
+			if len(part) == 1 {
+				return cssRule{}, false
+			}
+			r.Classes = append(r.Classes, part[1:])
+			classes++
+		case '#':
+			if len(part) == 1 {
+				return cssRule{}, false
+			}
+			r.ID = part[1:]
+			ids++
+		case '*':
+			if part != "*" {
+				return cssRule{}, false
+			}
+		default:
+			r.Tag = part
+			tags++
+		}
+	}
+	r.Spec = ids*10000 + classes*100 + tags
+	return r, true
+}
+
+// styledProps are the presentation attributes the parser also reads from
+// style, which a matching rule takes over.
+var styledProps = map[string]bool{
+	"stroke": true, "stroke-width": true, "stroke-dasharray": true, "stroke-dashoffset": true, "fill": true, "fill-rule": true,
+	"display": true, "visibility": true, "opacity": true, "font-family": true, "font-size": true, "text-anchor": true,
+}
+
+// matches reports whether the rule selects an element.
+func (r cssRule) matches(tag, id string, classes []string) bool {
+	if r.Tag != "" && r.Tag != tag || r.ID != "" && r.ID != id {
+		return false
+	}
+	for _, c := range r.Classes {
+		if !slices.Contains(classes, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply folds the rules matching an element into its attributes. The
+// declarations are appended to its style attribute, most specific first,
+// so the element's own style still wins (style lookups take the first
+// match); presentation attributes the sheet sets are dropped, as the
+// sheet overrides them.
+func (s StyleSheet) apply(t xml.StartElement) xml.StartElement {
+	if len(s) == 0 {
+		return t
+	}
+	var id, style string
+	var classes []string
+	for _, a := range t.Attr {
+		switch a.Name.Local {
+		case "id":
+			id = a.Value
+		case "class":
+			classes = strings.Fields(a.Value)
+		case "style":
+			style = a.Value
+		}
+	}
+	var hit []cssRule
+	for _, r := range s {
+		if r.matches(t.Name.Local, id, classes) {
+			hit = append(hit, r)
+		}
+	}
+	if len(hit) == 0 {
+		return t
+	}
+	slices.SortStableFunc(hit, func(a, b cssRule) int {
+		if a.Spec != b.Spec {
+			return b.Spec - a.Spec
+		}
+		return b.Order - a.Order
+	})
+	decls := []string{style}
+	set := map[string]bool{}
+	for _, r := range hit {
+		decls = append(decls, r.Decls)
+		for _, d := range strings.Split(r.Decls, ";") {
+			if k, _, ok := strings.Cut(d, ":"); ok {
+				if k = strings.ToLower(strings.TrimSpace(k)); styledProps[k] {
+					set[k] = true
+				}
+			}
+		}
+	}
+	attrs := make([]xml.Attr, 0, len(t.Attr)+1)
+	for _, a := range t.Attr {
+		if a.Name.Local != "style" && !(a.Name.Space == "" && set[a.Name.Local]) {
+			attrs = append(attrs, a)
+		}
+	}
+	attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "style"}, Value: strings.Join(decls, ";")})
+	t.Attr = attrs
+	return t
+}
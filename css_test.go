@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseStyleSheet(t *testing.T) {
+	sheet := parseStyleSheet(`
+		/* a comment { with braces } */
+		@import url(other.css);
+		@media print { .cut { stroke: red } }
+		path.cut#outline, .thin.cut { stroke: #ff0000 !important }
+		* { fill: none }
+		g path, a > b, .x:hover, rect[id], . { stroke: blue }
+	`)
+	want := []cssRule{
+		{Tag: "path", ID: "outline", Classes: []string{"cut"}, Decls: "stroke: #ff0000", Spec: 10101, Order: 0},
+		{Classes: []string{"thin", "cut"}, Decls: "stroke: #ff0000", Spec: 200, Order: 1},
+		{Decls: "fill: none", Spec: 0, Order: 2},
+	}
+	if len(sheet) != len(want) {
+		t.Fatalf("got %d rules %+v, want %d", len(sheet), sheet, len(want))
+	}
+	for i, w := range want {
+		r := sheet[i]
+		if r.Tag != w.Tag || r.ID != w.ID || !slices.Equal(r.Classes, w.Classes) || r.Decls != w.Decls || r.Spec != w.Spec || r.Order != w.Order {
+			t.Errorf("rule %d: %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestStyleSheetPrecedence(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		css   string
+		attrs string
+		want  string
+	}{
+		{"class rule", ".cut{stroke:#ff0000}", `class="cut"`, "#ff0000"},
+		{"class over attribute", ".cut{stroke:#ff0000}", `class="cut" stroke="#0000ff"`, "#ff0000"},
+		{"style over class", ".cut{stroke:#ff0000}", `class="cut" style="stroke:#00ff00"`, "#00ff00"},
+		{"id over class", "#a{stroke:#00ff00} .cut{stroke:#ff0000}", `id="a" class="cut"`, "#00ff00"},
+		{"two classes over one", ".cut.thin{stroke:#00ff00} .cut{stroke:#ff0000}", `class="thin cut"`, "#00ff00"},
+		{"tag and class over class", "path.cut{stroke:#00ff00} .cut{stroke:#ff0000}", `class="cut"`, "#00ff00"},
+		{"class over tag", ".cut{stroke:#00ff00} path{stroke:#ff0000}", `class="cut"`, "#00ff00"},
+		{"later rule wins a tie", ".a{stroke:#ff0000} .b{stroke:#00ff00}", `class="b a"`, "#00ff00"},
+		{"unmatched class", ".cut{stroke:#ff0000}", `class="cutter" stroke="#0000ff"`, "#0000ff"},
+		{"descendant selector ignored", "g path{stroke:#ff0000}", `stroke="#0000ff"`, "#0000ff"},
+		{"rule for another property", ".cut{fill:#ff0000}", `class="cut" stroke="#0000ff"`, "#0000ff"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// the sheet comes last: it applies wherever it is
+			svg := `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100" viewBox="0 0 100 100">` +
+				`<path ` + tc.attrs + ` d="M0 0 L10 0"/><style>` + tc.css + `</style></svg>`
+			paths, _, _, err := parseSVG(strings.NewReader(svg), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 {
+				t.Fatalf("%d paths, want 1", len(paths))
+			}
+			if paths[0].Stroke != tc.want {
+				t.Errorf("stroke %q, want %q", paths[0].Stroke, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Controller compensation (-comp-mode controller) leaves the geometry on
+// the drawn line and lets the controller offset it by the radius in its
+// tool table, so a worn or resharpened tool only needs a new table entry.
+// Each pass is wrapped in a lead-in from the waste side under G41/G42 and
+// a lead-out followed by G40, since the controller needs a move to take
+// up and drop the offset.
+
+// compCode is the G-code that puts the tool on side of the path.
+func compCode(side string) string {
+	if side == "left" {
+		return "G41"
+	}
+	return "G42"
+}
+
+// otherSide swaps left and right, for a path that is reversed or
+// mirrored; "" stays "".
+func otherSide(side string) string {
+	switch side {
+	case "left":
+		return "right"
+	case "right":
+		return "left"
+	}
+	return side
+}
+
+// compSide is the side of travel a compensated closed loop puts the tool
+// on: an inside cut of a counter-clockwise loop runs with the tool on the
+// left.
+func compSide(points []Point, mode string) string {
+	if (signedArea(points) > 0) == (mode == "inside") {
+		return "left"
+	}
+	return "right"
+}
+
+// compLeads returns where the tool plunges before a compensated pass of
+// pts and where it leaves to after, length away from the path ends on
+// the tool's side, which is the waste.
+func compLeads(pts []Point, side string, length float64) (in, out Point) {
+	sign := 1.0
+	if side == "right" {
+		sign = -1
+	}
+	away := func(p, a, b Point) Point {
+		l := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if l < 1e-12 {
+			return p
+		}
+		return Point{X: p.X - (b.Y-a.Y)/l*sign*length, Y: p.Y + (b.X-a.X)/l*sign*length}
+	}
+	n := len(pts)
+	return away(pts[0], pts[0], pts[1]), away(pts[n-1], pts[n-2], pts[n-1])
+}
+
+// planCompPass cuts one depth pass under controller compensation. The
+// tool is above the lead-in point and plunges there with compensation
+// off, in the waste, then feeds onto the path and off it to out.
+func planCompPass(prog *Program, pts []Point, side string, out Point, z, plunge, feed float64, cfg Config) {
+	prog.FeedZ(z, plunge)
+	prog.Raw(fmt.Sprintf("%s D%d  (%s)", compCode(side), cfg.CompD, cfg.Msg.T("comp_on")))
+	for _, pt := range pts {
+		prog.FeedXY(pt.X, pt.Y, feed)
+	}
+	prog.FeedXY(out.X, out.Y, feed)
+	prog.Raw(fmt.Sprintf("G40  (%s)", cfg.Msg.T("comp_off")))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A drawing can carry its own depths: data-depth and data-passes on a
+// shape, or on a group for everything in it, beat -cutz and the
+// -colormap rule for the shape's color. data-depth takes what a colormap
+// depth takes (-3.5, 30% of -stock-thickness, 0.5x of -cutz);
+// data-passes cuts the depth in that many equal passes.
+
+// DepthOverride is the data-depth and data-passes in effect for a path.
+type DepthOverride struct {
+	Rule   ColorRule // only the depth fields are set
+	Passes int       // equal passes to reach the depth; 0 = by step-down
+}
+
+// elementDepth reads the data-depth and data-passes of an element on top
+// of those it inherits. Values that don't parse are warned about and
+// ignored.
+func elementDepth(attrs []xml.Attr, parent *DepthOverride, warn *Warnings) *DepthOverride {
+	var depth, passes string
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "data-depth":
+			depth = strings.TrimSpace(a.Value)
+		case "data-passes":
+			passes = strings.TrimSpace(a.Value)
+		}
+	}
+	if depth == "" && passes == "" {
+		return parent
+	}
+	o := DepthOverride{}
+	if parent != nil {
+		o = *parent
+	}
+	if depth != "" {
+		var r ColorRule
+		if err := r.set("depth", depth); err != nil {
+			warn.Add(WIgnoredOption, 0, "data-depth=%q: %v; ignored", depth, err)
+		} else {
+			o.Rule = r
+		}
+	}
+	if passes != "" {
+		n, err := strconv.Atoi(passes)
+		if err != nil || n < 1 {
+			warn.Add(WIgnoredOption, 0, "data-passes=%q: must be a whole number >= 1; ignored", passes)
+		} else {
+			o.Passes = n
+		}
+	}
+	return &o
+}
+
+// rule is the color rule for p's stroke with the drawing's own depth and
+// passes for p applied.
+func (p Path) rule(cfg Config) ColorRule {
+	r := cfg.ColorMap.rule(p.Stroke)
+	o := p.Override
+	if o == nil {
+		return r
+	}
+	if o.Rule.Depth != 0 || o.Rule.DepthPct != 0 || o.Rule.DepthFrac != 0 {
+		r.Depth, r.DepthPct, r.DepthFrac = o.Rule.Depth, o.Rule.DepthPct, o.Rule.DepthFrac
+	}
+	if o.Passes > 0 {
+		r.StepDown = -r.targetZ(cfg) / float64(o.Passes)
+	}
+	return r
+}
+
+// checkOverrides makes sure every data-depth can be resolved.
+func checkOverrides(paths []Path, cfg Config) error {
+	for _, p := range paths {
+		if o := p.Override; o != nil && o.Rule.DepthPct > 0 && cfg.StockThickness <= 0 {
+			return fmt.Errorf("path %d: data-depth %g%% needs -stock-thickness", p.Index, o.Rule.DepthPct)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+// Machines that can't vary their depth smoothly, or drag engravers that
+// only mark or don't, need an image in black and white. -dither turns
+// the brightness samples of a -heightmap relief into 0 or 1, so every
+// sample is cut to full depth or left alone.
+
+// bayer4 is the 4x4 ordered-dither matrix.
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// dither turns light, rows of brightness from 0 black to 1 white, into
+// black and white in place: by a fixed threshold, a Bayer pattern, or
+// Floyd-Steinberg error diffusion. "none" and "" leave it as it is.
+func dither(light [][]float64, mode string) {
+	switch mode {
+	case "threshold":
+		for _, row := range light {
+			for c, v := range row {
+				row[c] = bw(v, 0.5)
+			}
+		}
+	case "ordered":
+		for r, row := range light {
+			for c, v := range row {
+				row[c] = bw(v, (bayer4[r%4][c%4]+0.5)/16)
+			}
+		}
+	case "floyd-steinberg":
+		for r, row := range light {
+			for c, v := range row {
+				row[c] = bw(v, 0.5)
+				e := v - row[c]
+				spread := func(r, c int, f float64) {
+					if r < len(light) && c >= 0 && c < len(light[r]) {
+						light[r][c] += e * f
+					}
+				}
+				spread(r, c+1, 7.0/16)
+				spread(r+1, c-1, 3.0/16)
+				spread(r+1, c, 5.0/16)
+				spread(r+1, c+1, 1.0/16)
+			}
+		}
+	}
+}
+
+// bw is 1 for white at or above the threshold, else 0.
+func bw(v, threshold float64) float64 {
+	if v >= threshold {
+		return 1
+	}
+	return 0
+}
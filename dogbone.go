@@ -0,0 +1,81 @@
+package main
+
+import "math"
+
+// dogbones adds an overcut at every corner of a compensated loop where
+// a round tool leaves material standing: the corners where the loop turns
+// away from the wall it cuts, such as the corners of a pocket or the
+// inside corners of a part. At such a vertex the tool runs out towards
+// the corner until its edge touches it and comes straight back, so a
+// square part fits. axis is "bisector" to run out along the corner's
+// bisector (a dog-bone), or "x" or "y" to run out along that axis (a
+// T-bone, which hides the overcut along one edge). delta is the distance
+// from the loop to the wall, the tool radius plus any allowance.
+func dogbones(loop []Point, delta float64, mode, axis string) []Point {
+	pts := dedupePoints(append([]Point(nil), loop...))
+	for len(pts) > 1 && almostEqualPoint(pts[len(pts)-1], pts[0]) {
+		pts = pts[:len(pts)-1]
+	}
+	n := len(pts)
+	if n < 3 || delta <= 0 {
+		return loop
+	}
+	// inside a pocket the wall lies outside the loop, around a part
+	// inside it
+	wallLeft := (signedArea(pts) > 0) == (mode == "outside")
+
+	out := make([]Point, 0, n+1)
+	for i, v := range pts {
+		a, b := pts[(i+n-1)%n], pts[(i+1)%n]
+		e0 := Point{X: v.X - a.X, Y: v.Y - a.Y}
+		e1 := Point{X: b.X - v.X, Y: b.Y - v.Y}
+		out = append(out, v)
+		turn := cross(e0, e1)
+		if math.Abs(turn) < 1e-9 || (turn > 0) == wallLeft {
+			continue // straight on, or turning around material
+		}
+		w0, w1 := wallNormal(e0, wallLeft), wallNormal(e1, wallLeft)
+		k := delta / (1 + w0.X*w1.X + w0.Y*w1.Y)
+		corner := Point{X: v.X + (w0.X+w1.X)*k, Y: v.Y + (w0.Y+w1.Y)*k}
+		if t, ok := overcut(v, corner, axis, delta); ok {
+			out = append(out, t, v)
+		}
+	}
+	return append(out, out[0])
+}
+
+// wallNormal is the unit normal of edge e on the wall side.
+func wallNormal(e Point, left bool) Point {
+	l := math.Hypot(e.X, e.Y)
+	if left {
+		return Point{X: -e.Y / l, Y: e.X / l}
+	}
+	return Point{X: e.Y / l, Y: -e.X / l}
+}
+
+// overcut is where the tool centre goes from v so that its edge, radius
+// r, reaches corner: along the bisector, or along the X or Y axis. A
+// corner the axis move cannot reach, one not square to the axes, gets a
+// bisector move instead. It reports false when the corner is within
+// reach already.
+func overcut(v, corner Point, axis string, r float64) (Point, bool) {
+	d := Point{X: corner.X - v.X, Y: corner.Y - v.Y}
+	l := math.Hypot(d.X, d.Y)
+	if l-r < restTol {
+		return Point{}, false // less than is worth a move
+	}
+	dir := Point{X: d.X / l, Y: d.Y / l}
+	switch axis {
+	case "x":
+		if math.Abs(d.Y) <= r+1e-9 {
+			dir = Point{X: math.Copysign(1, d.X)}
+		}
+	case "y":
+		if math.Abs(d.X) <= r+1e-9 {
+			dir = Point{Y: math.Copysign(1, d.Y)}
+		}
+	}
+	across := math.Abs(cross(dir, d))
+	t := d.X*dir.X + d.Y*dir.Y - math.Sqrt(math.Max(0, r*r-across*across))
+	return Point{X: v.X + dir.X*t, Y: v.Y + dir.Y*t}, true
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// dxfUnits maps $INSUNITS to millimetres per drawing unit. Unitless
+// drawings are taken to be in mm.
+var dxfUnits = map[int]float64{
+	0: 1, 1: 25.4, 2: 304.8, 4: 1, 5: 10, 6: 1000, 8: 0.0000254, 9: 0.0254, 10: 914.4,
+}
+
+// dxfColors are the AutoCAD Color Index entries with an obvious name.
+// Index 7 is white on a dark screen but black on paper.
+var dxfColors = map[int]string{
+	1: "#ff0000", 2: "#ffff00", 3: "#00ff00", 4: "#00ffff", 5: "#0000ff", 6: "#ff00ff", 7: "#000000",
+}
+
+// dxfPair is one group code and its value.
+type dxfPair struct {
+	Code  int
+	Value string
+}
+
+// dxfEntity is an entity's group codes, without the leading 0/TYPE pair.
+type dxfEntity struct {
+	Type  string
+	Pairs []dxfPair
+}
+
+func (e dxfEntity) str(code int) string {
+	for _, p := range e.Pairs {
+		if p.Code == code {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func (e dxfEntity) num(code int) float64 {
+	v, _ := strconv.ParseFloat(e.str(code), 64)
+	return v
+}
+
+func (e dxfEntity) int(code int) int {
+	v, _ := strconv.Atoi(e.str(code))
+	return v
+}
+
+// points collects the repeated x/y group codes (10/20, 11/21, ...) in order.
+func (e dxfEntity) points(xCode int) []Point {
+	var pts []Point
+	for _, p := range e.Pairs {
+		v, _ := strconv.ParseFloat(p.Value, 64)
+		switch p.Code {
+		case xCode:
+			pts = append(pts, Point{X: v})
+		case xCode + 10:
+			if len(pts) > 0 {
+				pts[len(pts)-1].Y = v
+			}
+		}
+	}
+	return pts
+}
+
+func (e dxfEntity) nums(code int) []float64 {
+	var vs []float64
+	for _, p := range e.Pairs {
+		if p.Code == code {
+			v, _ := strconv.ParseFloat(p.Value, 64)
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// readDXF splits an ASCII DXF file into group code/value pairs.
+func readDXF(r io.Reader) ([]dxfPair, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var pairs []dxfPair
+	for sc.Scan() {
+		code, err := strconv.Atoi(strings.TrimSpace(sc.Text()))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: group code %q is not a number (binary DXF is not supported)",
+				2*len(pairs)+1, truncate(sc.Text(), 20))
+		}
+		if !sc.Scan() {
+			return nil, fmt.Errorf("group code %d has no value", code)
+		}
+		pairs = append(pairs, dxfPair{code, strings.TrimSpace(sc.Text())})
+	}
+	return pairs, sc.Err()
+}
+
+// parseDXF reads LINE, LWPOLYLINE, ARC, CIRCLE and SPLINE entities from
+// the ENTITIES section of an ASCII DXF into paths in mm, Y up. w and h
+// are the far corner of the drawing, for placement. Other entities are
+// reported to warn.
+func parseDXF(r io.Reader, warn *Warnings) (paths []Path, w, h float64, err error) {
+	pairs, err := readDXF(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	// split into sections and entities
+	unit := 1.0
+	layers := map[string]string{}
+	layerOff := map[string]bool{}
+	var ents []dxfEntity
+	section := ""
+	for i := 0; i < len(pairs); i++ {
+		p := pairs[i]
+		if p.Code != 0 {
+			continue
+		}
+		e := dxfEntity{Type: p.Value}
+		for i+1 < len(pairs) && pairs[i+1].Code != 0 {
+			i++
+			e.Pairs = append(e.Pairs, pairs[i])
+		}
+		switch {
+		case e.Type == "SECTION":
+			section = e.str(2)
+			if section == "HEADER" {
+				// header variables are 9/$NAME pairs followed by values
+				for j, hp := range e.Pairs {
+					if hp.Code == 9 && hp.Value == "$INSUNITS" && j+1 < len(e.Pairs) {
+						n, _ := strconv.Atoi(e.Pairs[j+1].Value)
+						if u, ok := dxfUnits[n]; ok {
+							unit = u
+						}
+					}
+				}
+			}
+		case e.Type == "ENDSEC":
+			section = ""
+		case section == "TABLES" && e.Type == "LAYER":
+			// a negative color marks a layer that is switched off
+			layers[e.str(2)] = dxfColors[max(e.int(62), -e.int(62))]
+			layerOff[e.str(2)] = e.int(62) < 0
+		case section == "ENTITIES":
+			ents = append(ents, e)
+		}
+	}
+
+	const flatness = 0.1 // mm
+	skipped := map[string]int{}
+	badRadius := map[string]int{}
+	for _, e := range ents {
+		if layerOff[e.str(8)] {
+			continue
+		}
+		if (e.Type == "ARC" || e.Type == "CIRCLE") && !(e.num(40) > 0) {
+			// a broken export: a point, or a mirror image of the circle
+			badRadius[e.Type]++
+			continue
+		}
+		var pts []Point
+		closed := false
+		switch e.Type {
+		case "LINE":
+			pts = []Point{{e.num(10), e.num(20)}, {e.num(11), e.num(21)}}
+		case "LWPOLYLINE":
+			pts, closed = lwPolyline(e, flatness/unit)
+		case "ARC":
+			a0 := e.num(50) * math.Pi / 180
+			sweep := math.Mod(e.num(51)-e.num(50)+360, 360) * math.Pi / 180
+			if sweep == 0 {
+				sweep = 2 * math.Pi
+			}
+			pts = arcPoints(Point{e.num(10), e.num(20)}, e.num(40), a0, sweep, flatness/unit)
+		case "CIRCLE":
+			pts = circlePoints(Point{e.num(10), e.num(20)}, e.num(40), flatness/unit)
+			closed = true
+		case "SPLINE":
+			pts, closed = splinePoints(e)
+		case "SEQEND", "VIEWPORT":
+			continue
+		default:
+			skipped[e.Type]++
+			continue
+		}
+		if len(pts) < 2 {
+			continue
+		}
+		for i := range pts {
+			pts[i].X *= unit
+			pts[i].Y *= unit
+		}
+		pts = dedupePoints(pts)
+		if closed && !almostEqualPoint(pts[0], pts[len(pts)-1]) {
+			pts = append(pts, pts[0])
+		}
+		color := ""
+		switch n := e.int(62); {
+		case n == 0 || n == 256: // BYBLOCK, BYLAYER
+			color = layers[e.str(8)]
+		case n > 0:
+			color = dxfColors[n]
+		}
+		if tc := e.str(420); tc != "" {
+			if v, err := strconv.Atoi(tc); err == nil {
+				color = fmt.Sprintf("#%06x", v&0xffffff)
+			}
+		}
+		paths = append(paths, Path{
+			Points:    pts,
+			Closed:    closed,
+			Stroke:    color,
+			Transform: identityTransform(),
+			Index:     len(paths) + 1,
+			Shape:     len(paths) + 1,
+			Layer:     e.str(8),
+		})
+	}
+	for typ, n := range skipped {
+		warn.Add(WUnsupportedCommand, 0, "%d DXF %s entities skipped", n, typ)
+	}
+	for _, typ := range []string{"ARC", "CIRCLE"} {
+		if n := badRadius[typ]; n > 0 {
+			warn.Add(WUnsupportedCommand, 0, "%d DXF %s entities with a radius of zero or less skipped", n, typ)
+		}
+	}
+
+	if b, ok := pathBounds(paths); ok {
+		w, h = math.Max(b.MaxX, 0), math.Max(b.MaxY, 0)
+	}
+	return paths, w, h, nil
+}
+
+// lwPolyline flattens an LWPOLYLINE, turning bulged segments into arcs.
+func lwPolyline(e dxfEntity, flatness float64) ([]Point, bool) {
+	type vertex struct {
+		P     Point
+		Bulge float64
+	}
+	var vs []vertex
+	for _, p := range e.Pairs {
+		v, _ := strconv.ParseFloat(p.Value, 64)
+		switch p.Code {
+		case 10:
+			vs = append(vs, vertex{P: Point{X: v}})
+		case 20:
+			if len(vs) > 0 {
+				vs[len(vs)-1].P.Y = v
+			}
+		case 42:
+			if len(vs) > 0 {
+				vs[len(vs)-1].Bulge = v
+			}
+		}
+	}
+	if len(vs) == 0 {
+		return nil, false
+	}
+	closed := e.int(70)&1 != 0
+	pts := []Point{vs[0].P}
+	n := len(vs)
+	if closed {
+		n++ // back to the first vertex
+	}
+	for i := 1; i < n; i++ {
+		a, b := vs[i-1], vs[i%len(vs)]
+		if a.Bulge == 0 {
+			pts = append(pts, b.P)
+			continue
+		}
+		// bulge = tan(sweep/4); the centre is on the chord's bisector
+		sweep := 4 * math.Atan(a.Bulge)
+		chord := math.Hypot(b.P.X-a.P.X, b.P.Y-a.P.Y)
+		r := chord / 2 / math.Sin(sweep/2)
+		mid := lerp(a.P, b.P, 0.5)
+		d := r * math.Cos(sweep/2) // signed distance from chord to centre
+		c := Point{X: mid.X - (b.P.Y-a.P.Y)/chord*d, Y: mid.Y + (b.P.X-a.P.X)/chord*d}
+		a0 := math.Atan2(a.P.Y-c.Y, a.P.X-c.X)
+		arc := arcPoints(c, math.Abs(r), a0, sweep, flatness)
+		arc[len(arc)-1] = b.P
+		pts = append(pts, arc[1:]...)
+	}
+	return pts, closed
+}
+
+// splinePoints samples a (possibly rational) B-spline from its control
+// points, knots and weights; splines given only by fit points are run
+// through them as a polyline.
+func splinePoints(e dxfEntity) ([]Point, bool) {
+	closed := e.int(70)&1 != 0
+	ctrl := e.points(10)
+	knots := e.nums(40)
+	deg := e.int(71)
+	if len(ctrl) == 0 || deg < 1 || len(knots) != len(ctrl)+deg+1 {
+		return e.points(11), closed
+	}
+	weights := e.nums(41)
+	if len(weights) != len(ctrl) {
+		weights = make([]float64, len(ctrl))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	// de Boor evaluation in homogeneous coordinates
+	eval := func(t float64) Point {
+		k := deg
+		for k < len(ctrl)-1 && t >= knots[k+1] {
+			k++
+		}
+		type hp struct{ x, y, w float64 }
+		d := make([]hp, deg+1)
+		for j := range d {
+			c, w := ctrl[k-deg+j], weights[k-deg+j]
+			d[j] = hp{c.X * w, c.Y * w, w}
+		}
+		for r := 1; r <= deg; r++ {
+			for j := deg; j >= r; j-- {
+				i := k - deg + j
+				den := knots[i+deg+1-r] - knots[i]
+				a := 0.0
+				if den != 0 {
+					a = (t - knots[i]) / den
+				}
+				d[j] = hp{(1-a)*d[j-1].x + a*d[j].x, (1-a)*d[j-1].y + a*d[j].y, (1-a)*d[j-1].w + a*d[j].w}
+			}
+		}
+		return Point{X: d[deg].x / d[deg].w, Y: d[deg].y / d[deg].w}
+	}
+
+	t0, t1 := knots[deg], knots[len(ctrl)]
+	n := 16 * len(ctrl)
+	pts := make([]Point, 0, n+1)
+	for i := 0; i <= n; i++ {
+		pts = append(pts, eval(t0+(t1-t0)*float64(i)/float64(n)))
+	}
+	return pts, closed
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// dxfDoc builds an ASCII DXF from group code/value lines, with the
+// entities in an ENTITIES section after an optional header.
+func dxfDoc(header string, entities ...string) string {
+	var b strings.Builder
+	if header != "" {
+		b.WriteString("0\nSECTION\n2\nHEADER\n" + header + "\n0\nENDSEC\n")
+	}
+	b.WriteString("0\nSECTION\n2\nENTITIES\n")
+	for _, e := range entities {
+		b.WriteString(e + "\n")
+	}
+	b.WriteString("0\nENDSEC\n0\nEOF\n")
+	return b.String()
+}
+
+func TestReadDXF(t *testing.T) {
+	pairs, err := readDXF(strings.NewReader("  0\r\nLINE \n 10\n1.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dxfPair{{0, "LINE"}, {10, "1.5"}}
+	if len(pairs) != len(want) || pairs[0] != want[0] || pairs[1] != want[1] {
+		t.Errorf("pairs %v, want %v", pairs, want)
+	}
+	for _, src := range []string{"AutoCAD Binary DXF\n", "0\nLINE\n10\n"} {
+		if _, err := readDXF(strings.NewReader(src)); err == nil {
+			t.Errorf("%q was accepted", src)
+		}
+	}
+}
+
+func TestParseDXF(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		src        string
+		closed     bool
+		start, end Point
+		minY, maxY float64
+		centre     Point   // with r, every point must lie on this circle
+		r          float64 // 0 = not an arc
+	}{
+		{
+			name:  "line",
+			src:   dxfDoc("", "0\nLINE\n8\n0\n10\n0\n20\n0\n11\n10\n21\n5"),
+			start: Point{0, 0}, end: Point{10, 5}, minY: 0, maxY: 5,
+		},
+		{
+			name:   "circle",
+			src:    dxfDoc("", "0\nCIRCLE\n10\n10\n20\n10\n40\n5"),
+			closed: true, start: Point{15, 10}, end: Point{15, 10}, minY: 5, maxY: 15,
+			centre: Point{10, 10}, r: 5,
+		},
+		{
+			name:  "arc counter-clockwise from 0 to 90 degrees",
+			src:   dxfDoc("", "0\nARC\n10\n0\n20\n0\n40\n10\n50\n0\n51\n90"),
+			start: Point{10, 0}, end: Point{0, 10}, minY: 0, maxY: 10,
+			centre: Point{0, 0}, r: 10,
+		},
+		{
+			name:  "lwpolyline with a half-circle bulge",
+			src:   dxfDoc("", "0\nLWPOLYLINE\n90\n2\n70\n0\n10\n0\n20\n0\n42\n1\n10\n10\n20\n0"),
+			start: Point{0, 0}, end: Point{10, 0}, minY: -5, maxY: 0,
+			centre: Point{5, 0}, r: 5,
+		},
+		{
+			name:   "closed lwpolyline",
+			src:    dxfDoc("", "0\nLWPOLYLINE\n90\n3\n70\n1\n10\n0\n20\n0\n10\n10\n20\n0\n10\n10\n20\n10"),
+			closed: true, start: Point{0, 0}, end: Point{0, 0}, minY: 0, maxY: 10,
+		},
+		{
+			name:  "quadratic spline from control points",
+			src:   dxfDoc("", "0\nSPLINE\n70\n8\n71\n2\n40\n0\n40\n0\n40\n0\n40\n1\n40\n1\n40\n1\n10\n0\n20\n0\n10\n5\n20\n10\n10\n10\n20\n0"),
+			start: Point{0, 0}, end: Point{10, 0}, minY: 0, maxY: 5,
+		},
+		{
+			name:  "spline from fit points",
+			src:   dxfDoc("", "0\nSPLINE\n70\n8\n71\n3\n11\n0\n21\n0\n11\n5\n21\n5\n11\n10\n21\n0"),
+			start: Point{0, 0}, end: Point{10, 0}, minY: 0, maxY: 5,
+		},
+		{
+			name:  "inch drawing",
+			src:   dxfDoc("9\n$INSUNITS\n70\n1", "0\nLINE\n10\n0\n20\n0\n11\n1\n21\n1"),
+			start: Point{0, 0}, end: Point{25.4, 25.4}, minY: 0, maxY: 25.4,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, _, _, err := parseDXF(strings.NewReader(tc.src), &Warnings{Out: io.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 {
+				t.Fatalf("%d paths, want 1", len(paths))
+			}
+			p := paths[0]
+			if p.Closed != tc.closed {
+				t.Errorf("closed %v, want %v", p.Closed, tc.closed)
+			}
+			if start, end := p.Points[0], p.Points[len(p.Points)-1]; dist(start, tc.start) > 1e-9 || dist(end, tc.end) > 1e-9 {
+				t.Errorf("runs from %v to %v, want %v to %v", start, end, tc.start, tc.end)
+			}
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, pt := range p.Points {
+				lo, hi = math.Min(lo, pt.Y), math.Max(hi, pt.Y)
+				if tc.r > 0 && math.Abs(dist(pt, tc.centre)-tc.r) > 1e-6 {
+					t.Fatalf("%v is %g from %v, want %g", pt, dist(pt, tc.centre), tc.centre, tc.r)
+				}
+			}
+			if math.Abs(lo-tc.minY) > 0.01 || math.Abs(hi-tc.maxY) > 0.01 {
+				t.Errorf("Y from %g to %g, want %g to %g", lo, hi, tc.minY, tc.maxY)
+			}
+		})
+	}
+}
+
+func TestParseDXFSkipped(t *testing.T) {
+	warn := &Warnings{Out: io.Discard}
+	src := dxfDoc("",
+		"0\nCIRCLE\n10\n0\n20\n0\n40\n0",
+		"0\nCIRCLE\n10\n0\n20\n0\n40\n-3",
+		"0\nARC\n10\n0\n20\n0\n50\n0\n51\n90",
+		"0\nTEXT\n1\nhello",
+		"0\nLINE\n10\n0\n20\n0\n11\n10\n21\n0",
+	)
+	paths, _, _, err := parseDXF(strings.NewReader(src), warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("%d paths, want only the line", len(paths))
+	}
+	var msgs []string
+	for _, w := range warn.List {
+		msgs = append(msgs, w.Message)
+	}
+	got := strings.Join(msgs, "; ")
+	for _, want := range []string{
+		"1 DXF TEXT entities skipped",
+		"1 DXF ARC entities with a radius of zero or less skipped",
+		"2 DXF CIRCLE entities with a radius of zero or less skipped",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("warnings %q lack %q", got, want)
+		}
+	}
+	if len(warn.List) != 3 {
+		t.Errorf("%d warnings, want 3: %q", len(warn.List), got)
+	}
+}
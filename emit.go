@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// gcodeFormat controls how numbers are written. Moves are always planned
+// in mm; Factor converts them to output units.
+type gcodeFormat struct {
+	Factor float64 // output units per mm
+	Digits int     // decimal places
+
+	// LineNumbers prefixes every block with an N word; Checksums also
+	// appends the *NN checksum Marlin-style serial links expect, which
+	// implies line numbers. Comment-only and blank lines stay bare.
+	LineNumbers bool
+	Checksums   bool
+
+	// Modal leaves out the motion word, F and S when they repeat those of
+	// the previous block, as every controller keeps them modal.
+	Modal bool
+}
+
+// modalState is what the controller remembers between blocks.
+type modalState struct {
+	code  string // last motion word, "" = unknown
+	feed  string // last F value as written, "" = unknown
+	power string // last S value as written, "" = unknown
+}
+
+var (
+	formatMM   = gcodeFormat{Factor: 1, Digits: 3}
+	formatInch = gcodeFormat{Factor: 1 / 25.4, Digits: 4}
+)
+
+func (f gcodeFormat) num(v float64) string {
+	return string(f.appendNum(nil, v))
+}
+
+// appendNum is num into a buffer, which is what the emitter uses: a
+// program can run to millions of numbers.
+func (f gcodeFormat) appendNum(b []byte, v float64) []byte {
+	return strconv.AppendFloat(b, v*f.Factor, 'f', f.Digits, 64)
+}
+
+// emitGcode writes a planned program as G-code. Lines are built in one
+// reused buffer and written through a bufio.Writer, so a long program
+// costs neither an allocation nor a system call per block.
+func emitGcode(w io.Writer, moves []Move, f gcodeFormat) error {
+	bw := bufio.NewWriterSize(w, 64<<10)
+	n := 0
+	var st *modalState
+	if f.Modal {
+		st = &modalState{}
+	}
+	var line, numbered []byte
+	for _, m := range moves {
+		line = line[:0]
+		switch m.Kind {
+		case MoveRapid, MoveFeed, MoveArcCW, MoveArcCCW:
+			line = f.appendMotion(line, m, st)
+		case MoveComment:
+			line = append(append(line, "; "...), m.Text...)
+		case MoveRaw:
+			line = append(line, m.Text...)
+			if st != nil && isBlock(m.Text) {
+				// probing and the like may change motion mode and feed
+				*st = modalState{}
+			}
+		default:
+			continue
+		}
+		out := line
+		if (f.LineNumbers || f.Checksums) && isBlock(string(line)) {
+			n++
+			numbered = appendNumbered(numbered[:0], line, n, f.Checksums)
+			out = numbered
+		}
+		out = append(out, '\n')
+		if _, err := bw.Write(out); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// isBlock reports whether a line holds a command rather than only a
+// comment or nothing.
+func isBlock(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && line[0] != ';' && line[0] != '('
+}
+
+// appendNumbered appends line to b prefixed with N<n> and, if checksum
+// is set, followed by *<xor of every byte before the asterisk>, the form
+// Marlin and similar firmware verify on serial links.
+func appendNumbered(b, line []byte, n int, checksum bool) []byte {
+	start := len(b)
+	b = append(strconv.AppendInt(append(b, 'N'), int64(n), 10), ' ')
+	b = append(b, line...)
+	if !checksum {
+		return b
+	}
+	var cs byte
+	for _, c := range b[start:] {
+		cs ^= c
+	}
+	return strconv.AppendInt(append(b, '*'), int64(cs), 10)
+}
+
+// appendMotion appends one motion block to b. With st set, words the
+// controller already holds are left out and st is updated.
+func (f gcodeFormat) appendMotion(b []byte, m Move, st *modalState) []byte {
+	code := "G0"
+	switch m.Kind {
+	case MoveFeed:
+		code = "G1"
+	case MoveArcCW:
+		code = "G2"
+	case MoveArcCCW:
+		code = "G3"
+	}
+	start := len(b)
+	if st == nil || code != st.code {
+		b = append(b, code...)
+	}
+	if st != nil {
+		st.code = code
+	}
+	word := func(letter byte, v float64) {
+		if len(b) > start {
+			b = append(b, ' ')
+		}
+		b = f.appendNum(append(b, letter), v)
+	}
+	if m.Axes&AxisX != 0 {
+		word('X', m.X)
+	}
+	if m.Axes&AxisY != 0 {
+		word('Y', m.Y)
+	}
+	if m.Axes&AxisZ != 0 {
+		word('Z', m.Z)
+	}
+	if m.isArc() {
+		word('I', m.I)
+		word('J', m.J)
+	}
+	if m.Kind != MoveRapid {
+		at := len(b)
+		word('F', m.F)
+		feed := b[at:]
+		if len(feed) > 0 && feed[0] == ' ' {
+			feed = feed[1:]
+		}
+		feed = feed[1:] // the F
+		if st != nil {
+			if string(feed) == st.feed {
+				b = b[:at]
+			} else {
+				st.feed = string(feed)
+			}
+		}
+	}
+	if m.Axes&AxisS != 0 {
+		// power is in the controller's own units, never scaled to inches
+		at := len(b) + 1
+		b = strconv.AppendFloat(append(b, " S"...), m.S, 'f', -1, 64)
+		if st != nil {
+			if string(b[at:]) == st.power {
+				b = b[:at-1]
+			} else {
+				st.power = string(b[at:])
+			}
+		}
+	}
+	return b
+}
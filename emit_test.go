@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+// benchMoves plans a job of many small circles in several passes, the
+// shape of program where formatting dominates: over 100000 blocks.
+func benchMoves() []Move {
+	cfg := Config{SafeZ: 5}
+	prog := newProgram(cfg.SafeZ)
+	for i := range 500 {
+		c := Point{X: float64(i%25) * 12, Y: float64(i/25) * 12}
+		pts := circlePoints(c, 5, 0.01)
+		hop(prog, pts[0].X, pts[0].Y, cfg)
+		for _, z := range passDepths(-3, 0.5) {
+			prog.FeedZ(z, 120)
+			for _, p := range pts[1:] {
+				prog.FeedXY(p.X, p.Y, 300)
+			}
+		}
+	}
+	return prog.Moves
+}
+
+func BenchmarkEmitGcode(b *testing.B) {
+	moves := benchMoves()
+	for _, bc := range []struct {
+		name string
+		f    gcodeFormat
+	}{
+		{"plain", formatMM},
+		{"modal", gcodeFormat{Factor: 1, Digits: 3, Modal: true}},
+		{"checksums", gcodeFormat{Factor: 1, Digits: 3, LineNumbers: true, Checksums: true}},
+		{"inch", formatInch},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := emitGcode(io.Discard, moves, bc.f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
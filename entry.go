@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Entry describes one depth pass the planner is about to cut.
+type Entry struct {
+	Points []Point // pass geometry; the tool must end at Points[0], at Z
+	Closed bool
+	Z      float64 // depth of this pass
+	Prev   float64 // depth already cut by earlier passes, 0 on the first
+	Target float64 // final depth of the path
+	First  bool    // first pass of the path
+	Plunge float64 // plunge feed, mm/min
+	Feed   float64 // XY feed of the pass, mm/min
+	Comp   string  // side the path was offset to, as Path.Comp; "" = cut as drawn
+}
+
+// EntryStrategy takes the tool into the material at the start of a pass.
+// Enter is called with the tool above Points[0] (at safe or retract Z,
+// or at the previous depth when ping-ponging) and must leave it at
+// Points[0] at depth Z; the planner then cuts Points[1:].
+//
+// New techniques implement EntryStrategy and are added to parseEntry.
+type EntryStrategy interface {
+	Enter(prog *Program, e Entry)
+}
+
+// entryNames lists the -entry choices in help order.
+var entryNames = []string{"straight", "ramp", "helix", "pre-drill", "lead-in"}
+
+// parseEntry builds the strategy for an -entry name.
+func parseEntry(name string, angle, leadIn float64, cfg Config) (EntryStrategy, error) {
+	switch strings.ToLower(name) {
+	case "straight", "":
+		return straightEntry{}, nil
+	case "ramp":
+		if angle <= 0 || angle >= 90 {
+			return nil, fmt.Errorf("-ramp-angle must be in (0,90)")
+		}
+		return rampEntry{Angle: angle}, nil
+	case "helix":
+		if cfg.ToolDia <= 0 {
+			return nil, fmt.Errorf("-entry helix needs -tooldia")
+		}
+		if angle <= 0 || angle >= 90 {
+			return nil, fmt.Errorf("-ramp-angle must be in (0,90)")
+		}
+		return helixEntry{Radius: cfg.ToolDia / 4, Angle: angle}, nil
+	case "pre-drill":
+		peck := cfg.StepDown
+		if peck <= 0 {
+			peck = cfg.ToolDia
+		}
+		return preDrillEntry{Peck: peck, ClearZ: cfg.retractZ()}, nil
+	case "lead-in":
+		if leadIn <= 0 {
+			leadIn = cfg.ToolDia
+		}
+		if leadIn <= 0 {
+			return nil, fmt.Errorf("-entry lead-in needs -lead-in or -tooldia")
+		}
+		return leadInEntry{Length: leadIn}, nil
+	}
+	return nil, fmt.Errorf("invalid -entry %q (must be %s)", name, strings.Join(entryNames, ", "))
+}
+
+// straightEntry plunges vertically at the plunge feed.
+type straightEntry struct{}
+
+func (straightEntry) Enter(prog *Program, e Entry) {
+	prog.FeedZ(e.Z, e.Plunge)
+}
+
+// rampEntry descends along the path at Angle degrees, out and back, so
+// the cutter enters the material sideways. A path shorter than the ramp
+// is traversed back and forth as often as needed.
+type rampEntry struct {
+	Angle float64
+}
+
+func (r rampEntry) Enter(prog *Program, e Entry) {
+	if prog.z > e.Prev {
+		prog.FeedZ(e.Prev, e.Plunge)
+	}
+	total := (prog.z - e.Z) / math.Tan(r.Angle*math.Pi/180)
+	reach := math.Min(total/2, polylineLength(e.Points))
+	if total <= 1e-9 || reach <= 1e-9 {
+		prog.FeedZ(e.Z, e.Plunge)
+		return
+	}
+	trips := math.Ceil(total / (2 * reach))
+	fwd := polylinePrefix(e.Points, reach)
+	back := reversePoints(fwd)
+	z0, dz := prog.z, (prog.z-e.Z)/(trips*2*reach)
+	walked := 0.0
+	for t := 0; t < int(trips); t++ {
+		for _, leg := range [][]Point{fwd, back} {
+			for i := 1; i < len(leg); i++ {
+				walked += math.Hypot(leg[i].X-leg[i-1].X, leg[i].Y-leg[i-1].Y)
+				prog.FeedXYZ(leg[i].X, leg[i].Y, math.Max(e.Z, z0-walked*dz), e.Feed)
+			}
+		}
+	}
+	if prog.z > e.Z+1e-9 {
+		prog.FeedZ(e.Z, e.Plunge)
+	}
+}
+
+// helixEntry spirals down on a small circle that starts and ends at the
+// path start. On compensated paths the circle lies in the waste, touching
+// the path and turning the way it goes, so it never swings into the
+// part: where the start is a corner the waste closes in on, the circle
+// sits in the corner, touching both sides, and the tool feeds back along
+// the path to the start. On anything else it is centred a Radius ahead
+// along the first segment.
+type helixEntry struct {
+	Radius float64
+	Angle  float64 // descent angle in degrees
+}
+
+func (h helixEntry) Enter(prog *Program, e Entry) {
+	if prog.z > e.Prev {
+		prog.FeedZ(e.Prev, e.Plunge)
+	}
+	p0 := e.Points[0]
+	dir := Point{X: 1}
+	if len(e.Points) > 1 {
+		if d := math.Hypot(e.Points[1].X-p0.X, e.Points[1].Y-p0.Y); d > 1e-9 {
+			dir = Point{X: (e.Points[1].X - p0.X) / d, Y: (e.Points[1].Y - p0.Y) / d}
+		}
+	}
+	cx, cy := p0.X+dir.X*h.Radius, p0.Y+dir.Y*h.Radius
+	cw := false
+	at := p0 // where the helix starts and ends
+	if off, ok := wasteSide(e.Points, dir, e.Closed, e.Comp); ok {
+		cw = cross(dir, off) < 0 // waste on the right: clockwise leaves along dir
+		if e.Closed {
+			// the side before the start, seen from it
+			for i := len(e.Points) - 1; i > 0; i-- {
+				q := e.Points[i]
+				d := math.Hypot(q.X-p0.X, q.Y-p0.Y)
+				if d <= 1e-9 {
+					continue
+				}
+				u := Point{X: (q.X - p0.X) / d, Y: (q.Y - p0.Y) / d}
+				a := math.Atan2(cross(dir, u), dir.X*u.X+dir.Y*u.Y)
+				if cw {
+					a = -a
+				}
+				if a > 1e-9 && a < math.Pi { // the waste's angle at the start
+					first := math.Hypot(e.Points[1].X-p0.X, e.Points[1].Y-p0.Y)
+					s := math.Min(h.Radius/math.Tan(a/2), first)
+					at = Point{X: p0.X + dir.X*s, Y: p0.Y + dir.Y*s}
+				}
+				break
+			}
+		}
+		cx, cy = at.X+off.X*h.Radius, at.Y+off.Y*h.Radius
+	}
+	if at != p0 {
+		prog.FeedXY(at.X, at.Y, e.Feed) // along the path, above what's left to cut
+	}
+	pitch := 2 * math.Pi * h.Radius * math.Tan(h.Angle*math.Pi/180)
+	for z := prog.z - pitch; ; z -= pitch {
+		if z <= e.Z+1e-9 {
+			prog.ArcXY(cw, at.X, at.Y, e.Z, cx, cy, e.Feed)
+			break
+		}
+		prog.ArcXY(cw, at.X, at.Y, z, cx, cy, e.Feed)
+	}
+	if at != p0 {
+		prog.FeedXY(p0.X, p0.Y, e.Feed)
+	}
+}
+
+// wasteSide is the unit normal to the path at its start, which runs along
+// dir, that points into the waste: away from the part of an -comp
+// outside loop, into the hole of an inside one, and to the side of
+// travel the tool was offset to with left or right. ok is false when
+// there is no waste side, on paths cut as drawn.
+func wasteSide(pts []Point, dir Point, closed bool, comp string) (Point, bool) {
+	left := Point{X: -dir.Y, Y: dir.X}
+	right := Point{X: dir.Y, Y: -dir.X}
+	switch {
+	case closed && (comp == "inside" || comp == "outside"):
+		// outside cuts keep the interior, so waste is away from it
+		if (signedArea(pts) > 0) == (comp == "outside") {
+			return right, true
+		}
+		return left, true
+	case !closed && comp == "left":
+		return left, true
+	case !closed && comp == "right":
+		return right, true
+	}
+	return Point{}, false
+}
+
+// preDrillEntry peck-drills a hole at the start to full depth before the
+// first pass, then drops every pass into it.
+type preDrillEntry struct {
+	Peck   float64
+	ClearZ float64 // height to lift to between pecks
+}
+
+func (d preDrillEntry) Enter(prog *Program, e Entry) {
+	if e.First {
+		for _, z := range passDepths(e.Target, d.Peck) {
+			prog.FeedZ(z, e.Plunge)
+			prog.RapidZ(d.ClearZ) // clear chips
+		}
+	}
+	prog.FeedZ(e.Z, e.Plunge)
+}
+
+// leadInEntry plunges Length away from the path, in the waste, and feeds
+// onto the start. On compensated loops the waste side is the side the
+// loop was offset to; on anything else the lead-in extends the first
+// segment backwards.
+type leadInEntry struct {
+	Length float64
+}
+
+func (l leadInEntry) Enter(prog *Program, e Entry) {
+	p0 := e.Points[0]
+	if prog.z < 0 || len(e.Points) < 2 {
+		prog.FeedZ(e.Z, e.Plunge) // already in the material
+		return
+	}
+	d := Point{X: e.Points[1].X - p0.X, Y: e.Points[1].Y - p0.Y}
+	n := math.Hypot(d.X, d.Y)
+	if n < 1e-9 {
+		prog.FeedZ(e.Z, e.Plunge)
+		return
+	}
+	d.X, d.Y = d.X/n, d.Y/n
+	off := Point{X: -d.X, Y: -d.Y}
+	if e.Closed && (e.Comp == "inside" || e.Comp == "outside") {
+		off, _ = wasteSide(e.Points, d, e.Closed, e.Comp)
+	}
+	prog.RapidXY(p0.X+off.X*l.Length, p0.Y+off.Y*l.Length)
+	prog.FeedZ(e.Z, e.Plunge)
+	prog.FeedXY(p0.X, p0.Y, e.Feed)
+}
+
+// polylineLength is the total length of a polyline.
+func polylineLength(pts []Point) float64 {
+	l := 0.0
+	for i := 1; i < len(pts); i++ {
+		l += math.Hypot(pts[i].X-pts[i-1].X, pts[i].Y-pts[i-1].Y)
+	}
+	return l
+}
+
+// polylinePrefix returns the first d mm of a polyline.
+func polylinePrefix(pts []Point, d float64) []Point {
+	out := []Point{pts[0]}
+	for i := 1; i < len(pts); i++ {
+		seg := math.Hypot(pts[i].X-pts[i-1].X, pts[i].Y-pts[i-1].Y)
+		if seg >= d {
+			if seg > 0 {
+				out = append(out, lerp(pts[i-1], pts[i], d/seg))
+			}
+			return out
+		}
+		out = append(out, pts[i])
+		d -= seg
+	}
+	return out
+}
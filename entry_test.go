@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestHelixEntryStaysInWaste spirals into compensated loops of both
+// orientations and checks that no point of the helix crosses the loop
+// into the part.
+func TestHelixEntryStaysInWaste(t *testing.T) {
+	square := []Point{{X: 10, Y: 10}, {X: 50, Y: 10}, {X: 50, Y: 50}, {X: 10, Y: 50}, {X: 10, Y: 10}}
+	const radius = 2.0 // -tooldia 8
+	for _, comp := range []string{"outside", "inside"} {
+		for _, reversed := range []bool{false, true} {
+			loop := offsetPolygon(square, 4, comp)
+			if reversed {
+				loop = reversePoints(loop)
+			}
+			prog := newProgram(5)
+			prog.RapidXY(loop[0].X, loop[0].Y)
+			start := len(prog.Moves)
+			helixEntry{Radius: radius, Angle: 3}.Enter(prog, Entry{
+				Points: loop, Closed: true, Z: -2, Plunge: 120, Feed: 300, Comp: comp,
+			})
+
+			from := prog.Moves[start-1]
+			arcs := 0
+			for _, m := range prog.Moves[start:] {
+				if m.isArc() {
+					arcs++
+					c, r, sweep := arcGeometry(from, m)
+					a0 := math.Atan2(from.Y-c.Y, from.X-c.X)
+					if m.Kind == MoveArcCW {
+						sweep = -sweep
+					}
+					for i := 0; i <= 64; i++ {
+						a := a0 + sweep*float64(i)/64
+						p := Point{X: c.X + r*math.Cos(a), Y: c.Y + r*math.Sin(a)}
+						if onLoop(p, loop) {
+							continue
+						}
+						if inside := pointInPolygon(p, loop); inside != (comp == "inside") {
+							t.Fatalf("%s, reversed %v: helix reaches X%.3f Y%.3f on the part side of the loop",
+								comp, reversed, p.X, p.Y)
+						}
+					}
+				}
+				from = m
+			}
+			if arcs == 0 {
+				t.Fatalf("%s, reversed %v: no helix was cut", comp, reversed)
+			}
+			if last := prog.Moves[len(prog.Moves)-1]; math.Hypot(last.X-loop[0].X, last.Y-loop[0].Y) > 1e-9 || last.Z != -2 {
+				t.Fatalf("%s, reversed %v: helix ends at X%.3f Y%.3f Z%.3f, not the loop start at depth",
+					comp, reversed, last.X, last.Y, last.Z)
+			}
+		}
+	}
+}
+
+// onLoop reports whether p lies on the loop, where the helix touches it.
+func onLoop(p Point, loop []Point) bool {
+	for i := 1; i < len(loop); i++ {
+		if distPointToSegment(p, loop[i-1], loop[i]) < 1e-6 {
+			return true
+		}
+	}
+	return false
+}
+
+// TestEntryCompoundHole enters the wall of a hole in a compound shape cut
+// with -comp outside. Its offset goes into the hole, so both the helix
+// and the lead-in must stay inside the offset loop, not swing out into
+// the part around it.
+func TestEntryCompoundHole(t *testing.T) {
+	outer := []Point{{X: 0, Y: 0}, {X: 60, Y: 0}, {X: 60, Y: 60}, {X: 0, Y: 60}, {X: 0, Y: 0}}
+	hole := []Point{{X: 20, Y: 20}, {X: 20, Y: 40}, {X: 40, Y: 40}, {X: 40, Y: 20}, {X: 20, Y: 20}}
+	shapes := map[int][][]Point{1: {outer, hole}}
+	cfg := testConfig()
+	cfg.Compensation, cfg.ToolDia = "outside", 8
+
+	r := compensatePath(Path{Index: 2, Shape: 1, Closed: true, FillRule: "nonzero", Points: hole}, shapes, cfg)
+	if len(r.Paths) != 1 {
+		t.Fatalf("hole compensated to %d paths, want 1", len(r.Paths))
+	}
+	p := r.Paths[0]
+	if p.Comp != "inside" {
+		t.Fatalf("hole offset with Comp %q, want inside", p.Comp)
+	}
+	for _, tc := range []struct {
+		name  string
+		entry EntryStrategy
+	}{
+		{"helix", helixEntry{Radius: 2, Angle: 3}},
+		{"lead-in", leadInEntry{Length: 3}},
+	} {
+		prog := newProgram(5)
+		prog.RapidXY(p.Points[0].X, p.Points[0].Y)
+		start := len(prog.Moves)
+		tc.entry.Enter(prog, Entry{
+			Points: p.Points, Closed: true, Z: -2, Plunge: 120, Feed: 300, Comp: p.Comp,
+		})
+		from := prog.Moves[start-1]
+		for _, m := range prog.Moves[start:] {
+			pts := []Point{{X: m.X, Y: m.Y}}
+			if m.isArc() {
+				// the far side of the helix circle from where it starts
+				c, _, _ := arcGeometry(from, m)
+				pts = append(pts, Point{X: 2*c.X - from.X, Y: 2*c.Y - from.Y})
+			}
+			for _, q := range pts {
+				if !onLoop(q, p.Points) && !pointInPolygon(q, p.Points) {
+					t.Errorf("%s: entry reaches X%.3f Y%.3f outside the hole", tc.name, q.X, q.Y)
+				}
+			}
+			from = m
+		}
+	}
+}
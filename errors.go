@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The kinds of failure a caller may want to tell apart. Errors that stop
+// a conversion wrap one of them, so errors.Is(err, ErrMalformedPath) and
+// the like work however much context was added on the way out.
+var (
+	ErrUnsupportedCommand = errors.New("unsupported path command")
+	ErrMalformedPath      = errors.New("malformed path data")
+	ErrDegenerateOffset   = errors.New("degenerate offset")
+)
+
+// ElementError is a failure traced to one element of the input or one
+// path of the job, with enough context to find it: errors.As gives the
+// details, errors.Is the Kind.
+type ElementError struct {
+	Kind    error  // ErrUnsupportedCommand, ErrMalformedPath or ErrDegenerateOffset
+	Element string // tag name, e.g. "path"; "" when not from one element
+	ID      string // the element's id, "" if it has none
+	Line    int    // source line, approximate (a <use> reports its own); 0 = unknown
+	Path    int    // 1-based path index, for failures after parsing; 0 = none
+	Snippet string // the offending data, shortened
+	Err     error  // what exactly went wrong
+}
+
+func (e *ElementError) Error() string {
+	var b strings.Builder
+	if e.Element != "" {
+		b.WriteString("<" + e.Element)
+		if e.ID != "" {
+			fmt.Fprintf(&b, " id=%q", e.ID)
+		}
+		b.WriteString(">")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " line %d", e.Line)
+	}
+	if e.Path > 0 {
+		fmt.Fprintf(&b, " path %d", e.Path)
+	}
+	if b.Len() > 0 {
+		b.WriteString(": ")
+	}
+	switch {
+	case e.Err == nil:
+		b.WriteString(e.Kind.Error())
+	case errors.Is(e.Err, e.Kind):
+		b.WriteString(e.Err.Error()) // says what kind it is already
+	default:
+		b.WriteString(e.Kind.Error() + ": " + e.Err.Error())
+	}
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, " in %q", e.Snippet)
+	}
+	return strings.TrimPrefix(b.String(), " ")
+}
+
+func (e *ElementError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// newElementError places err at the element t, which started on line
+// (0 if unknown). A path command the parser doesn't know is reported as
+// ErrUnsupportedCommand whatever kind is given.
+func newElementError(kind error, t xml.StartElement, line int, snippet string, err error) *ElementError {
+	e := &ElementError{Kind: kind, Element: t.Name.Local, Line: line, Snippet: snippet, Err: err}
+	for _, a := range t.Attr {
+		if a.Name.Local == "id" {
+			e.ID = a.Value
+		}
+	}
+	if errors.Is(err, ErrUnsupportedCommand) {
+		e.Kind = ErrUnsupportedCommand
+	}
+	return e
+}
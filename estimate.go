@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// JobEstimate summarizes a planned job without emitting it.
+type JobEstimate struct {
+	Paths       int           // paths that produce motion
+	CutLength   float64       // mm travelled at feed (G1), including plunges
+	RapidLength float64       // mm travelled at rapid (G0)
+	Time        time.Duration // CutLength at programmed feeds + RapidLength at RapidFeed
+	Bounds      Rect          // XY extent of all motion, machine coordinates
+	MinZ        float64       // deepest Z reached
+}
+
+// estimateMoves measures a planned program. rapid is the assumed G0 rate
+// in mm/min; <= 0 leaves rapids out of the time.
+func estimateMoves(moves []Move, rapid float64) JobEstimate {
+	var est JobEstimate
+	est.Bounds = Rect{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	seen := map[int]bool{}
+	minutes := 0.0
+
+	var prev Move
+	havePrev := false
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		if havePrev {
+			d := moveLength(prev, m)
+			minutes += moveMinutes(prev, m, rapid)
+			if m.isArc() {
+				c, r, _ := arcGeometry(prev, m)
+				est.Bounds.MinX = math.Min(est.Bounds.MinX, c.X-r)
+				est.Bounds.MinY = math.Min(est.Bounds.MinY, c.Y-r)
+				est.Bounds.MaxX = math.Max(est.Bounds.MaxX, c.X+r)
+				est.Bounds.MaxY = math.Max(est.Bounds.MaxY, c.Y+r)
+			}
+			if m.Kind != MoveRapid {
+				est.CutLength += d
+			} else {
+				est.RapidLength += d
+			}
+		}
+		if m.Axes&(AxisX|AxisY) != 0 {
+			est.Bounds.MinX = math.Min(est.Bounds.MinX, m.X)
+			est.Bounds.MinY = math.Min(est.Bounds.MinY, m.Y)
+			est.Bounds.MaxX = math.Max(est.Bounds.MaxX, m.X)
+			est.Bounds.MaxY = math.Max(est.Bounds.MaxY, m.Y)
+		}
+		est.MinZ = math.Min(est.MinZ, m.Z)
+		if m.Path > 0 && !seen[m.Path] {
+			seen[m.Path] = true
+			est.Paths++
+		}
+		prev, havePrev = m, true
+	}
+	if math.IsInf(est.Bounds.MinX, 1) {
+		est.Bounds = Rect{}
+	}
+	est.Time = time.Duration(minutes * float64(time.Minute)).Round(time.Second)
+	return est
+}
+
+// moveLength is the distance travelled by m starting from prev, along
+// the arc for arc moves.
+func moveLength(prev, m Move) float64 {
+	if m.isArc() {
+		_, r, sweep := arcGeometry(prev, m)
+		return math.Hypot(r*sweep, m.Z-prev.Z)
+	}
+	return math.Sqrt((m.X-prev.X)*(m.X-prev.X) + (m.Y-prev.Y)*(m.Y-prev.Y) + (m.Z-prev.Z)*(m.Z-prev.Z))
+}
+
+// moveMinutes is how long m takes starting from prev: at its feed, or
+// at rapid for G0 moves (rapid <= 0 counts them as instant).
+func moveMinutes(prev, m Move, rapid float64) float64 {
+	f := m.F
+	if m.Kind == MoveRapid {
+		f = rapid
+	}
+	if f <= 0 {
+		return 0
+	}
+	return moveLength(prev, m) / f
+}
+
+func (e JobEstimate) String() string {
+	return fmt.Sprintf("paths:        %d\n"+
+		"cut length:   %.1f mm\n"+
+		"rapid length: %.1f mm\n"+
+		"time:         %s\n"+
+		"extent:       X %.3f..%.3f  Y %.3f..%.3f  Z >= %.3f\n",
+		e.Paths, e.CutLength, e.RapidLength, e.Time,
+		e.Bounds.MinX, e.Bounds.MaxX, e.Bounds.MinY, e.Bounds.MaxY, e.MinZ)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// With -outformat svg or dxf the job is written as geometry instead of a
+// program: the toolpaths after compensation, fills, ordering and every
+// other preparation, one shape per path in cutting order, in mm with the
+// machine's origin. Passes, tabs, ramps and rapids are left out; they
+// belong to the program, not the drawing. Recognised holes are written as
+// circles.
+
+// exportPaths prepares paths for export. Closed paths lose the repeated
+// closing point, which both formats imply.
+func exportPaths(paths []Path, cfg Config) ([]Path, error) {
+	paths, err := preparePaths(paths, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out := paths[:0]
+	for _, p := range paths {
+		if p.Hole == nil && len(p.Points) < 2 {
+			continue
+		}
+		if p.Closed && len(p.Points) > 2 && almostEqualPoint(p.Points[0], p.Points[len(p.Points)-1]) {
+			p.Points = p.Points[:len(p.Points)-1]
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// writeSVGPaths writes the prepared toolpaths as an SVG drawing at 1 user
+// unit per mm. The machine's Y axis points up, so Y is negated and the
+// viewBox placed to match: the drawing looks as it will be cut.
+func writeSVGPaths(w io.Writer, paths []Path, cfg Config) error {
+	paths, err := exportPaths(paths, cfg)
+	if err != nil {
+		return err
+	}
+	b, ok := pathBounds(paths) // holes keep their outline's points
+	if !ok {
+		b = Rect{MaxX: 1, MaxY: 1}
+	}
+	n := func(v float64) string { return strconv.FormatFloat(v+0, 'f', 3, 64) } // no -0
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%smm" height="%smm" viewBox="%s %s %s %s">`+"\n",
+		n(b.Width()), n(b.Height()), n(b.MinX), n(-b.MaxY), n(b.Width()), n(b.Height()))
+	fmt.Fprintf(bw, "<!-- %s -->\n", cfg.Msg.T("header"))
+	for _, p := range paths {
+		stroke := p.Stroke
+		if stroke == "" {
+			stroke = "#000000"
+		}
+		style := fmt.Sprintf(`fill="none" stroke="%s" stroke-width="0.1"`, stroke)
+		if p.Hole != nil {
+			fmt.Fprintf(bw, `<circle id="path%d" cx="%s" cy="%s" r="%s" %s/>`+"\n",
+				p.Index, n(p.Hole.Center.X), n(-p.Hole.Center.Y), n(p.Hole.R), style)
+			continue
+		}
+		var d strings.Builder
+		for i, pt := range p.Points {
+			if i == 0 {
+				d.WriteString("M")
+			} else {
+				d.WriteString(" L")
+			}
+			d.WriteString(n(pt.X) + " " + n(-pt.Y))
+		}
+		if p.Closed {
+			d.WriteString(" Z")
+		}
+		fmt.Fprintf(bw, `<path id="path%d" d="%s" %s/>`+"\n", p.Index, d.String(), style)
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// writeDXFPaths writes the prepared toolpaths as an ASCII DXF drawing in
+// mm: LWPOLYLINEs, and CIRCLEs for holes, on the layer they were drawn on.
+// Colors go back to their AutoCAD index where there is one and are
+// written as true colors otherwise.
+func writeDXFPaths(w io.Writer, paths []Path, cfg Config) error {
+	paths, err := exportPaths(paths, cfg)
+	if err != nil {
+		return err
+	}
+	aci := map[string]int{}
+	for i, c := range dxfColors {
+		aci[c] = i
+	}
+	bw := bufio.NewWriter(w)
+	pair := func(code int, v string) { fmt.Fprintf(bw, "%3d\n%s\n", code, v) }
+	num := func(code int, v float64) { pair(code, strconv.FormatFloat(v, 'f', -1, 64)) }
+
+	pair(999, cfg.Msg.T("header"))
+	pair(0, "SECTION")
+	pair(2, "HEADER")
+	pair(9, "$ACADVER")
+	pair(1, "AC1015")
+	pair(9, "$INSUNITS")
+	pair(70, "4")
+	pair(0, "ENDSEC")
+	pair(0, "SECTION")
+	pair(2, "ENTITIES")
+	for _, p := range paths {
+		layer := p.Layer
+		if layer == "" {
+			layer = "0"
+		}
+		color := func() {
+			if i, ok := aci[p.Stroke]; ok {
+				pair(62, strconv.Itoa(i))
+			} else if v, err := strconv.ParseUint(strings.TrimPrefix(p.Stroke, "#"), 16, 32); err == nil && len(p.Stroke) == 7 {
+				pair(420, strconv.FormatUint(v, 10))
+			}
+		}
+		if p.Hole != nil {
+			pair(0, "CIRCLE")
+			pair(8, layer)
+			color()
+			num(10, round3(p.Hole.Center.X))
+			num(20, round3(p.Hole.Center.Y))
+			num(40, round3(p.Hole.R))
+			continue
+		}
+		pair(0, "LWPOLYLINE")
+		pair(8, layer)
+		color()
+		pair(90, strconv.Itoa(len(p.Points)))
+		flags := "0"
+		if p.Closed {
+			flags = "1"
+		}
+		pair(70, flags)
+		for _, pt := range p.Points {
+			num(10, round3(pt.X))
+			num(20, round3(pt.Y))
+		}
+	}
+	pair(0, "ENDSEC")
+	pair(0, "EOF")
+	return bw.Flush()
+}
+
+// round3 rounds to the 0.001 mm the G-code is written at.
+func round3(v float64) float64 {
+	return math.Round(v*1000)/1000 + 0 // no -0
+}
@@ -0,0 +1,21 @@
+package main
+
+import "fmt"
+
+// chipLoadFeed is the XY feed (mm/min) that gives each cutting edge
+// chipLoad mm of material per revolution: rpm × flutes × chip load.
+func chipLoadFeed(rpm float64, flutes int, chipLoad float64) float64 {
+	return rpm * float64(flutes) * chipLoad
+}
+
+// checkChipLoad validates -rpm, -flutes and -chipload. Chip load needs
+// both other values; flutes alone is harmless but unused.
+func checkChipLoad(rpm float64, flutes int, chipLoad float64) error {
+	if rpm < 0 || flutes < 0 || chipLoad < 0 {
+		return fmt.Errorf("-rpm, -flutes and -chipload must be >= 0")
+	}
+	if chipLoad > 0 && (rpm <= 0 || flutes <= 0) {
+		return fmt.Errorf("-chipload needs -rpm and -flutes to compute the feed")
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"sort"
+	"strings"
+)
+
+// svgFill is the fill paint in effect for an element, inherited from its
+// groups like the stroke color.
+type svgFill struct {
+	Color string // normalized color, "none", or "" when never set
+	Rule  string // "nonzero" or "evenodd"
+}
+
+// inheritFill resolves an element's fill and fill-rule from its own
+// attributes and style, falling back to the parent's.
+func inheritFill(parent svgFill, fillAttr, ruleAttr, style string) svgFill {
+	f := parent
+	if v := cmp.Or(styleProp(style, "fill"), fillAttr); v != "" {
+		if v = strings.ToLower(strings.TrimSpace(v)); v == "none" || v == "transparent" {
+			f.Color = "none"
+		} else {
+			f.Color = normalizeColor(v)
+		}
+	}
+	if v := strings.ToLower(cmp.Or(styleProp(style, "fill-rule"), ruleAttr)); v == "evenodd" || v == "nonzero" {
+		f.Rule = v
+	}
+	return f
+}
+
+// paint returns the fill color to record on a Path, "" for unfilled.
+func (f svgFill) paint() string {
+	if f.Color == "none" {
+		return ""
+	}
+	return f.Color
+}
+
+// inRegion tests a point against a set of rings under an SVG fill rule:
+// "evenodd", or "nonzero" (the default).
+func inRegion(p Point, rings [][]Point, rule string) bool {
+	wind := winding(p, rings)
+	if rule == "evenodd" {
+		return wind%2 != 0
+	}
+	return wind != 0
+}
+
+// winding is the winding number of rings around p.
+func winding(p Point, rings [][]Point) int {
+	wind := 0
+	for _, ring := range rings {
+		n := len(ring)
+		for i := 0; i < n; i++ {
+			a, b := ring[i], ring[(i+1)%n]
+			if a.Y <= p.Y && b.Y > p.Y && cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: p.X - a.X, Y: p.Y - a.Y}) > 0 {
+				wind++
+			} else if a.Y > p.Y && b.Y <= p.Y && cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: p.X - a.X, Y: p.Y - a.Y}) < 0 {
+				wind--
+			}
+		}
+	}
+	return wind
+}
+
+// ringSides reports whether the area just inside and just outside ring
+// belongs to the region the rings enclose. An outline or island has
+// material inside, a hole outside; a ring with material on both sides or
+// neither is not part of the region's boundary.
+func ringSides(ring []Point, rings [][]Point, rule string) (in, out bool) {
+	ring = dedupePoints(ring)
+	if len(ring) < 3 {
+		return true, false
+	}
+	// probe either side of the first edge
+	a, b := ring[0], ring[1]
+	l := math.Hypot(b.X-a.X, b.Y-a.Y)
+	mid := lerp(a, b, 0.5)
+	n := Point{X: -(b.Y - a.Y) / l * 1e-6, Y: (b.X - a.X) / l * 1e-6}
+	left := inRegion(Point{X: mid.X + n.X, Y: mid.Y + n.Y}, rings, rule)
+	right := inRegion(Point{X: mid.X - n.X, Y: mid.Y - n.Y}, rings, rule)
+	if signedArea(ring) > 0 {
+		return left, right // counter-clockwise: the inside is on the left
+	}
+	return right, left
+}
+
+// shapeRings collects the closed outlines of every multi-ring shape, so
+// each subpath can be judged against its siblings under the fill rule.
+func shapeRings(paths []Path) map[int][][]Point {
+	rings := map[int][][]Point{}
+	for _, p := range paths {
+		if p.Closed && p.Shape != 0 && p.Hole == nil && p.Slot == nil && p.Depths == nil {
+			rings[p.Shape] = append(rings[p.Shape], p.Points)
+		}
+	}
+	for k, r := range rings {
+		if len(r) < 2 {
+			delete(rings, k)
+		}
+	}
+	return rings
+}
+
+// isHole reports whether p is a hole in its compound shape: the fill rule
+// leaves its inside empty, so the material is around it.
+func isHole(p Path, shapes map[int][][]Point) bool {
+	rings, ok := shapes[p.Shape]
+	if !ok || !p.Closed {
+		return false
+	}
+	in, out := ringSides(p.Points, rings, p.FillRule)
+	return !in && out
+}
+
+// insetRegion moves every ring of a filled region by d towards the
+// material, so a tool of radius d running on the result stays inside the
+// region. Outlines shrink, holes grow. Rings that pinch apart split and
+// rings that collapse are dropped.
+func insetRegion(rings [][]Point, rule string, d float64) [][]Point {
+	if d <= 0 {
+		return rings
+	}
+	var out [][]Point
+	for _, ring := range rings {
+		ring = dedupePoints(ring)
+		if len(ring) < 3 {
+			continue
+		}
+		in, around := ringSides(ring, rings, rule)
+		if in == around {
+			continue // not an edge of the region
+		}
+		mode := "outside"
+		if in {
+			mode = "inside"
+		}
+		// a ring may split into several, or vanish
+		out = append(out, offsetContours(ring, d, mode, Join{})...)
+	}
+	return out
+}
+
+// hatchRegion covers a region with parallel lines spacing apart at angle
+// degrees. Lines on consecutive rows are joined into zig-zags wherever
+// the link stays inside the region, to save retracts.
+func hatchRegion(rings [][]Point, rule string, spacing, angle float64) [][]Point {
+	sin, cos := math.Sincos(-angle * math.Pi / 180)
+	rot := func(p Point, s, c float64) Point {
+		return Point{X: p.X*c - p.Y*s, Y: p.X*s + p.Y*c}
+	}
+	// rotate so hatch lines are horizontal
+	rr := make([][]Point, len(rings))
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for i, ring := range rings {
+		rr[i] = make([]Point, len(ring))
+		for j, p := range ring {
+			q := rot(p, sin, cos)
+			rr[i][j] = q
+			minY, maxY = math.Min(minY, q.Y), math.Max(maxY, q.Y)
+		}
+	}
+	inside := func(p Point) bool { return inRegion(p, rr, rule) }
+
+	var chains [][]Point
+	row := 0
+	for y := minY + spacing/2; y < maxY; y += spacing {
+		type crossing struct {
+			x float64
+			w int
+		}
+		var xs []crossing
+		for _, ring := range rr {
+			n := len(ring)
+			for i := 0; i < n; i++ {
+				a, b := ring[i], ring[(i+1)%n]
+				if (a.Y <= y) == (b.Y <= y) {
+					continue
+				}
+				w := 1
+				if b.Y < a.Y {
+					w = -1
+				}
+				xs = append(xs, crossing{a.X + (y-a.Y)/(b.Y-a.Y)*(b.X-a.X), w})
+			}
+		}
+		sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+		var segs [][2]Point
+		wind := 0
+		for i := 0; i+1 < len(xs); i++ {
+			wind += xs[i].w
+			in := wind != 0
+			if rule == "evenodd" {
+				in = (i+1)%2 == 1
+			}
+			if in && xs[i+1].x-xs[i].x > 1e-9 {
+				segs = append(segs, [2]Point{{X: xs[i].x, Y: y}, {X: xs[i+1].x, Y: y}})
+			}
+		}
+		if row%2 == 1 {
+			// boustrophedon: every other row runs right to left
+			for i, j := 0, len(segs)-1; i < j; i, j = i+1, j-1 {
+				segs[i], segs[j] = segs[j], segs[i]
+			}
+			for i := range segs {
+				segs[i][0], segs[i][1] = segs[i][1], segs[i][0]
+			}
+		}
+		row++
+
+		extended := map[int]bool{}
+		for _, s := range segs {
+			best := -1
+			for ci, c := range chains {
+				end := c[len(c)-1]
+				if extended[ci] || math.Abs(end.Y-(y-spacing)) > 1e-6 {
+					continue
+				}
+				if canLink(end, s[0], rr, inside) {
+					best = ci
+					break
+				}
+			}
+			if best >= 0 {
+				chains[best] = append(chains[best], s[0], s[1])
+				extended[best] = true
+			} else {
+				chains = append(chains, []Point{s[0], s[1]})
+				extended[len(chains)-1] = true
+			}
+		}
+	}
+
+	for _, c := range chains {
+		for j, p := range c {
+			c[j] = rot(p, -sin, cos)
+		}
+	}
+	return chains
+}
+
+// canLink reports whether the tool can feed straight from a to b without
+// leaving the region. Links usually run along the region's edge, so
+// touching the boundary counts as inside.
+func canLink(a, b Point, rings [][]Point, inside func(Point) bool) bool {
+	for _, ring := range rings {
+		for j := 1; j <= len(ring); j++ {
+			c, d := ring[j-1], ring[j%len(ring)]
+			if t, ok := segmentCrossing(a, b, c, d); ok && t > 1e-6 && t < 1-1e-6 &&
+				distPointToSegment(a, c, d) > 1e-6 && distPointToSegment(b, c, d) > 1e-6 {
+				return false // crosses an edge it does not run along
+			}
+		}
+	}
+	mid := lerp(a, b, 0.5)
+	if inside(mid) {
+		return true
+	}
+	for _, ring := range rings {
+		for j := 1; j <= len(ring); j++ {
+			if distPointToSegment(mid, ring[j-1], ring[j%len(ring)]) < 1e-6 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// concentricRegion covers a region with rings offset inward by spacing,
+// each clipped to the region so grown holes and shrunk outlines never
+// leave it.
+func concentricRegion(rings [][]Point, rule string, spacing float64) [][]Point {
+	inside := func(p Point) bool { return inRegion(p, rings, rule) }
+	var out [][]Point
+	for k := 0; k < 10000; k++ {
+		step := insetRegion(rings, rule, float64(k)*spacing)
+		if k == 0 {
+			step = rings
+		}
+		if len(step) == 0 {
+			break
+		}
+		any := false
+		for _, ring := range step {
+			if k == 0 {
+				out = append(out, ring)
+				any = true
+				continue
+			}
+			pieces, whole := clipPolyline(ring, true, rings, inside)
+			if whole {
+				out = append(out, ring)
+				any = true
+				continue
+			}
+			for _, p := range pieces {
+				out = append(out, p)
+				any = true
+			}
+		}
+		if !any {
+			break
+		}
+	}
+	return out
+}
+
+// islands adds to a filled shape's rings every other closed path that
+// lies wholly inside its region, oriented so the fill rule leaves the
+// island's inside out of the region. own reports the shape's own
+// subpaths. Under nonzero this holds where the shape winds once around
+// the island, which is every ordinary outline.
+func islands(rings [][]Point, rule string, paths []Path, own func(Path) bool) [][]Point {
+	out := append([][]Point(nil), rings...)
+	for _, q := range paths {
+		if own(q) || !q.Closed || q.Hole != nil || q.Slot != nil || q.Depths != nil || len(q.Points) < 3 {
+			continue
+		}
+		inside := true
+		for _, pt := range q.Points {
+			if !inRegion(pt, rings, rule) {
+				inside = false
+				break
+			}
+		}
+		if !inside {
+			continue
+		}
+		island := q.Points
+		if rule != "evenodd" {
+			// wind against the shape: the winding number drops to zero
+			if (signedArea(island) > 0) == (winding(island[0], rings) > 0) {
+				island = reversePoints(island)
+			}
+		}
+		out = append(out, island)
+	}
+	return out
+}
+
+// fillPaths turns closed, filled, unstroked shapes into area-filling
+// toolpaths. The subpaths of one shape are filled together, so the fill
+// rule decides which of them are holes and which islands. Other closed
+// paths lying inside a shape are islands as well and are left standing.
+// The tool centre keeps radius away from every outline.
+func fillPaths(paths []Path, mode string, spacing, angle, radius float64, warn *Warnings) []Path {
+	filled := func(p Path) bool {
+		return p.Fill != "" && p.Stroke == "" && p.Closed && p.Hole == nil && p.Slot == nil && p.Depths == nil
+	}
+	key := func(p Path) int {
+		if p.Shape == 0 {
+			return -p.Index // not from a drawing element: on its own
+		}
+		return p.Shape
+	}
+	shapes := map[int][][]Point{}
+	for _, p := range paths {
+		if filled(p) {
+			shapes[key(p)] = append(shapes[key(p)], p.Points)
+		}
+	}
+
+	out := make([]Path, 0, len(paths))
+	done := map[int]bool{}
+	for _, p := range paths {
+		if !filled(p) {
+			out = append(out, p)
+			continue
+		}
+		if done[key(p)] {
+			continue // filled with the first subpath of its shape
+		}
+		done[key(p)] = true
+		rings := islands(shapes[key(p)], p.FillRule, paths, func(q Path) bool {
+			return filled(q) && key(q) == key(p)
+		})
+		region := insetRegion(rings, p.FillRule, radius)
+		if len(region) == 0 {
+			warn.Add(WCompCollapsed, p.Index, "filled shape is narrower than the tool; skipped")
+			continue
+		}
+		var lines [][]Point
+		switch mode {
+		case "hatch":
+			lines = hatchRegion(region, p.FillRule, spacing, angle)
+		case "cross":
+			lines = append(hatchRegion(region, p.FillRule, spacing, angle),
+				hatchRegion(region, p.FillRule, spacing, angle+90)...)
+		case "concentric":
+			lines = concentricRegion(region, p.FillRule, spacing)
+		}
+		for _, pts := range lines {
+			q := p
+			q.Points = pts
+			q.Closed = len(pts) > 2 && almostEqualPoint(pts[0], pts[len(pts)-1])
+			q.Stroke = p.Fill // color rules apply by fill color
+			q.Fill = ""
+			q.Dash = nil
+			q.Filled = true
+			out = append(out, q)
+		}
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// G-code input (-informat gcode) reads an existing program back in, so
+// it can be scaled, moved, reformatted or previewed like a drawing. Every
+// run of cutting moves becomes one path that keeps its own Z and feed at
+// each point (Path.Depths and Path.Feeds); rapids between runs are
+// planned afresh. Arcs are flattened. Everything but motion, units and
+// distance mode (spindle, coolant, tool changes, comments) is left out:
+// the output gets its own header and footer.
+
+// gcodeSetup lists the codes that set modes a re-posted program does
+// not need or that are already assumed, so reading them is silent.
+var gcodeSetup = map[string]bool{
+	"G17": true, "G40": true, "G49": true, "G54": true, "G61": true, "G64": true,
+	"G80": true, "G90.1": true, "G91.1": true, "G94": true,
+}
+
+// parseGcode reads the motion of a G-code program into paths in mm, with
+// w and h the far corner of the motion, for placement. Commands it cannot
+// follow are reported to warn.
+func parseGcode(r io.Reader, warn *Warnings) (paths []Path, w, h float64, err error) {
+	const flatness = 0.01 // mm, for arcs
+	var (
+		pos      Point
+		z        float64
+		feed     float64
+		motion   = 0
+		relative = false
+		unit     = 1.0
+		run      Path
+		skipped  = map[string]int{}
+	)
+	flush := func() {
+		if len(run.Points) > 1 {
+			run.Index = len(paths) + 1
+			run.Shape = run.Index
+			run.Transform = identityTransform()
+			paths = append(paths, run)
+		}
+		run = Path{}
+	}
+	to := func(p Point, pz, f float64) {
+		if len(run.Points) == 0 {
+			run.Points, run.Depths, run.Feeds = []Point{pos}, []float64{z}, []float64{0}
+		}
+		run.Points = append(run.Points, p)
+		run.Depths = append(run.Depths, pz)
+		run.Feeds = append(run.Feeds, f)
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		words, err := gcodeWords(sc.Text())
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("line %d: %w", n, err)
+		}
+		var axes map[byte]float64
+		for _, wd := range words {
+			switch wd.letter {
+			case 'G':
+				code := "G" + strconv.FormatFloat(wd.value, 'f', -1, 64)
+				switch code {
+				case "G0", "G1", "G2", "G3":
+					motion = int(wd.value)
+				case "G20":
+					unit = 25.4
+				case "G21":
+					unit = 1
+				case "G90":
+					relative = false
+				case "G91":
+					relative = true
+				default:
+					if !gcodeSetup[code] {
+						skipped[code]++
+					}
+				}
+			case 'X', 'Y', 'Z', 'I', 'J', 'R':
+				if axes == nil {
+					axes = map[byte]float64{}
+				}
+				axes[wd.letter] = wd.value * unit
+			case 'F':
+				feed = wd.value * unit
+			}
+		}
+		_, hx := axes['X']
+		_, hy := axes['Y']
+		_, hz := axes['Z']
+		if !hx && !hy && !hz {
+			continue // no move in this block
+		}
+		next, nz := pos, z
+		for _, a := range []struct {
+			letter byte
+			dst    *float64
+		}{{'X', &next.X}, {'Y', &next.Y}, {'Z', &nz}} {
+			if v, ok := axes[a.letter]; ok {
+				if relative {
+					*a.dst += v
+				} else {
+					*a.dst = v
+				}
+			}
+		}
+
+		switch motion {
+		case 0:
+			flush()
+		case 1:
+			to(next, nz, feed)
+		case 2, 3:
+			c, ok := arcCenter(pos, next, axes, motion == 2)
+			if !ok {
+				skipped["arc without I, J or R"]++
+				to(next, nz, feed)
+				break
+			}
+			r := dist(c, pos)
+			a0 := math.Atan2(pos.Y-c.Y, pos.X-c.X)
+			sweep := math.Atan2(next.Y-c.Y, next.X-c.X) - a0
+			if motion == 2 {
+				sweep = -math.Mod(-sweep+4*math.Pi, 2*math.Pi)
+			} else {
+				sweep = math.Mod(sweep+4*math.Pi, 2*math.Pi)
+			}
+			if math.Abs(sweep) < 1e-9 {
+				sweep = 2 * math.Pi // start = end: a full circle
+				if motion == 2 {
+					sweep = -sweep
+				}
+			}
+			pts := arcPoints(c, r, a0, sweep, flatness)
+			for i, p := range pts[1:] {
+				t := float64(i+1) / float64(len(pts)-1)
+				to(p, z+(nz-z)*t, feed)
+			}
+			run.Points[len(run.Points)-1] = next // exact end
+		}
+		pos, z = next, nz
+	}
+	if err := sc.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	flush()
+	for code, n := range skipped {
+		warn.Add(WUnsupportedCommand, 0, "%d G-code %s blocks not followed", n, code)
+	}
+	if b, ok := pathBounds(paths); ok {
+		w, h = math.Max(b.MaxX, 0), math.Max(b.MaxY, 0)
+	}
+	return paths, w, h, nil
+}
+
+// arcCenter finds the centre of an arc from a to b, from its I and J
+// offsets or its R radius (negative for the long way round).
+func arcCenter(a, b Point, axes map[byte]float64, cw bool) (Point, bool) {
+	i, hasI := axes['I']
+	j, hasJ := axes['J']
+	if hasI || hasJ {
+		return Point{X: a.X + i, Y: a.Y + j}, true
+	}
+	r, ok := axes['R']
+	if !ok {
+		return Point{}, false
+	}
+	d := dist(a, b)
+	if d < 1e-12 || d > 2*math.Abs(r)+1e-9 {
+		return Point{}, false
+	}
+	m := lerp(a, b, 0.5)
+	hgt := math.Sqrt(math.Max(0, r*r-d*d/4))
+	// the centre is left of a→b for a short counter-clockwise arc
+	side := 1.0
+	if cw != (r < 0) {
+		side = -1
+	}
+	return Point{X: m.X - (b.Y-a.Y)/d*hgt*side, Y: m.Y + (b.X-a.X)/d*hgt*side}, true
+}
+
+type gcodeWord struct {
+	letter byte
+	value  float64
+}
+
+// gcodeWords splits a block into its words, leaving out line numbers,
+// checksums and comments.
+func gcodeWords(line string) ([]gcodeWord, error) {
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.IndexByte(line, '*'); i >= 0 {
+		line = line[:i]
+	}
+	var words []gcodeWord
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '(':
+			end := strings.IndexByte(line[i:], ')')
+			if end < 0 {
+				return words, nil
+			}
+			i += end + 1
+		case c == ' ' || c == '\t' || c == '\r' || c == '%':
+			i++
+		case unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(line) && (line[j] == ' ' || line[j] == '\t') {
+				j++
+			}
+			k := j
+			for k < len(line) && strings.IndexByte("+-.0123456789", line[k]) >= 0 {
+				k++
+			}
+			v, err := strconv.ParseFloat(line[j:k], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number after %c: %q", c, line[j:k])
+			}
+			if l := c &^ 0x20; l != 'N' {
+				words = append(words, gcodeWord{letter: l, value: v})
+			}
+			i = k
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return words, nil
+}
+
+// planPosted cuts a path read from G-code as it was posted: the tool
+// goes to the first point, then through every other at its own Z and
+// feed. Points without a feed are cut at the job's feed.
+func planPosted(prog *Program, p Path, cfg Config) {
+	hop(prog, p.Points[0].X, p.Points[0].Y, cfg)
+	if z := p.Depths[0]; z < 0 {
+		prog.FeedZ(z, cfg.PlungeFeed)
+	} else if z != prog.z {
+		prog.RapidZ(z)
+	}
+	for i := 1; i < len(p.Points); i++ {
+		pt, z, f := p.Points[i], p.Depths[i], p.Feeds[i]
+		if f <= 0 {
+			f = cfg.CutFeed
+		}
+		switch {
+		case z == prog.z:
+			prog.FeedXY(pt.X, pt.Y, f)
+		case pt.X == prog.x && pt.Y == prog.y:
+			prog.FeedZ(z, f)
+		default:
+			prog.FeedXYZ(pt.X, pt.Y, z, f)
+		}
+	}
+}
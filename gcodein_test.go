@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseGcodeModal(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		src    string
+		points []Point
+		depths []float64
+		feeds  []float64
+	}{
+		{
+			name:   "motion and feed stay set",
+			src:    "G0 Z5\nG0 X0 Y0\nG1 Z-1 F100\nX10\nY10 F200\nG0 Z5\n",
+			points: []Point{{0, 0}, {0, 0}, {10, 0}, {10, 10}},
+			depths: []float64{5, -1, -1, -1},
+			feeds:  []float64{0, 100, 100, 200},
+		},
+		{
+			name:   "inches and relative moves",
+			src:    "G20 G91\nG1 X1 Y1 F10\nX1\nZ-0.1\n",
+			points: []Point{{0, 0}, {25.4, 25.4}, {50.8, 25.4}, {50.8, 25.4}},
+			depths: []float64{0, 0, 0, -2.54},
+			feeds:  []float64{0, 254, 254, 254},
+		},
+		{
+			name:   "line numbers, comments and checksums",
+			src:    "N10 G1 X5 (to the side) F50 ; note\nN20 Y5*57\n%\n",
+			points: []Point{{0, 0}, {5, 0}, {5, 5}},
+			depths: []float64{0, 0, 0},
+			feeds:  []float64{0, 50, 50},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, _, _, err := parseGcode(strings.NewReader(tc.src), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 {
+				t.Fatalf("%d paths, want 1", len(paths))
+			}
+			p := paths[0]
+			if len(p.Points) != len(tc.points) {
+				t.Fatalf("points %v, want %v", p.Points, tc.points)
+			}
+			for i := range tc.points {
+				if dist(p.Points[i], tc.points[i]) > 1e-9 || math.Abs(p.Depths[i]-tc.depths[i]) > 1e-9 || math.Abs(p.Feeds[i]-tc.feeds[i]) > 1e-9 {
+					t.Fatalf("point %d: %v Z%g F%g, want %v Z%g F%g",
+						i, p.Points[i], p.Depths[i], p.Feeds[i], tc.points[i], tc.depths[i], tc.feeds[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseGcodeArcs(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		src    string
+		centre Point
+		r      float64
+		end    Point
+		minY   float64 // the arc's lowest and highest Y show its side
+		maxY   float64
+	}{
+		{"G3 by I and J", "G0 X10 Y0\nG1 Z-1 F100\nG3 X0 Y10 I-10 J0\n", Point{0, 0}, 10, Point{0, 10}, 0, 10},
+		{"G2 by R", "G1 Z-1 F100\nG2 X10 Y0 R5\n", Point{5, 0}, 5, Point{10, 0}, 0, 5},
+		{"G3 by R", "G1 Z-1 F100\nG3 X10 Y0 R5\n", Point{5, 0}, 5, Point{10, 0}, -5, 0},
+		{"G2 the long way by -R", "G1 Z-1 F100\nG2 X10 Y0 R-5\n", Point{5, 0}, 5, Point{10, 0}, 0, 5},
+		{"full circle", "G0 X10 Y0\nG1 Z-1 F100\nG2 X10 Y0 I5 J0\n", Point{15, 0}, 5, Point{10, 0}, -5, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, _, _, err := parseGcode(strings.NewReader(tc.src), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 {
+				t.Fatalf("%d paths, want 1", len(paths))
+			}
+			pts := paths[0].Points[1:] // after the plunge
+			if len(pts) < 8 {
+				t.Fatalf("arc flattened to %d points", len(pts))
+			}
+			if end := pts[len(pts)-1]; end != tc.end {
+				t.Errorf("ends at %v, want %v", end, tc.end)
+			}
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, p := range pts {
+				if d := dist(p, tc.centre); math.Abs(d-tc.r) > 1e-6 {
+					t.Fatalf("%v is %g from %v, want %g", p, d, tc.centre, tc.r)
+				}
+				lo, hi = math.Min(lo, p.Y), math.Max(hi, p.Y)
+			}
+			if math.Abs(lo-tc.minY) > 0.01 || math.Abs(hi-tc.maxY) > 0.01 {
+				t.Errorf("Y from %g to %g, want %g to %g", lo, hi, tc.minY, tc.maxY)
+			}
+		})
+	}
+}
+
+func TestParseGcodeSkipped(t *testing.T) {
+	warn := &Warnings{Out: io.Discard}
+	src := "G17 G21 G90 G94\nM3 S10000\nG81 X1 Y1 Z-2 R1\nG1 Z-1 F100\nG1 X5\nG2 X10\n"
+	paths, _, _, err := parseGcode(strings.NewReader(src), warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("%d paths, want 1", len(paths))
+	}
+	var msgs []string
+	for _, w := range warn.List {
+		msgs = append(msgs, w.Message)
+	}
+	got := strings.Join(msgs, "; ")
+	for _, want := range []string{"1 G-code G81 blocks", "1 G-code arc without I, J or R blocks"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("warnings %q lack %q", got, want)
+		}
+	}
+	if len(warn.List) != 2 {
+		t.Errorf("%d warnings, want 2: %q", len(warn.List), got)
+	}
+
+	if _, _, _, err := parseGcode(strings.NewReader("G1 X1Y\n"), nil); err == nil {
+		t.Error("a letter without a number was accepted")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -33,38 +34,147 @@ func (t Transform) Apply(p Point) Point {
 	}
 }
 
+// IsSimilarity reports whether t only translates, rotates, mirrors and
+// scales uniformly, i.e. preserves angles and ratios of distances.
+func (t Transform) IsSimilarity() bool {
+	const eps = 1e-9
+	sx := math.Hypot(t.A, t.B)
+	sy := math.Hypot(t.C, t.D)
+	dot := t.A*t.C + t.B*t.D
+	return math.Abs(sx-sy) <= eps*math.Max(1, sx) && math.Abs(dot) <= eps*math.Max(1, sx*sy)
+}
+
+// parseTransformAttr parses an SVG transform list such as
+// "translate(10,20) rotate(45) scale(2)". Functions are composed left to
+// right, so the rightmost is applied to the geometry first. Malformed or
+// unknown functions are ignored (treated as identity).
 func parseTransformAttr(s string) Transform {
 	s = strings.TrimSpace(s)
-	if s == "" {
-		return identityTransform()
-	}
-	// handle only translate(x[,y]) for now
-	if strings.HasPrefix(s, "translate") {
+	result := identityTransform()
+	for s != "" {
 		open := strings.IndexByte(s, '(')
 		close := strings.IndexByte(s, ')')
-		if open >= 0 && close > open {
-			args := strings.Split(s[open+1:close], ",")
-			if len(args) == 1 {
-				args = strings.Split(args[0], " ")
-			}
-			var tx, ty float64
-			if len(args) >= 1 {
-				tx, _ = strconv.ParseFloat(strings.TrimSpace(args[0]), 64)
-			}
-			if len(args) >= 2 {
-				ty, _ = strconv.ParseFloat(strings.TrimSpace(args[1]), 64)
-			}
-			return Transform{A: 1, D: 1, E: tx, F: ty}
+		if open < 0 || close < open {
+			break
+		}
+		name := strings.Trim(strings.TrimSpace(s[:open]), ", \t\n")
+		args := parseTransformArgs(s[open+1 : close])
+		s = strings.TrimSpace(s[close+1:])
+
+		if t, ok := transformFunc(name, args); ok {
+			result = result.Mul(t)
+		}
+	}
+	return result
+}
+
+func parseTransformArgs(s string) []float64 {
+	s = strings.ReplaceAll(s, ",", " ")
+	var args []float64
+	for _, f := range strings.Fields(s) {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil
+		}
+		args = append(args, v)
+	}
+	return args
+}
+
+func transformFunc(name string, a []float64) (Transform, bool) {
+	switch name {
+	case "matrix":
+		if len(a) == 6 {
+			return Transform{A: a[0], B: a[1], C: a[2], D: a[3], E: a[4], F: a[5]}, true
+		}
+	case "translate":
+		switch len(a) {
+		case 1:
+			return Transform{A: 1, D: 1, E: a[0]}, true
+		case 2:
+			return Transform{A: 1, D: 1, E: a[0], F: a[1]}, true
+		}
+	case "scale":
+		switch len(a) {
+		case 1:
+			return Transform{A: a[0], D: a[0]}, true
+		case 2:
+			return Transform{A: a[0], D: a[1]}, true
+		}
+	case "rotate":
+		if len(a) != 1 && len(a) != 3 {
+			break
+		}
+		rad := a[0] * math.Pi / 180
+		sin, cos := math.Sin(rad), math.Cos(rad)
+		r := Transform{A: cos, B: sin, C: -sin, D: cos}
+		if len(a) == 3 {
+			// rotate(a, cx, cy) = translate(cx,cy) rotate(a) translate(-cx,-cy)
+			to := Transform{A: 1, D: 1, E: a[1], F: a[2]}
+			back := Transform{A: 1, D: 1, E: -a[1], F: -a[2]}
+			r = to.Mul(r).Mul(back)
+		}
+		return r, true
+	case "skewX":
+		if len(a) == 1 {
+			return Transform{A: 1, C: math.Tan(a[0] * math.Pi / 180), D: 1}, true
+		}
+	case "skewY":
+		if len(a) == 1 {
+			return Transform{A: 1, B: math.Tan(a[0] * math.Pi / 180), D: 1}, true
 		}
 	}
-	// unsupported transform -> identity for now
-	return identityTransform()
+	return Transform{}, false
 }
 
 type Point struct {
 	X, Y float64
 }
 
+// Rect is an axis-aligned bounding box.
+type Rect struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+func (r Rect) Width() float64  { return r.MaxX - r.MinX }
+func (r Rect) Height() float64 { return r.MaxY - r.MinY }
+
+// pathBounds returns the bounding box of all points in paths; ok is false
+// when there are no points.
+func pathBounds(paths []Path) (r Rect, ok bool) {
+	r = Rect{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for _, p := range paths {
+		for _, pt := range p.Points {
+			r.MinX = math.Min(r.MinX, pt.X)
+			r.MinY = math.Min(r.MinY, pt.Y)
+			r.MaxX = math.Max(r.MaxX, pt.X)
+			r.MaxY = math.Max(r.MaxY, pt.Y)
+			ok = true
+		}
+	}
+	if !ok {
+		return Rect{}, false
+	}
+	return r, true
+}
+
+// contains reports whether o lies entirely inside r.
+func (r Rect) contains(o Rect) bool {
+	return o.MinX >= r.MinX && o.MaxX <= r.MaxX && o.MinY >= r.MinY && o.MaxY <= r.MaxY
+}
+
+// pointInPolygon is the even-odd ray casting test.
+func pointInPolygon(p Point, poly []Point) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return in
+}
+
 func lerp(a, b Point, t float64) Point {
 	return Point{
 		X: a.X + (b.X-a.X)*t,
@@ -87,35 +197,485 @@ func distPointToLine(p, a, b Point) float64 {
 	return math.Hypot(p.X-px, p.Y-py)
 }
 
-// recursively subdivide cubic Bézier until "flat enough"
+// maxBezierDepth bounds Bézier subdivision: 2^12 segments per curve is
+// finer than any drawing needs at the parser's flatness, and it keeps
+// zero flatness or wild control points (1e99 and the like, from broken
+// or hostile files) from turning a few bytes into millions of points.
+const maxBezierDepth = 12
+
+// flattenCubicBezier appends points along the cubic Bézier p0..p3 to out,
+// leaving out p0 and ending at p3, so the curve strays at most flatness
+// from them. It halves the curve (De Casteljau) until both control points
+// lie within flatness of the chord, working from a stack so deep
+// subdivision can't exhaust the goroutine stack. A curve with a
+// non-finite coordinate becomes a line to p3.
 func flattenCubicBezier(p0, p1, p2, p3 Point, flatness float64, out *[]Point) {
-	// Measure distance of control points from the line p0-p3
-	d1 := distPointToLine(p1, p0, p3)
-	d2 := distPointToLine(p2, p0, p3)
-	if d1 <= flatness && d2 <= flatness {
-		// flat enough: approximate with straight line to p3
-		*out = append(*out, p3)
+	for _, p := range [...]Point{p0, p1, p2, p3} {
+		if math.IsNaN(p.X+p.Y) || math.IsInf(p.X+p.Y, 0) {
+			*out = append(*out, p3)
+			return
+		}
+	}
+	type piece struct {
+		p0, p1, p2, p3 Point
+		depth          int
+	}
+	stack := []piece{{p0, p1, p2, p3, 0}}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		// distances to the chord itself, not its line: control points
+		// beyond an end mean the curve overshoots it
+		d1 := distPointToSegment(c.p1, c.p0, c.p3)
+		d2 := distPointToSegment(c.p2, c.p0, c.p3)
+		if d1 <= flatness && d2 <= flatness || c.depth >= maxBezierDepth {
+			// flat enough: approximate with straight line to p3
+			*out = append(*out, c.p3)
+			continue
+		}
+
+		m01 := lerp(c.p0, c.p1, 0.5)
+		m12 := lerp(c.p1, c.p2, 0.5)
+		m23 := lerp(c.p2, c.p3, 0.5)
+		m012 := lerp(m01, m12, 0.5)
+		m123 := lerp(m12, m23, 0.5)
+		m0123 := lerp(m012, m123, 0.5)
+
+		// the second half goes on the stack first, so the first half is
+		// flattened first
+		stack = append(stack,
+			piece{m0123, m123, m23, c.p3, c.depth + 1},
+			piece{c.p0, m01, m012, m0123, c.depth + 1})
+	}
+}
+
+// circlePoints flattens a circle into a closed polygon whose edges stray
+// at most flatness from the true circle. The first point is at angle 0.
+func circlePoints(c Point, r, flatness float64) []Point {
+	n := 32 // enough that fitArc can tell it from a polygon
+	if flatness < r {
+		n = max(n, int(math.Ceil(math.Pi/math.Acos(1-flatness/r))))
+	}
+	pts := make([]Point, 0, n+1)
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		pts = append(pts, Point{X: c.X + r*math.Cos(a), Y: c.Y + r*math.Sin(a)})
+	}
+	return append(pts, pts[0])
+}
+
+// arcPoints flattens a circular arc starting at angle a0 (radians) and
+// turning through sweep (positive counter-clockwise). Both ends are
+// included.
+func arcPoints(c Point, r, a0, sweep, flatness float64) []Point {
+	n := 1
+	if flatness < r {
+		n = max(n, int(math.Ceil(math.Abs(sweep)/2/math.Acos(1-flatness/r))))
+	}
+	pts := make([]Point, 0, n+1)
+	for i := 0; i <= n; i++ {
+		a := a0 + sweep*float64(i)/float64(n)
+		pts = append(pts, Point{X: c.X + r*math.Cos(a), Y: c.Y + r*math.Sin(a)})
+	}
+	return pts
+}
+
+// simplifyPath reduces a polyline using Ramer–Douglas–Peucker with the
+// given tolerance. Endpoints are always preserved, so closed paths stay closed.
+func simplifyPath(points []Point, tol float64) []Point {
+	if tol <= 0 || len(points) < 3 {
+		return points
+	}
+	keep := make([]bool, len(points))
+	keep[0] = true
+	keep[len(points)-1] = true
+	rdp(points, 0, len(points)-1, tol, keep)
+
+	out := make([]Point, 0, len(points))
+	for i, p := range points {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func rdp(points []Point, first, last int, tol float64, keep []bool) {
+	if last <= first+1 {
+		return
+	}
+	maxD := -1.0
+	idx := first
+	for i := first + 1; i < last; i++ {
+		d := distPointToSegment(points[i], points[first], points[last])
+		if d > maxD {
+			maxD = d
+			idx = i
+		}
+	}
+	if maxD <= tol {
 		return
 	}
+	keep[idx] = true
+	rdp(points, first, idx, tol, keep)
+	rdp(points, idx, last, tol, keep)
+}
+
+// windingLoops untangles a closed polygon (first == last) that crosses or
+// touches itself. It returns the boundary of the area the polygon winds
+// around in the direction ccw says (winding number ≥ 1 counter-clockwise,
+// ≤ -1 clockwise) as simple loops with that orientation, each closed.
+// Pieces wound the other way are dropped. A polygon that never meets
+// itself comes back unchanged.
+func windingLoops(poly []Point, ccw bool) [][]Point {
+	in := func(w int) bool { return w >= 1 }
+	if !ccw {
+		in = func(w int) bool { return w <= -1 }
+	}
+	loops, split := regionLoops(poly, in)
+	if !split {
+		return loops
+	}
+	if !ccw {
+		for i, l := range loops {
+			loops[i] = reversePoints(l)
+		}
+	}
+	return loops
+}
+
+// regionLoops traces the boundary of the area where in(winding number of
+// poly) holds, for a closed polygon (first == last), as simple closed
+// loops with the area on their left: outlines counter-clockwise, holes
+// clockwise. split is false when the polygon never meets itself; it then
+// comes back unchanged, in one loop.
+func regionLoops(poly []Point, in func(w int) bool) (loops [][]Point, split bool) {
+	pts := poly
+	if len(pts) > 1 && almostEqualPoint(pts[0], pts[len(pts)-1]) {
+		pts = pts[:len(pts)-1]
+	}
+	n := len(pts)
+	if n < 3 {
+		return nil, false
+	}
+
+	// where every edge meets another: crossings, touches and the ends of
+	// collinear overlaps, as parameters along the edge
+	const eps = 1e-9
+	cuts := make([][]float64, n)
+	edge := func(i int) (Point, Point) { return pts[i], pts[(i+1)%n] }
+	grid := newSegGrid(pts)
+	for i := 0; i < n; i++ {
+		a, b := edge(i)
+		r := Point{X: b.X - a.X, Y: b.Y - a.Y}
+		for _, j := range grid.near(a, b, 0) {
+			if j <= i {
+				continue
+			}
+			c, d := edge(j)
+			if math.Max(a.X, b.X) < math.Min(c.X, d.X)-eps || math.Max(c.X, d.X) < math.Min(a.X, b.X)-eps ||
+				math.Max(a.Y, b.Y) < math.Min(c.Y, d.Y)-eps || math.Max(c.Y, d.Y) < math.Min(a.Y, b.Y)-eps {
+				continue
+			}
+			s := Point{X: d.X - c.X, Y: d.Y - c.Y}
+			ac := Point{X: c.X - a.X, Y: c.Y - a.Y}
+			den := cross(r, s)
+			if math.Abs(den) < eps*math.Hypot(r.X, r.Y)*math.Hypot(s.X, s.Y) {
+				if math.Abs(cross(r, ac)) > eps*math.Hypot(r.X, r.Y)*math.Max(1, math.Hypot(ac.X, ac.Y)) {
+					continue // parallel, apart
+				}
+				// collinear: each edge is cut where the other one ends
+				rr, ss := r.X*r.X+r.Y*r.Y, s.X*s.X+s.Y*s.Y
+				for _, q := range []Point{c, d} {
+					cuts[i] = append(cuts[i], ((q.X-a.X)*r.X+(q.Y-a.Y)*r.Y)/rr)
+				}
+				for _, q := range []Point{a, b} {
+					cuts[j] = append(cuts[j], ((q.X-c.X)*s.X+(q.Y-c.Y)*s.Y)/ss)
+				}
+				continue
+			}
+			t, u := cross(ac, s)/den, cross(ac, r)/den
+			if t < -eps || t > 1+eps || u < -eps || u > 1+eps {
+				continue
+			}
+			cuts[i] = append(cuts[i], t)
+			cuts[j] = append(cuts[j], u)
+		}
+	}
+
+	type piece struct{ a, b Point }
+	var pieces []piece
+	for i := 0; i < n; i++ {
+		a, b := edge(i)
+		ts := []float64{0}
+		for _, t := range cuts[i] {
+			if t > eps && t < 1-eps {
+				ts = append(ts, t)
+			}
+		}
+		sort.Float64s(ts)
+		ts = append(ts, 1)
+		for k := 1; k < len(ts); k++ {
+			if ts[k]-ts[k-1] < eps {
+				continue
+			}
+			pieces = append(pieces, piece{lerp(a, b, ts[k-1]), lerp(a, b, ts[k])})
+		}
+		split = split || len(ts) > 2
+	}
+	if !split {
+		return [][]Point{append(append([]Point(nil), pts...), pts[0])}, false
+	}
+
+	// keep the pieces with the area on one side only, turned to have it
+	// on their left
+	var keep []piece
+	seen := map[[4]int64]bool{}
+	key := func(p Point) [2]int64 {
+		return [2]int64{int64(math.Round(p.X * 1e6)), int64(math.Round(p.Y * 1e6))}
+	}
+	for _, pc := range pieces {
+		l := math.Hypot(pc.b.X-pc.a.X, pc.b.Y-pc.a.Y)
+		h := math.Max(1e-6, l*1e-4)
+		m := lerp(pc.a, pc.b, 0.5)
+		nx, ny := -(pc.b.Y-pc.a.Y)/l*h, (pc.b.X-pc.a.X)/l*h
+		left := in(grid.winding(Point{X: m.X + nx, Y: m.Y + ny}))
+		right := in(grid.winding(Point{X: m.X - nx, Y: m.Y - ny}))
+		if left == right {
+			continue
+		}
+		if right {
+			pc.a, pc.b = pc.b, pc.a
+		}
+		ka, kb := key(pc.a), key(pc.b)
+		k := [4]int64{ka[0], ka[1], kb[0], kb[1]}
+		if !seen[k] { // collinear overlaps run twice
+			seen[k] = true
+			keep = append(keep, pc)
+		}
+	}
+
+	// chain them into loops, turning as sharply as possible to the left
+	// where several pieces leave one point
+	from := map[[2]int64][]int{}
+	for i, pc := range keep {
+		from[key(pc.a)] = append(from[key(pc.a)], i)
+	}
+	used := make([]bool, len(keep))
+	for s := range keep {
+		if used[s] {
+			continue
+		}
+		var loop []Point
+		closed := false
+		for cur := s; ; {
+			used[cur] = true
+			pc := keep[cur]
+			loop = append(loop, pc.a)
+			if key(pc.b) == key(keep[s].a) {
+				closed = true
+				break
+			}
+			next, best := -1, math.Inf(-1)
+			dir := Point{X: pc.b.X - pc.a.X, Y: pc.b.Y - pc.a.Y}
+			for _, c := range from[key(pc.b)] {
+				if used[c] {
+					continue
+				}
+				o := Point{X: keep[c].b.X - keep[c].a.X, Y: keep[c].b.Y - keep[c].a.Y}
+				turn := math.Atan2(cross(dir, o), dir.X*o.X+dir.Y*o.Y)
+				if turn > best {
+					next, best = c, turn
+				}
+			}
+			if next < 0 {
+				break
+			}
+			cur = next
+		}
+		if !closed || len(loop) < 3 {
+			continue
+		}
+		// drop the points left along straight edges by the cuts
+		var clean []Point
+		for i, p := range loop {
+			prev, next := loop[(i+len(loop)-1)%len(loop)], loop[(i+1)%len(loop)]
+			e0 := Point{X: p.X - prev.X, Y: p.Y - prev.Y}
+			e1 := Point{X: next.X - p.X, Y: next.Y - p.Y}
+			if math.Abs(cross(e0, e1)) < eps*math.Hypot(e0.X, e0.Y)*math.Hypot(e1.X, e1.Y) &&
+				e0.X*e1.X+e0.Y*e1.Y > 0 {
+				continue
+			}
+			clean = append(clean, p)
+		}
+		if len(clean) >= 3 {
+			loops = append(loops, append(clean, clean[0]))
+		}
+	}
+	return loops, true
+}
+
+// segGrid buckets the edges of a closed polygon into square cells, so
+// crossing, winding and distance queries look at nearby edges only.
+type segGrid struct {
+	pts        []Point
+	minX, minY float64
+	size       float64
+	nx, ny     int
+	cells      [][]int // edge indexes by cell, row-major
+	stamp      []int   // per edge: last query that saw it
+	query      int
+}
+
+func newSegGrid(pts []Point) *segGrid {
+	n := len(pts)
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range pts {
+		minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+		maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+	}
+	// about one edge per cell for a polygon spread over its box
+	side := math.Max(1, math.Ceil(math.Sqrt(float64(n))))
+	size := math.Max(maxX-minX, maxY-minY) / side
+	if size <= 0 {
+		size = 1
+	}
+	g := &segGrid{
+		pts: pts, minX: minX, minY: minY, size: size,
+		nx: int((maxX-minX)/size) + 1, ny: int((maxY-minY)/size) + 1,
+		stamp: make([]int, n),
+	}
+	g.cells = make([][]int, g.nx*g.ny)
+	for i := 0; i < n; i++ {
+		x0, y0, x1, y1 := g.span(pts[i], pts[(i+1)%n], 0)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				g.cells[y*g.nx+x] = append(g.cells[y*g.nx+x], i)
+			}
+		}
+	}
+	return g
+}
+
+// span is the range of cells covering the box of a and b grown by pad.
+func (g *segGrid) span(a, b Point, pad float64) (x0, y0, x1, y1 int) {
+	cell := func(v, lo float64, n int) int {
+		return max(0, min(n-1, int(math.Floor((v-lo)/g.size))))
+	}
+	x0 = cell(math.Min(a.X, b.X)-pad, g.minX, g.nx)
+	x1 = cell(math.Max(a.X, b.X)+pad, g.minX, g.nx)
+	y0 = cell(math.Min(a.Y, b.Y)-pad, g.minY, g.ny)
+	y1 = cell(math.Max(a.Y, b.Y)+pad, g.minY, g.ny)
+	return
+}
+
+// near lists, once each, the edges in cells the box of a and b grown by
+// pad touches.
+func (g *segGrid) near(a, b Point, pad float64) []int {
+	g.query++
+	var out []int
+	x0, y0, x1, y1 := g.span(a, b, pad)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			for _, i := range g.cells[y*g.nx+x] {
+				if g.stamp[i] != g.query {
+					g.stamp[i] = g.query
+					out = append(out, i)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// winding is the polygon's winding number around p. It counts the edges
+// crossing a ray from p along X, upwards as +1 and downwards as -1 on the
+// +X side, and the other way round on the -X side; the ray runs to
+// whichever end of p's row of cells is nearer.
+func (g *segGrid) winding(p Point) int {
+	n := len(g.pts)
+	dir := 1.0
+	end := g.minX + float64(g.nx)*g.size
+	if p.X-g.minX < end-p.X {
+		dir, end = -1, g.minX
+	}
+	wind := 0
+	for _, i := range g.near(p, Point{X: end, Y: p.Y}, 0) {
+		a, b := g.pts[i], g.pts[(i+1)%n]
+		// on the ray's side of p
+		side := cross(Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: p.X - a.X, Y: p.Y - a.Y}) * dir
+		if a.Y <= p.Y && b.Y > p.Y && side > 0 {
+			wind++
+		} else if a.Y > p.Y && b.Y <= p.Y && side < 0 {
+			wind--
+		}
+	}
+	return wind * int(dir)
+}
+
+// closerThan reports whether p lies within d of the polygon.
+func (g *segGrid) closerThan(p Point, d float64) bool {
+	n := len(g.pts)
+	for _, i := range g.near(p, p, d) {
+		if distPointToSegment(p, g.pts[i], g.pts[(i+1)%n]) < d {
+			return true
+		}
+	}
+	return false
+}
 
-	// Subdivide using De Casteljau algorithm
-	m01 := lerp(p0, p1, 0.5)
-	m12 := lerp(p1, p2, 0.5)
-	m23 := lerp(p2, p3, 0.5)
-	m012 := lerp(m01, m12, 0.5)
-	m123 := lerp(m12, m23, 0.5)
-	m0123 := lerp(m012, m123, 0.5)
+// distPointToSegment is like distPointToLine but clamps to the segment ends.
+func distPointToSegment(p, a, b Point) float64 {
+	dx := b.X - a.X
+	dy := b.Y - a.Y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
 
-	// First half: p0, m01, m012, m0123
-	flattenCubicBezier(p0, m01, m012, m0123, flatness, out)
-	// Second half: m0123, m123, m23, p3
-	flattenCubicBezier(m0123, m123, m23, p3, flatness, out)
+// signedArea returns the shoelace area of a polygon; positive means
+// counter-clockwise in a Y-up (machine) coordinate system. A duplicate
+// closing point is harmless.
+func signedArea(poly []Point) float64 {
+	n := len(poly)
+	area := 0.0
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += poly[i].X*poly[j].Y - poly[j].X*poly[i].Y
+	}
+	return area * 0.5
+}
+
+func reversePoints(points []Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[len(points)-1-i] = p
+	}
+	return out
 }
 
 func cross(a, b Point) float64 {
 	return a.X*b.Y - a.Y*b.X
 }
 
+// dedupePoints drops consecutive repeated points, which would otherwise
+// become zero-length segments and edges with no direction.
+func dedupePoints(points []Point) []Point {
+	if len(points) < 2 {
+		return points
+	}
+	out := points[:1]
+	for _, p := range points[1:] {
+		if !almostEqualPoint(p, out[len(out)-1]) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
 func almostEqualPoint(a, b Point) bool {
 	const eps = 1e-9
 	return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func flatten(p0, p1, p2, p3 Point, flatness float64) []Point {
+	var out []Point
+	flattenCubicBezier(p0, p1, p2, p3, flatness, &out)
+	return out
+}
+
+// cubicAt evaluates the Bézier p0..p3 at t.
+func cubicAt(p0, p1, p2, p3 Point, t float64) Point {
+	u := 1 - t
+	a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return Point{X: a*p0.X + b*p1.X + c*p2.X + d*p3.X, Y: a*p0.Y + b*p1.Y + c*p2.Y + d*p3.Y}
+}
+
+func TestFlattenCubicBezierDegenerate(t *testing.T) {
+	p := Point{X: 3, Y: 4}
+	out := flatten(p, p, p, p, 0.1)
+	if len(out) != 1 || out[0] != p {
+		t.Fatalf("a curve that is one point flattened to %v, want just its end", out)
+	}
+
+	// control points on the end points: a straight line
+	a, b := Point{X: 0, Y: 0}, Point{X: 10, Y: 5}
+	out = flatten(a, a, b, b, 0.1)
+	if len(out) != 1 || out[0] != b {
+		t.Fatalf("a curve with its controls on its ends flattened to %v, want one line", out)
+	}
+}
+
+func TestFlattenCubicBezierCollinear(t *testing.T) {
+	// all on the X axis, the controls beyond the ends: the curve runs out
+	// past both before it ends at X10
+	p0, p1, p2, p3 := Point{X: 0}, Point{X: 100}, Point{X: -50}, Point{X: 10}
+	out := flatten(p0, p1, p2, p3, 0.01)
+	lo, hi := 0.0, 0.0
+	for _, p := range out {
+		if p.Y != 0 {
+			t.Fatalf("collinear curve left its line at %v", p)
+		}
+		lo, hi = math.Min(lo, p.X), math.Max(hi, p.X)
+	}
+	// the true extent, sampled finely
+	wantLo, wantHi := 0.0, 0.0
+	for i := range 10001 {
+		x := cubicAt(p0, p1, p2, p3, float64(i)/10000).X
+		wantLo, wantHi = math.Min(wantLo, x), math.Max(wantHi, x)
+	}
+	if math.Abs(lo-wantLo) > 0.05 || math.Abs(hi-wantHi) > 0.05 {
+		t.Fatalf("flattened to X%.3f..X%.3f, the curve reaches X%.3f..X%.3f", lo, hi, wantLo, wantHi)
+	}
+	if out[len(out)-1] != p3 {
+		t.Fatalf("flattened curve ends at %v, want %v", out[len(out)-1], p3)
+	}
+}
+
+func TestFlattenCubicBezierStaysWithinFlatness(t *testing.T) {
+	p0, p1, p2, p3 := Point{X: 0, Y: 0}, Point{X: 0, Y: 40}, Point{X: 60, Y: -40}, Point{X: 60, Y: 0}
+	const flatness = 0.05
+	pts := append([]Point{p0}, flatten(p0, p1, p2, p3, flatness)...)
+	for i := range 1001 {
+		c := cubicAt(p0, p1, p2, p3, float64(i)/1000)
+		best := math.Inf(1)
+		for j := 1; j < len(pts); j++ {
+			best = math.Min(best, distPointToSegment(c, pts[j-1], pts[j]))
+		}
+		if best > 2*flatness {
+			t.Fatalf("curve point %v is %.3f from the polyline, more than flatness %g allows", c, best, flatness)
+		}
+	}
+}
+
+func TestFlattenCubicBezierExtreme(t *testing.T) {
+	limit := 1 << maxBezierDepth
+	for _, tc := range []struct {
+		name           string
+		p0, p1, p2, p3 Point
+		flatness       float64
+	}{
+		{"zero flatness", Point{}, Point{X: 0, Y: 10}, Point{X: 10, Y: 10}, Point{X: 10}, 0},
+		{"huge controls", Point{}, Point{X: 1e99, Y: -1e99}, Point{X: -1e99, Y: 1e99}, Point{X: 1}, 0.1},
+		{"tiny curve", Point{}, Point{X: 1e-12, Y: 1e-12}, Point{X: 2e-12}, Point{X: 3e-12}, 0.1},
+	} {
+		out := flatten(tc.p0, tc.p1, tc.p2, tc.p3, tc.flatness)
+		if len(out) == 0 || len(out) > limit {
+			t.Errorf("%s: %d points, want 1..%d", tc.name, len(out), limit)
+			continue
+		}
+		if out[len(out)-1] != tc.p3 {
+			t.Errorf("%s: ends at %v, want %v", tc.name, out[len(out)-1], tc.p3)
+		}
+		for _, p := range out {
+			if math.IsNaN(p.X+p.Y) || math.IsInf(p.X+p.Y, 0) {
+				t.Errorf("%s: non-finite point %v", tc.name, p)
+				break
+			}
+		}
+	}
+
+	// a non-finite coordinate is a line to the end
+	for _, bad := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		end := Point{X: 5, Y: 5}
+		out := flatten(Point{}, Point{X: bad}, Point{Y: 1}, end, 0.1)
+		if len(out) != 1 || out[0] != end {
+			t.Errorf("control X%v: flattened to %v, want a line to %v", bad, out, end)
+		}
+	}
+}
@@ -1,3 +1,10 @@
 module svg2gcode
 
 go 1.25.4
+
+require (
+	golang.org/x/image v0.33.0
+	golang.org/x/sys v0.38.0
+)
+
+require golang.org/x/text v0.31.0 // indirect
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The golden tests convert the drawings in testdata with the real
+// command line and compare the G-code with testdata/<name>.nc. After a
+// change that is meant to alter the output, rewrite the files with
+//
+//	go test -run Golden -update
+//
+// and review the diff before committing it.
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenEnv, when set, makes the test binary run main with its own
+// arguments instead of the tests, so each case is the program as built.
+const goldenEnv = "SVG2GCODE_GOLDEN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(goldenEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// goldenCases are representative exports, each with the flags a user
+// of that program would typically run it with.
+var goldenCases = []struct {
+	name string // testdata/<name>.nc
+	args []string
+}{
+	// layers, a translated layer, a hidden one and relative curves
+	{"inkscape", []string{"-tooldia", "3", "-comp", "outside", "-stepdown", "0.5", "-cutz", "-1", "inkscape.svg"}},
+	// <style> classes, compact path data and no document size
+	{"illustrator", []string{"-tooldia", "2", "-comp", "inside", "-stepdown", "1", "-cutz", "-2", "-order", "nearest", "illustrator.svg"}},
+	// a flipped sketch in cm, profiled outside with its holes bored
+	{"fusion", []string{"-scale", "10", "-tooldia", "3", "-comp", "outside", "-bore", "-cutz", "-3", "-stepdown", "1", "fusion.svg"}},
+	// an engraved image and cut vectors in one laser job
+	{"laser", []string{"-laser", "-laser-dpi", "50.8", "-overscan", "2", "-cutz", "-1", "-stepdown", "0.5",
+		"-colormap", "#ff0000:op=score,depth=-0.2", "laser.svg"}},
+}
+
+func TestGolden(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(exe, tc.args...)
+			cmd.Dir = "testdata"
+			cmd.Env = append(os.Environ(), goldenEnv+"=1")
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("svg2gcode %s: %v\n%s", strings.Join(tc.args, " "), err, stderr.Bytes())
+			}
+			golden := filepath.Join("testdata", tc.name+".nc")
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -run Golden -update to create it)", err)
+			}
+			if got := stdout.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("svg2gcode %s differs from %s:\n%s",
+					strings.Join(tc.args, " "), golden, firstDiff(string(want), string(got)))
+			}
+		})
+	}
+}
+
+// firstDiff describes the first line where got differs from want.
+func firstDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return "(same lines, different bytes)"
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decoders for <image>
+	_ "image/png"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// With -heightmap, <image> elements are engraved as reliefs: the image
+// is scanned in rows across its box, back and forth, and each sample is
+// cut as deep as the pixel is dark, from the stock top for white down to
+// the path's depth (-cutz, or its data-depth / colormap rule) for black.
+// With -laser they are raster-engraved instead (see laser.go). Otherwise
+// images are ignored, as they always were.
+
+// svgImage is an <image> element. Its path holds the corners of its box,
+// top-left first, so the box follows the path through every transform.
+type svgImage struct {
+	Href   string      // data: URI or file name
+	Aspect string      // preserveAspectRatio
+	W, H   float64     // box size in user units
+	Img    image.Image // decoded by loadImages; nil until then
+}
+
+// loadImages decodes the images of image paths. Files are looked up
+// relative to dir; with dir "" only images embedded as data: URIs are
+// read. Images that can't be read are warned about and their paths
+// dropped.
+func loadImages(paths []Path, dir string, warn *Warnings) []Path {
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if p.Image == nil {
+			out = append(out, p)
+			continue
+		}
+		img, err := decodeImage(p.Image.Href, dir)
+		if err != nil {
+			warn.Add(WUnknownReference, p.Index, "<image href=%q>: %v; skipped", truncate(p.Image.Href, 40), err)
+			continue
+		}
+		im := *p.Image
+		im.Img = img
+		p.Image = &im
+		out = append(out, p)
+	}
+	return out
+}
+
+// decodeImage reads the PNG or JPEG an <image> href refers to.
+func decodeImage(href, dir string) (image.Image, error) {
+	var data []byte
+	if rest, ok := strings.CutPrefix(href, "data:"); ok {
+		meta, payload, ok := strings.Cut(rest, ",")
+		if !ok {
+			return nil, errors.New("malformed data: URI")
+		}
+		var err error
+		if strings.HasSuffix(meta, ";base64") {
+			data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+		} else {
+			var s string
+			s, err = url.PathUnescape(payload)
+			data = []byte(s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("data: URI: %w", err)
+		}
+	} else {
+		if dir == "" {
+			return nil, errors.New("linked images can't be read here, embed it")
+		}
+		name := strings.TrimPrefix(href, "file://")
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, filepath.FromSlash(name))
+		}
+		var err error
+		if data, err = os.ReadFile(name); err != nil {
+			return nil, err
+		}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// reliefPaths replaces every image path by the zigzag of its relief,
+// which carries a depth per point, or with -laser by its raster scan,
+// which carries a power per point. Image paths that aren't engraved,
+// without -heightmap or -laser or with no decoded image, are dropped.
+func reliefPaths(paths []Path, cfg Config) []Path {
+	engrave := relief
+	if cfg.Laser.Enabled {
+		engrave = raster
+	}
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if p.Image == nil {
+			out = append(out, p)
+			continue
+		}
+		if !cfg.Heightmap && !cfg.Laser.Enabled || p.Image.Img == nil || len(p.Points) != 4 {
+			continue
+		}
+		if r := engrave(p, cfg); len(r.Points) > 1 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// imageSamples is the brightness of an image sampled in a grid over the
+// part of its box the image covers, 0 black to 1 white.
+type imageSamples struct {
+	Light      [][]float64
+	Rows, Cols int
+	Cells      bool // samples are the centres of Rows x Cols cells, not points from edge to edge
+
+	u0, u1, v0, v1 float64 // the sampled part of the box, in its user units
+	o, ex, ey      Point   // the box's first corner and its edges, in mm
+	w, h           float64 // the box size in user units
+}
+
+// sampleImage samples the image of p in rows step mm apart, as often
+// along each row: from edge to edge, or with cells set at the centres of
+// cells about step mm square. ok is false when no part of the image is
+// in its box.
+func sampleImage(p Path, step float64, cells bool) (s imageSamples, ok bool) {
+	im := p.Image
+	b := im.Img.Bounds()
+	pw, ph := b.Dx(), b.Dy()
+	if pw == 0 || ph == 0 || im.W <= 0 || im.H <= 0 {
+		return s, false
+	}
+
+	// where the pixels land in the box, by preserveAspectRatio
+	attr := func(k, v string) xml.Attr { return xml.Attr{Name: xml.Name{Local: k}, Value: v} }
+	pt, _ := viewportTransform([]xml.Attr{
+		attr("viewBox", fmt.Sprintf("0 0 %d %d", pw, ph)),
+		attr("width", strconv.FormatFloat(im.W, 'g', -1, 64)),
+		attr("height", strconv.FormatFloat(im.H, 'g', -1, 64)),
+		attr("preserveAspectRatio", im.Aspect),
+	}, viewport{})
+	s.u0, s.u1 = math.Max(pt.E, 0), math.Min(pt.E+float64(pw)*pt.A, im.W)
+	s.v0, s.v1 = math.Max(pt.F, 0), math.Min(pt.F+float64(ph)*pt.D, im.H)
+	if s.u1 <= s.u0 || s.v1 <= s.v0 {
+		return s, false
+	}
+
+	// the box's corners in machine coordinates, and its edges in mm
+	o, ex, ey := p.Points[0], p.Points[1], p.Points[3]
+	s.o, s.ex, s.ey = o, Point{X: ex.X - o.X, Y: ex.Y - o.Y}, Point{X: ey.X - o.X, Y: ey.Y - o.Y}
+	s.w, s.h = im.W, im.H
+	lu, lv := math.Hypot(s.ex.X, s.ex.Y)/im.W, math.Hypot(s.ey.X, s.ey.Y)/im.H // mm per user unit
+	s.Cells = cells
+	if cells {
+		s.Cols = max(int(math.Round((s.u1-s.u0)*lu/step)), 1)
+		s.Rows = max(int(math.Round((s.v1-s.v0)*lv/step)), 1)
+	} else {
+		s.Cols = int(math.Ceil((s.u1-s.u0)*lu/step)) + 1
+		s.Rows = int(math.Ceil((s.v1-s.v0)*lv/step)) + 1
+	}
+
+	s.Light = make([][]float64, s.Rows)
+	for r := range s.Rows {
+		v := s.at(float64(r), s.Rows, s.v0, s.v1)
+		y := min(max(int((v-pt.F)/pt.D), 0), ph-1)
+		s.Light[r] = make([]float64, s.Cols)
+		for c := range s.Cols {
+			u := s.at(float64(c), s.Cols, s.u0, s.u1)
+			x := min(max(int((u-pt.E)/pt.A), 0), pw-1)
+			s.Light[r][c] = brightness(im.Img, b.Min.X+x, b.Min.Y+y)
+		}
+	}
+	return s, true
+}
+
+// at is where sample i of n lies between lo and hi. i may be fractional,
+// or outside 0..n-1, to reach between or beyond samples.
+func (s imageSamples) at(i float64, n int, lo, hi float64) float64 {
+	switch {
+	case s.Cells:
+		return lo + (hi-lo)*(i+0.5)/float64(n)
+	case n == 1:
+		return lo
+	}
+	return lo + (hi-lo)*i/float64(n-1)
+}
+
+// pos is where sample c of row r lies in machine coordinates.
+func (s imageSamples) pos(r int, c float64) Point {
+	u := s.at(c, s.Cols, s.u0, s.u1)
+	v := s.at(float64(r), s.Rows, s.v0, s.v1)
+	return Point{X: s.o.X + s.ex.X*u/s.w + s.ey.X*v/s.h, Y: s.o.Y + s.ex.Y*u/s.w + s.ey.Y*v/s.h}
+}
+
+// relief scans the image of p in rows cfg.HeightmapStep apart, sampled
+// as often along each row, within the part of the box the image covers.
+// The samples are dithered, if asked, before they become depths.
+func relief(p Path, cfg Config) Path {
+	out := Path{Stroke: p.Stroke, Transform: p.Transform, Index: p.Index, Shape: p.Shape,
+		Label: p.Label, Layer: p.Layer, Override: p.Override, Image: p.Image}
+	s, ok := sampleImage(p, cfg.HeightmapStep, false)
+	if !ok {
+		return out
+	}
+	light := s.Light
+	if cfg.HeightmapInvert {
+		for _, row := range light {
+			for c := range row {
+				row[c] = 1 - row[c]
+			}
+		}
+	}
+	dither(light, cfg.Dither)
+
+	target := p.rule(cfg).targetZ(cfg)
+	for r := range s.Rows {
+		start := len(out.Points)
+		for c := range s.Cols {
+			if r%2 == 1 {
+				c = s.Cols - 1 - c // back the other way
+			}
+			pt := s.pos(r, float64(c))
+			z := target*(1-light[r][c]) + 0 // +0 rather than -0 for white
+			if n := len(out.Points); n-start >= 2 && out.Depths[n-1] == z && out.Depths[n-2] == z {
+				out.Points[n-1] = pt // a level run is one move
+				continue
+			}
+			out.Points = append(out.Points, pt)
+			out.Depths = append(out.Depths, z)
+		}
+	}
+	return out
+}
+
+// brightness is how light the pixel at x, y is, 0 black to 1 white, as
+// if laid over white stock: transparent pixels are not cut.
+func brightness(img image.Image, x, y int) float64 {
+	r, g, b, a := img.At(x, y).RGBA()     // alpha-premultiplied, 0..0xffff
+	lum := (299*r + 587*g + 114*b) / 1000 // exact, so white is exactly 1
+	return float64(lum+0xffff-a) / 0xffff
+}
+
+// planRelief cuts an image relief, in passes no deeper than the step-down
+// below the one before so a deep relief isn't cut all at once.
+func planRelief(prog *Program, p Path, cfg Config) {
+	prog.op = "relief"
+	b := p.Image.Img.Bounds()
+	prog.Comment(cfg.Msg.T("relief", b.Dx(), b.Dy()))
+	rule := p.rule(cfg)
+	step := cfg.StepDown
+	if rule.StepDown > 0 {
+		step = rule.StepDown
+	}
+	deepest := 0.0
+	for _, z := range p.Depths {
+		deepest = math.Min(deepest, z)
+	}
+	feed, plunge := rule.feeds(cfg)
+	for _, floor := range passDepths(deepest, step) {
+		hop(prog, p.Points[0].X, p.Points[0].Y, cfg)
+		prog.FeedZ(math.Max(p.Depths[0], floor), plunge)
+		for i := 1; i < len(p.Points); i++ {
+			prog.FeedXYZ(p.Points[i].X, p.Points[i].Y, math.Max(p.Depths[i], floor), feed)
+		}
+	}
+}
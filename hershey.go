@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HersheyFont is a single-stroke font in the classic Hershey .jhf format.
+// Engravers want these for small lettering: an outline font at 3 mm cap
+// height has strokes narrower than any cutter, a Hershey glyph is cut
+// once down the middle of each stroke.
+type HersheyFont struct {
+	Glyphs map[rune]hersheyGlyph
+}
+
+type hersheyGlyph struct {
+	Left, Right int       // horizontal bounds; the advance is Right-Left
+	Strokes     [][]Point // pen-down polylines, Y down, origin mid-glyph
+}
+
+// Hershey coordinates span about 32 units per em with the baseline at +9.
+const (
+	hersheyEm       = 32.0
+	hersheyBaseline = 9.0
+)
+
+// loadHershey loads a .jhf file. name is either a path, or a font name
+// such as "futural" looked up as name.jhf in dir.
+func loadHershey(name, dir string) (*HersheyFont, error) {
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) && filepath.Ext(name) == "" {
+		path = filepath.Join(dir, name+".jhf")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	font, err := parseJHF(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return font, nil
+}
+
+// parseJHF reads .jhf glyph records. Each record is a 5-digit glyph
+// number, a 3-digit vertex count, then that many coordinate pairs, each
+// coordinate a character offset from 'R'. The first pair holds the left
+// and right bounds and " R" lifts the pen. Long records wrap onto
+// following lines. Glyphs map to consecutive characters from space, as
+// in the standard ASCII-ordered files.
+func parseJHF(r io.Reader) (*HersheyFont, error) {
+	font := &HersheyFont{Glyphs: map[rune]hersheyGlyph{}}
+	sc := bufio.NewScanner(r)
+	ch := ' '
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line) < 8 {
+			return nil, fmt.Errorf("short glyph record %q", line)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line[5:8]))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("bad vertex count in %q", line)
+		}
+		data := line[8:]
+		for len(data) < 2*n && sc.Scan() {
+			data += sc.Text()
+		}
+		if len(data) < 2*n {
+			return nil, fmt.Errorf("glyph %q: record truncated", ch)
+		}
+
+		g := hersheyGlyph{Left: int(data[0]) - 'R', Right: int(data[1]) - 'R'}
+		var cur []Point
+		for i := 1; i < n; i++ {
+			a, b := data[2*i], data[2*i+1]
+			if a == ' ' && b == 'R' {
+				if len(cur) > 1 {
+					g.Strokes = append(g.Strokes, cur)
+				}
+				cur = nil
+				continue
+			}
+			cur = append(cur, Point{X: float64(int(a) - 'R'), Y: float64(int(b) - 'R')})
+		}
+		if len(cur) > 1 {
+			g.Strokes = append(g.Strokes, cur)
+		}
+		font.Glyphs[ch] = g
+		ch++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(font.Glyphs) == 0 {
+		return nil, fmt.Errorf("no glyphs")
+	}
+	return font, nil
+}
+
+// textStrokes lays out s as open pen strokes in SVG user units, with the
+// same x/y/size/anchor meaning as textOutlines. Characters the font lacks
+// advance like a space.
+func (f *HersheyFont) textStrokes(s string, x, y, size float64, anchor string) [][]Point {
+	scale := size / hersheyEm
+	space := f.Glyphs[' ']
+	width := 0.0
+	for _, r := range s {
+		g, ok := f.Glyphs[r]
+		if !ok {
+			g = space
+		}
+		width += float64(g.Right - g.Left)
+	}
+	switch anchor {
+	case "middle":
+		x -= width * scale / 2
+	case "end":
+		x -= width * scale
+	}
+
+	var out [][]Point
+	pen := 0.0
+	for _, r := range s {
+		g, ok := f.Glyphs[r]
+		if !ok {
+			g = space
+		}
+		for _, st := range g.Strokes {
+			pts := make([]Point, len(st))
+			for i, p := range st {
+				pts[i] = Point{
+					X: x + (pen+p.X-float64(g.Left))*scale,
+					Y: y + (p.Y-hersheyBaseline)*scale,
+				}
+			}
+			out = append(out, pts)
+		}
+		pen += float64(g.Right - g.Left)
+	}
+	return out
+}
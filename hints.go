@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// grblHints suggests GRBL settings for the job, one block per operation,
+// from the cutting segment lengths and feeds in moves. They are advice for
+// the operator only and are emitted as comments; nothing is sent to the
+// controller.
+//
+// The reasoning: a segment of length L can only be run at feed v if the
+// machine can accelerate to v within about half of it, so acceleration
+// should be at least v²/L. We use the 10th percentile segment length
+// rather than the absolute shortest, since a few tiny segments from curve
+// flattening should not dictate the whole job. Junction deviation ($11)
+// is kept well below typical segment length so corners are not rounded
+// more than the geometry itself.
+func grblHints(moves []Move) []string {
+	type stats struct {
+		lengths   []float64
+		maxFeed   float64
+		maxPlunge float64
+	}
+	ops := map[string]*stats{}
+	var order []string
+
+	var prev Move
+	havePrev := false
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		if m.Kind == MoveFeed && havePrev {
+			op := m.Op
+			if op == "" {
+				op = "cut"
+			}
+			st := ops[op]
+			if st == nil {
+				st = &stats{}
+				ops[op] = st
+				order = append(order, op)
+			}
+			if m.Axes&(AxisX|AxisY) != 0 {
+				if l := math.Hypot(m.X-prev.X, m.Y-prev.Y); l > 1e-6 {
+					st.lengths = append(st.lengths, l)
+					st.maxFeed = math.Max(st.maxFeed, m.F)
+				}
+			} else if m.Axes&AxisZ != 0 {
+				st.maxPlunge = math.Max(st.maxPlunge, m.F)
+			}
+		}
+		prev, havePrev = m, true
+	}
+
+	var out []string
+	for _, op := range order {
+		st := ops[op]
+		if len(st.lengths) == 0 {
+			continue
+		}
+		sort.Float64s(st.lengths)
+		shortest := st.lengths[0]
+		p10 := st.lengths[len(st.lengths)/10]
+		v := st.maxFeed / 60 // mm/s
+		accel := math.Ceil(v * v / p10)
+		jd := math.Max(0.002, math.Min(0.02, p10/50))
+
+		out = append(out,
+			fmt.Sprintf("GRBL hints for %s: shortest segment %.3f mm, 10th percentile %.3f mm, feed %.0f mm/min",
+				op, shortest, p10, st.maxFeed),
+			fmt.Sprintf("  $110/$111 max rate X/Y >= %.0f mm/min", st.maxFeed),
+			fmt.Sprintf("  $120/$121 accel X/Y >= %.0f mm/s^2", accel),
+			fmt.Sprintf("  $11 junction deviation ~ %.3f mm", jd))
+		if st.maxPlunge > 0 {
+			out = append(out, fmt.Sprintf("  $112 max rate Z >= %.0f mm/min", st.maxPlunge))
+		}
+	}
+	return out
+}
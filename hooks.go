@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Hooks are G-code snippets from the user, written into the program
+// around the whole job and around every path: air assist on and off, a
+// dust boot up and down, a custom probing routine. Each is one or more
+// lines, "" = none.
+type Hooks struct {
+	PreJob   string // after the units and coordinate setup, before probing and the spindle
+	PostJob  string // after the spindle stops, before the program end
+	PrePath  string // before moving to each path
+	PostPath string // after each path, with the tool out of the cut
+}
+
+// parseSnippet reads a hook flag: "@file" takes the snippet from a file,
+// anything else is the snippet itself with "\n" standing for a line
+// break.
+func parseSnippet(s string) (string, error) {
+	if name, ok := strings.CutPrefix(s, "@"); ok {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		s = string(data)
+	} else {
+		s = strings.ReplaceAll(s, `\n`, "\n")
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.TrimRight(s, "\n"), nil
+}
+
+// inject writes a snippet line by line. The snippet may move the machine,
+// so the next move after it rapids from safe Z as if the position were
+// unknown.
+func inject(prog *Program, snippet string) {
+	if snippet == "" {
+		return
+	}
+	for _, line := range strings.Split(snippet, "\n") {
+		prog.Raw(strings.TrimRight(line, " \t"))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// hpglUnits is the HP-GL plotter unit: 0.025 mm.
+const hpglUnits = 40 // per mm
+
+// writeHPGL plans paths like writeGcode and writes the result as HP-GL
+// for vinyl cutters and pen plotters. The pen is down wherever the tool
+// would be below the stock top (Z < 0); comments, pauses and spindle
+// control have no HP-GL equivalent and are dropped.
+func writeHPGL(w io.Writer, paths []Path, cfg Config) error {
+	body, err := planJob(paths, cfg)
+	if err != nil {
+		return err
+	}
+	return emitHPGL(w, body.Moves)
+}
+
+// emitHPGL writes planned moves as PU/PD/PA plotter commands. Arcs are
+// kept as arcs (AA).
+func emitHPGL(w io.Writer, moves []Move) error {
+	bw := bufio.NewWriter(w)
+	u := func(v float64) int { return int(math.Round(v * hpglUnits)) }
+
+	fmt.Fprint(bw, "IN;SP1;PA;\n")
+	down := false
+	var prev Move
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		want := m.Z < 0
+		if want != down {
+			if want {
+				fmt.Fprint(bw, "PD;\n")
+			} else {
+				fmt.Fprint(bw, "PU;\n")
+			}
+			down = want
+		}
+		if m.Axes&(AxisX|AxisY) != 0 {
+			switch {
+			case m.isArc():
+				c, _, sweep := arcGeometry(prev, m)
+				if m.Kind == MoveArcCW {
+					sweep = -sweep
+				}
+				fmt.Fprintf(bw, "AA%d,%d,%.3f;\n", u(c.X), u(c.Y), sweep*180/math.Pi)
+			case down:
+				fmt.Fprintf(bw, "PD%d,%d;\n", u(m.X), u(m.Y))
+			default:
+				fmt.Fprintf(bw, "PU%d,%d;\n", u(m.X), u(m.Y))
+			}
+		}
+		prev = m
+	}
+	fmt.Fprint(bw, "PU;SP0;IN;\n")
+	return bw.Flush()
+}
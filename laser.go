@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// With -laser the job is planned for a diode or CO2 laser instead of a
+// spindle. There is no Z: the beam is switched with M4 dynamic power,
+// which GRBL and most laser firmware turn off during G0, and every
+// burning move carries its S word. Vectors are cut at full power, once
+// per depth pass, and <image> elements are engraved in raster lines with
+// the power of each run taken from how dark its pixels are, so one
+// drawing can hold both the cut and the engraving.
+
+// Laser is the -laser configuration; the zero value is a milling job.
+type Laser struct {
+	Enabled        bool
+	Power          float64 // S for vectors and black pixels
+	MinPower       float64 // S for the lightest shade that is engraved; white is off
+	Interval       float64 // raster line and sample spacing, mm (25.4 / -laser-dpi)
+	Overscan       float64 // unlit run past both ends of a raster line, mm
+	Unidirectional bool    // scan every line the same way instead of back and forth
+}
+
+// enableLaser checks the -laser options l, with dpi raster lines per
+// inch, and switches cfg to laser mode. The beam has no depth and no
+// spindle, so the options given that plunge, carve, probe or spin up are
+// warned about and turned off.
+func enableLaser(cfg *Config, l Laser, dpi float64, outFormat string, given map[string]bool) error {
+	switch {
+	case cfg.Heightmap:
+		return errors.New("-heightmap and -laser both engrave <image> elements; give one")
+	case l.Power <= 0 || l.MinPower < 0 || l.MinPower >= l.Power:
+		return errors.New("-laser-power must be > 0 and -laser-min-power at least 0 and below it")
+	case dpi <= 0:
+		return errors.New("-laser-dpi must be > 0")
+	case l.Overscan < 0:
+		return errors.New("-overscan must be >= 0")
+	case strings.ToLower(outFormat) != "gcode":
+		return fmt.Errorf("-laser sets the power in S words; it cannot be combined with -outformat %s", outFormat)
+	}
+	l.Enabled, l.Interval = true, 25.4/dpi
+	cfg.Laser = l
+
+	for _, o := range []struct {
+		set  bool
+		name string
+	}{
+		{cfg.Bore, "-bore"}, {cfg.Slots, "-slots"}, {cfg.VCarve, "-vcarve"}, {given["entry"], "-entry"},
+		{cfg.RetractZ > 0, "-retract-z"}, {cfg.SpindleRPM > 0, "-rpm"}, {cfg.Probe.Enabled, "-probe"},
+		{given["aircut"] || given["aircut-only"], "-aircut"}, {cfg.CompMode == "controller", "-comp-mode controller"},
+	} {
+		if o.set {
+			cfg.Warn.Add(WIgnoredOption, 0, "%s does not apply with -laser", o.name)
+		}
+	}
+	cfg.Bore, cfg.Slots, cfg.VCarve, cfg.Entry = false, false, false, straightEntry{}
+	cfg.RetractZ, cfg.SpindleRPM, cfg.Probe.Enabled = 0, 0, false
+	if cfg.CompMode == "controller" {
+		cfg.CompMode = "software" // the kerf is offset here instead
+	}
+	return nil
+}
+
+// power is the S word for a sample of brightness light, 0 black to 1
+// white, in 256 levels like the 8-bit images they come from.
+func (l Laser) power(light float64) float64 {
+	level := math.Round(255 * (1 - light))
+	if level <= 0 {
+		return 0
+	}
+	s := l.MinPower + (l.Power-l.MinPower)*level/255
+	return math.Round(s*1000) / 1000
+}
+
+// raster scans the image of p in lines cfg.Laser.Interval apart, a
+// sample in the middle of every cell that wide, into one path with the
+// power of every move.
+// Each line runs from the first dark sample to the last, with the
+// overscan unlit before and after it so the head is at speed where it
+// burns; blank lines are skipped. Lines alternate direction unless
+// Unidirectional is set, and the moves between them are rapids.
+func raster(p Path, cfg Config) Path {
+	out := Path{Stroke: p.Stroke, Transform: p.Transform, Index: p.Index, Shape: p.Shape,
+		Label: p.Label, Layer: p.Layer, Override: p.Override, Image: p.Image}
+	l := cfg.Laser
+	s, ok := sampleImage(p, l.Interval, true)
+	if !ok {
+		return out
+	}
+	dither(s.Light, cfg.Dither)
+
+	add := func(pt Point, power float64) {
+		out.Points = append(out.Points, pt)
+		out.Power = append(out.Power, power)
+	}
+	forward := true
+	for r := range s.Rows {
+		powers := make([]float64, s.Cols)
+		first, last := -1, -1
+		for c, v := range s.Light[r] {
+			if powers[c] = l.power(v); powers[c] > 0 {
+				if first < 0 {
+					first = c
+				}
+				last = c
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		// each sample burns its cell, from edge to edge
+		step, from, to := 1, first, last
+		if !forward {
+			step, from, to = -1, last, first
+		}
+		half := 0.5 * float64(step)
+		a, b := s.pos(r, float64(from)-half), s.pos(r, float64(to)+half)
+		d := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if d == 0 {
+			continue // the box was scaled to nothing
+		}
+		dir := Point{X: (b.X - a.X) / d, Y: (b.Y - a.Y) / d}
+		if l.Overscan > 0 {
+			add(Point{X: a.X - dir.X*l.Overscan, Y: a.Y - dir.Y*l.Overscan}, -1)
+			add(a, 0)
+		} else {
+			add(a, -1)
+		}
+		start := len(out.Points)
+		for c := from; ; c += step {
+			pt := s.pos(r, float64(c)+half)
+			if n := len(out.Points); n > start && out.Power[n-1] == powers[c] {
+				out.Points[n-1] = pt // a run of one shade is one move
+			} else {
+				add(pt, powers[c])
+			}
+			if c == to {
+				break
+			}
+		}
+		if l.Overscan > 0 {
+			add(Point{X: b.X + dir.X*l.Overscan, Y: b.Y + dir.Y*l.Overscan}, 0)
+		}
+		if !l.Unidirectional {
+			forward = !forward
+		}
+	}
+	return out
+}
+
+// planRaster engraves a raster path at the image's feed: rapids between
+// lines, and every other move at its own power.
+func planRaster(prog *Program, p Path, cfg Config) {
+	prog.op = "raster"
+	b := p.Image.Img.Bounds()
+	prog.Comment(cfg.Msg.T("raster", b.Dx(), b.Dy(), 25.4/cfg.Laser.Interval))
+	feed, _ := p.rule(cfg).feeds(cfg)
+	for i, pt := range p.Points {
+		if p.Power[i] < 0 {
+			prog.RapidXY(pt.X, pt.Y)
+		} else {
+			prog.FeedXYS(pt.X, pt.Y, feed, p.Power[i])
+		}
+	}
+}
+
+// planBeam cuts a vector path with the laser at full power, one pass for
+// every depth pass a spindle would have made, so -stepdown, data-passes
+// and colormap rules still set how many times a line is burnt.
+func planBeam(prog *Program, p Path, rule ColorRule, cfg Config) {
+	step := cfg.StepDown
+	if rule.Op == "score" {
+		step = 0 // scores are one pass unless the rule asks for more
+	}
+	if rule.StepDown > 0 {
+		step = rule.StepDown
+	}
+	feed, _ := rule.feeds(cfg)
+	pts := p.Points
+	for n := range passDepths(rule.targetZ(cfg), step) {
+		if n > 0 && cfg.PingPong && !p.Closed {
+			pts = reversePoints(pts) // already at the far end: burn back
+		}
+		if !prog.xyKnown || prog.x != pts[0].X || prog.y != pts[0].Y {
+			prog.RapidXY(pts[0].X, pts[0].Y)
+		}
+		for _, pt := range pts[1:] {
+			prog.FeedXYS(pt.X, pt.Y, feed, cfg.Laser.Power)
+		}
+	}
+}
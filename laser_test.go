@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// rasterImage is an image path 8 x 2 mm at the origin, Y up, with one
+// grey level per column: white, white, then black fading to white.
+func rasterImage() Path {
+	img := image.NewGray(image.Rect(0, 0, 8, 2))
+	shades := []uint8{255, 255, 0, 64, 128, 191, 255, 255}
+	for y := range 2 {
+		for x, v := range shades {
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return Path{
+		Index:  1,
+		Image:  &svgImage{W: 8, H: 2, Img: img},
+		Points: []Point{{X: 0, Y: 2}, {X: 8, Y: 2}, {X: 8, Y: 0}, {X: 0, Y: 0}},
+	}
+}
+
+func laserConfig() Config {
+	return Config{
+		SafeZ: 5, CutDepth: -2, StepDown: 1, CutFeed: 300, PlungeFeed: 100,
+		Scale: 1, Compensation: "none", Units: "mm",
+		Warn:  &Warnings{Out: io.Discard},
+		Laser: Laser{Enabled: true, Power: 1000, Interval: 1, Overscan: 2},
+	}
+}
+
+func TestRasterLines(t *testing.T) {
+	cfg := laserConfig()
+	r := raster(rasterImage(), cfg)
+	if len(r.Points) != len(r.Power) {
+		t.Fatalf("%d points but %d powers", len(r.Points), len(r.Power))
+	}
+
+	// two lines through the middle of the pixel rows, each a rapid to
+	// the overscan, the run-up, one move per shade and the run-out
+	type move struct {
+		X, Y, S float64
+	}
+	want := []move{
+		{0, 1.5, -1}, {2, 1.5, 0}, {3, 1.5, 1000}, {4, 1.5, 749.02}, {5, 1.5, 498.039}, {6, 1.5, 250.98}, {8, 1.5, 0},
+		{8, 0.5, -1}, {6, 0.5, 0}, {5, 0.5, 250.98}, {4, 0.5, 498.039}, {3, 0.5, 749.02}, {2, 0.5, 1000}, {0, 0.5, 0},
+	}
+	if len(r.Points) != len(want) {
+		t.Fatalf("got %d moves %v %v, want %d", len(r.Points), r.Points, r.Power, len(want))
+	}
+	for i, w := range want {
+		got := move{r.Points[i].X, r.Points[i].Y, r.Power[i]}
+		if math.Abs(got.X-w.X) > 1e-9 || math.Abs(got.Y-w.Y) > 1e-9 || got.S != w.S {
+			t.Errorf("move %d: X%g Y%g S%g, want X%g Y%g S%g", i, got.X, got.Y, got.S, w.X, w.Y, w.S)
+		}
+	}
+}
+
+func TestRasterUnidirectional(t *testing.T) {
+	cfg := laserConfig()
+	cfg.Laser.Unidirectional = true
+	cfg.Laser.Overscan = 0
+	r := raster(rasterImage(), cfg)
+	for i := 1; i < len(r.Points); i++ {
+		if r.Power[i] >= 0 && r.Points[i].X < r.Points[i-1].X {
+			t.Fatalf("move %d burns right to left: %v to %v", i, r.Points[i-1], r.Points[i])
+		}
+	}
+	if r.Power[0] != -1 || r.Points[0].X != 2 {
+		t.Fatalf("without overscan the line starts at X%g S%g, want a rapid to the first dark pixel at X2", r.Points[0].X, r.Power[0])
+	}
+}
+
+func TestLaserPower(t *testing.T) {
+	l := Laser{Power: 1000, MinPower: 200}
+	for _, tc := range []struct{ light, want float64 }{
+		{1, 0}, {0.999, 0}, {0, 1000}, {254.0 / 255, 200 + 800.0/255},
+	} {
+		if got := l.power(tc.light); math.Abs(got-tc.want) > 1e-3 {
+			t.Errorf("power(%g) = %g, want %g", tc.light, got, tc.want)
+		}
+	}
+}
+
+func TestPlanLaserJob(t *testing.T) {
+	cfg := laserConfig()
+	cfg.StepDown = 1 // -cutz -2: two passes of the beam
+	square := Path{Index: 2, Closed: true, Stroke: "#000000",
+		Points: []Point{{X: 10, Y: 10}, {X: 20, Y: 10}, {X: 20, Y: 20}, {X: 10, Y: 10}}}
+	paths := []Path{raster(rasterImage(), cfg), square}
+	prog := newProgram(cfg.SafeZ)
+	planPaths(prog, paths, cfg)
+
+	burns := 0
+	for i, m := range prog.Moves {
+		if m.Axes&AxisZ != 0 {
+			t.Fatalf("move %d moves Z: %+v", i, m)
+		}
+		if m.Kind == MoveFeed && m.Axes&AxisS == 0 {
+			t.Fatalf("move %d feeds without a power: %+v", i, m)
+		}
+		if m.Path == 2 && m.Kind == MoveFeed {
+			burns++
+			if m.S != cfg.Laser.Power {
+				t.Errorf("vector move %d at S%g, want S%g", i, m.S, cfg.Laser.Power)
+			}
+		}
+	}
+	if burns != 2*3 {
+		t.Errorf("square burnt in %d moves, want two passes of 3", burns)
+	}
+
+	var b bytes.Buffer
+	if err := emitProgram(&b, prog.Moves, cfg); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{"M4 S0", "G1 X3.000 Y1.500 F300.000 S1000\n", "G1 X4.000 Y1.500 F300.000 S749.02\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("program lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Z") || strings.Contains(out, "M3") {
+		t.Errorf("laser program moves Z or starts a spindle:\n%s", out)
+	}
+}
+
+func TestEmitModalPower(t *testing.T) {
+	moves := []Move{
+		{Kind: MoveFeed, Axes: AxisX | AxisS, X: 1, F: 100, S: 500},
+		{Kind: MoveFeed, Axes: AxisX | AxisS, X: 2, F: 100, S: 500},
+		{Kind: MoveFeed, Axes: AxisX | AxisS, X: 3, F: 100, S: 0},
+	}
+	var b bytes.Buffer
+	if err := emitGcode(&b, moves, gcodeFormat{Factor: 1, Digits: 1, Modal: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "G1 X1.0 F100.0 S500\nX2.0\nX3.0 S0\n"; b.String() != want {
+		t.Fatalf("got\n%swant\n%s", b.String(), want)
+	}
+}
+
+func TestEnableLaser(t *testing.T) {
+	l := Laser{Power: 1000, Overscan: 3}
+	cfg := Config{Bore: true, RetractZ: 1, SpindleRPM: 18000, CompMode: "controller", Warn: &Warnings{Out: io.Discard}}
+	if err := enableLaser(&cfg, l, 254, "gcode", map[string]bool{"aircut": true}); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Laser.Enabled || math.Abs(cfg.Laser.Interval-0.1) > 1e-12 {
+		t.Errorf("laser %+v, want enabled at a 0.1 mm interval", cfg.Laser)
+	}
+	if cfg.Bore || cfg.RetractZ != 0 || cfg.SpindleRPM != 0 || cfg.CompMode != "software" {
+		t.Errorf("spindle options left on: %+v", cfg)
+	}
+	if len(cfg.Warn.List) != 5 {
+		t.Errorf("%d warnings, want one each for -bore, -retract-z, -rpm, -aircut and -comp-mode: %v", len(cfg.Warn.List), cfg.Warn.List)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		cfg    Config
+		l      Laser
+		dpi    float64
+		format string
+	}{
+		{"heightmap", Config{Heightmap: true}, l, 254, "gcode"},
+		{"no power", Config{}, Laser{}, 254, "gcode"},
+		{"minimum above maximum", Config{}, Laser{Power: 100, MinPower: 200}, 254, "gcode"},
+		{"no dpi", Config{}, l, 0, "gcode"},
+		{"negative overscan", Config{}, Laser{Power: 1000, Overscan: -1}, 254, "gcode"},
+		{"hpgl", Config{}, l, 254, "hpgl"},
+	} {
+		if err := enableLaser(&tc.cfg, tc.l, tc.dpi, tc.format, nil); err == nil {
+			t.Errorf("%s: accepted", tc.name)
+		}
+	}
+}
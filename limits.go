@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Limits describes the machine envelope. Zero values disable a check.
+type Limits struct {
+	MaxX, MaxY float64
+	MinZ       float64
+	Soft       bool // warn instead of failing
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxX > 0 || l.MaxY > 0 || l.MinZ < 0
+}
+
+// checkLimits verifies every motion in moves against the envelope. It
+// returns an error naming the offending paths, or records a warning when
+// the limits are soft.
+func checkLimits(moves []Move, l Limits, warn *Warnings) error {
+	if !l.enabled() {
+		return nil
+	}
+	found := map[int][]string{} // path index → first violation per axis
+	seen := map[string]bool{}
+	var prev Move
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		check := func(axis string, bad bool, v, lim float64) {
+			key := fmt.Sprintf("%d/%s", m.Path, axis)
+			if !bad || seen[key] {
+				return
+			}
+			seen[key] = true
+			found[m.Path] = append(found[m.Path], fmt.Sprintf("%s%.3f beyond %.3f", axis, v, lim))
+		}
+		x, y := m.X, m.Y
+		if m.isArc() {
+			// conservative: the whole circle the arc lies on
+			c, r, _ := arcGeometry(prev, m)
+			x = math.Max(x, c.X+r)
+			y = math.Max(y, c.Y+r)
+		}
+		prev = m
+		if l.MaxX > 0 && m.Axes&AxisX != 0 {
+			check("X", x > l.MaxX, x, l.MaxX)
+		}
+		if l.MaxY > 0 && m.Axes&AxisY != 0 {
+			check("Y", y > l.MaxY, y, l.MaxY)
+		}
+		if l.MinZ < 0 && m.Axes&AxisZ != 0 {
+			check("Z", m.Z < l.MinZ, m.Z, l.MinZ)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	idx := make([]int, 0, len(found))
+	for i := range found {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	var lines []string
+	for _, i := range idx {
+		what := "job"
+		if i > 0 {
+			what = fmt.Sprintf("path %d", i)
+		}
+		msg := strings.Join(found[i], ", ")
+		if l.Soft {
+			warn.Add(WOutOfEnvelope, i, "%s", msg)
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", what, msg))
+	}
+	if l.Soft {
+		return nil
+	}
+	return fmt.Errorf("job exceeds machine limits:\n%s", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+)
+
+// Manifest records how a G-code file was made, so a job can be audited
+// or regenerated with identical settings: rerun svg2gcode with Flags on
+// an input whose hash matches InputSHA256.
+type Manifest struct {
+	Created      time.Time         `json:"created"`
+	Input        string            `json:"input"`
+	InputSHA256  string            `json:"input_sha256"`
+	Output       string            `json:"output"`
+	OutputSHA256 string            `json:"output_sha256"`
+	Flags        map[string]string `json:"flags"` // every flag, defaults included
+	Document     struct {
+		Width       float64 `json:"width"`
+		Height      float64 `json:"height"`
+		SizeGuessed bool    `json:"size_guessed,omitempty"`
+	} `json:"document"`
+	Tools    []ManifestTool   `json:"tools"`
+	Estimate ManifestEstimate `json:"estimate"`
+	Warnings []Warning        `json:"warnings"`
+}
+
+// ManifestTool is one cutter the job needs.
+type ManifestTool struct {
+	Kind     string   `json:"kind"` // "end mill", "v-bit"
+	Diameter float64  `json:"diameter_mm,omitempty"`
+	Angle    float64  `json:"angle_deg,omitempty"`
+	Ops      []string `json:"ops"`
+}
+
+type ManifestEstimate struct {
+	Paths       int     `json:"paths"`
+	CutLength   float64 `json:"cut_length_mm"`
+	RapidLength float64 `json:"rapid_length_mm"`
+	Seconds     float64 `json:"seconds"`
+}
+
+// buildManifest describes a finished job. moves is the planned body.
+func buildManifest(inPath, outPath string, outSum []byte, moves []Move, cfg Config) (*Manifest, error) {
+	in, err := os.ReadFile(inPath)
+	if err != nil {
+		return nil, err
+	}
+	inSum := sha256.Sum256(in)
+
+	m := &Manifest{
+		Created:      time.Now().UTC().Truncate(time.Second),
+		Input:        inPath,
+		InputSHA256:  hex.EncodeToString(inSum[:]),
+		Output:       outPath,
+		OutputSHA256: hex.EncodeToString(outSum),
+		Flags:        map[string]string{},
+		Warnings:     []Warning{},
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		m.Flags[f.Name] = f.Value.String()
+	})
+	m.Document.Width = cfg.SvgWidth
+	m.Document.Height = cfg.SvgHeight
+	m.Document.SizeGuessed = cfg.SizeFromExtents
+	if cfg.Warn != nil {
+		m.Warnings = append(m.Warnings, cfg.Warn.List...)
+	}
+
+	// one entry per cutter, listing the operations it runs
+	var mill, vbit []string
+	seen := map[string]bool{}
+	for _, mv := range moves {
+		if mv.Op == "" || seen[mv.Op] {
+			continue
+		}
+		seen[mv.Op] = true
+		if mv.Op == "vcarve" {
+			vbit = append(vbit, mv.Op)
+		} else {
+			mill = append(mill, mv.Op)
+		}
+	}
+	if len(mill) > 0 {
+		m.Tools = append(m.Tools, ManifestTool{Kind: "end mill", Diameter: cfg.ToolDia, Ops: mill})
+	}
+	if len(vbit) > 0 {
+		m.Tools = append(m.Tools, ManifestTool{Kind: "v-bit", Angle: cfg.VBitAngle, Ops: vbit})
+	}
+
+	est := estimateMoves(moves, cfg.RapidFeed)
+	m.Estimate = ManifestEstimate{
+		Paths:       est.Paths,
+		CutLength:   est.CutLength,
+		RapidLength: est.RapidLength,
+		Seconds:     est.Time.Seconds(),
+	}
+	return m, nil
+}
+
+// writeManifest writes m as indented JSON to path.
+func writeManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Material holds starting cutting parameters for one stock material, for
+// a typical 2-flute end mill in a hobby router spindle. Chip load and
+// step-down scale with the tool diameter; the numbers are deliberately
+// conservative and meant as a first guess, not a replacement for the tool
+// maker's chart.
+type Material struct {
+	RPM          float64
+	ChipLoadPerD float64 // chip load per tooth, mm per mm of tool diameter
+	StepDownPerD float64 // depth per pass as a multiple of the diameter
+	PlungeRatio  float64 // plunge feed as a share of the XY feed
+}
+
+var materials = map[string]Material{
+	"plywood":  {RPM: 18000, ChipLoadPerD: 0.0075, StepDownPerD: 1, PlungeRatio: 0.3},
+	"mdf":      {RPM: 18000, ChipLoadPerD: 0.009, StepDownPerD: 1, PlungeRatio: 0.3},
+	"acrylic":  {RPM: 16000, ChipLoadPerD: 0.006, StepDownPerD: 0.5, PlungeRatio: 0.25},
+	"aluminum": {RPM: 18000, ChipLoadPerD: 0.004, StepDownPerD: 0.25, PlungeRatio: 0.2},
+	"brass":    {RPM: 15000, ChipLoadPerD: 0.0035, StepDownPerD: 0.2, PlungeRatio: 0.2},
+}
+
+// lookupMaterial finds a -material name; "aluminium" is accepted too.
+func lookupMaterial(name string) (Material, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "aluminium" {
+		name = "aluminum"
+	}
+	if m, ok := materials[name]; ok {
+		return m, nil
+	}
+	names := make([]string, 0, len(materials))
+	for n := range materials {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return Material{}, fmt.Errorf("unknown -material %q (must be %s)", name, strings.Join(names, ", "))
+}
+
+// chipLoad is the chip load per tooth for a tool of diameter dia.
+func (m Material) chipLoad(dia float64) float64 {
+	return m.ChipLoadPerD * dia
+}
+
+// stepDown is the depth per pass for a tool of diameter dia.
+func (m Material) stepDown(dia float64) float64 {
+	return m.StepDownPerD * dia
+}
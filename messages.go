@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Messages is a catalog of operator-facing comment text keyed by message
+// ID. Values are fmt templates; a translation must keep the same verbs in
+// the same order. Only comment text is localized: G-code words and
+// warning codes always stay ASCII.
+type Messages map[string]string
+
+var defaultMessages = Messages{
+	"header":       "Generated by svg2gcode",
+	"units_mm":     "units in mm",
+	"units_inch":   "units in inches",
+	"absolute":     "absolute coordinates",
+	"size_assumed": "document size %.3f x %.3f assumed from geometry extents",
+	"wcs":          "work coordinate system",
+	"operation":    "=== Operation %d of %d: %s, %d paths ===",
+	"path":         "Path %d stroke=%q",
+	"progress":     "%d/%d paths, %.0f%%",
+	"resume":       "resuming at path %d; earlier paths are skipped",
+	"aircut":       "air cut %.3f mm above the stock: the whole job without touching it",
+	"aircut_done":  "air cut done: check the placement, press cycle start to cut",
+	"bounds":       "tracing the job's extent X%.3f Y%.3f to X%.3f Y%.3f at safe Z",
+	"bounds_done":  "check the stock covers the traced box, press cycle start to cut",
+	"finish":       "finish pass",
+	"spring":       "spring pass",
+	"pause":        "paused, press cycle start to continue",
+	"score":        "score %.3f mm deep",
+	"score_pct":    "score %.3f mm deep, %g%% of stock",
+	"bore":         "helical bore, diameter %.3f mm",
+	"drill":        "peck drill, diameter %.3f mm",
+	"slot":         "slot %.3f mm wide, %.3f mm long",
+	"vcarve":       "v-carve, %g degree bit",
+	"relief":       "relief from a %d x %d pixel image",
+	"raster":       "raster from a %d x %d pixel image at %.0f dpi",
+	"file":         "file %s",
+	"next_file":    "next file %s: change tool or stock, press cycle start",
+	"probe":        "Z probe with touch plate",
+	"probe_ready":  "place touch plate under the tool and attach the probe clip",
+	"probe_done":   "remove touch plate and probe clip",
+	"material":     "material %s preset for a %.3f mm tool",
+	"chipload":     "feed %.0f mm/min = %.0f rpm x %d flutes x %.3f mm chip load",
+	"spindle_on":   "spindle on",
+	"laser_on":     "laser on, power set by each move",
+	"comp_on":      "cutter compensation on",
+	"comp_off":     "cutter compensation off",
+	"spindle_off":  "spindle off, if relevant",
+	"program_end":  "program end",
+}
+
+// loadMessages reads a JSON object of message ID → template. IDs that are
+// missing fall back to English; unknown IDs are rejected so typos show up.
+func loadMessages(path string) (Messages, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Messages
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for k := range m {
+		if _, ok := defaultMessages[k]; !ok {
+			return nil, fmt.Errorf("%s: unknown message id %q", path, k)
+		}
+	}
+	return m, nil
+}
+
+// T formats message id, falling back to the built-in English text. The
+// result is safe to place inside a ( ) or ; comment.
+func (m Messages) T(id string, args ...any) string {
+	tmpl, ok := m[id]
+	if !ok {
+		tmpl = defaultMessages[id]
+	}
+	return commentSafe(fmt.Sprintf(tmpl, args...))
+}
+
+// commentSafe strips characters that would end or nest a G-code comment.
+func commentSafe(s string) string {
+	return strings.NewReplacer("(", "[", ")", "]", "\n", " ", "\r", " ").Replace(s)
+}
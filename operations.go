@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// With -operations a job is cut as a sequence of operations, in an
+// order that keeps the stock held down for as long as possible: shallow
+// engraving first, while the sheet is whole, then holes and pockets, and
+// the profiles that cut parts free last.
+var operationOrder = []string{"engrave", "drill", "pocket", "profile"}
+
+// layerOperations maps the first word of a layer name to the operation
+// its paths belong to.
+var layerOperations = map[string]string{
+	"engrave": "engrave", "engraving": "engrave", "score": "engrave",
+	"drill": "drill", "drilling": "drill", "holes": "drill",
+	"pocket": "pocket", "pockets": "pocket", "pocketing": "pocket",
+	"profile": "profile", "profiles": "profile", "cutout": "profile", "outline": "profile",
+}
+
+// parseOperation validates an operation name.
+func parseOperation(s string) (string, error) {
+	op := strings.ToLower(strings.TrimSpace(s))
+	if !slices.Contains(operationOrder, op) {
+		return "", fmt.Errorf("unknown operation %q (must be %s)", s, strings.Join(operationOrder, ", "))
+	}
+	return op, nil
+}
+
+// layerOperation returns the operation a layer name asks for, or "".
+func layerOperation(layer string) string {
+	word, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(layer)), " ")
+	return layerOperations[word]
+}
+
+// operationOf decides which operation a prepared path belongs to: the
+// color rule's operation if it has one, then its layer's, and otherwise
+// what it is: holes are drilled, slots and fills are pockets, v-carves,
+// rasters and scores are engraved and everything else is a profile.
+func operationOf(p Path, cm ColorMap) string {
+	if op := cm.rule(p.Stroke).Operation; op != "" {
+		return op
+	}
+	if op := layerOperation(p.Layer); op != "" {
+		return op
+	}
+	switch {
+	case p.Hole != nil:
+		return "drill"
+	case p.Slot != nil:
+		return "pocket"
+	case p.Filled:
+		return "pocket"
+	case p.Depths != nil, p.Power != nil, cm.rule(p.Stroke).Op == "score":
+		return "engrave"
+	}
+	return "profile"
+}
+
+// operationPaths tags every path with its operation and regroups the job
+// by operation. The sort is stable, so the cutting order within each
+// operation, and a finish pass right behind its roughing pass, is kept.
+func operationPaths(paths []Path, cm ColorMap) []Path {
+	for i := range paths {
+		paths[i].Operation = operationOf(paths[i], cm)
+	}
+	slices.SortStableFunc(paths, func(a, b Path) int {
+		return slices.Index(operationOrder, a.Operation) - slices.Index(operationOrder, b.Operation)
+	})
+	return paths
+}
+
+// usesOperations reports whether any rule assigns an operation.
+func (cm ColorMap) usesOperations() bool {
+	for _, r := range cm {
+		if r.Operation != "" {
+			return true
+		}
+	}
+	return false
+}
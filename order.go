@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Orderer decides the sequence paths are cut in. Order receives the
+// placed machine-space paths before compensation, plus the position the
+// tool starts from, and returns the same paths in cutting order. It must
+// not drop or add paths.
+//
+// Shop-specific sequencing (say, spreading plasma cuts out to limit heat
+// build-up) is a new Orderer registered in orderers.
+type Orderer interface {
+	Order(paths []Path, start Point) []Path
+}
+
+// orderers maps -order names to strategies.
+var orderers = map[string]Orderer{
+	"document":     documentOrder{},
+	"nearest":      nearestOrder{},
+	"inside-first": insideFirstOrder{},
+	"per-part":     perPartOrder{},
+}
+
+// parseOrder looks up a -order name.
+func parseOrder(name string) (Orderer, error) {
+	if o, ok := orderers[strings.ToLower(name)]; ok {
+		return o, nil
+	}
+	names := make([]string, 0, len(orderers))
+	for n := range orderers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("invalid -order %q (must be %s)", name, strings.Join(names, ", "))
+}
+
+// documentOrder cuts paths in the order they appear in the SVG.
+type documentOrder struct{}
+
+func (documentOrder) Order(paths []Path, _ Point) []Path { return paths }
+
+// nearestOrder greedily picks the path that starts closest to where the
+// previous one ended, which shortens rapids on scattered artwork. Open
+// paths may be cut from either end unless KeepDirection is set, for
+// direction-sensitive tools such as drag knives.
+type nearestOrder struct {
+	KeepDirection bool
+}
+
+func (n nearestOrder) Order(paths []Path, start Point) []Path {
+	out := make([]Path, 0, len(paths))
+	used := make([]bool, len(paths))
+	at := start
+	for range paths {
+		best, bestD, flip := -1, math.Inf(1), false
+		for i, p := range paths {
+			if used[i] || len(p.Points) == 0 {
+				continue
+			}
+			if d := math.Hypot(p.Points[0].X-at.X, p.Points[0].Y-at.Y); d < bestD {
+				best, bestD, flip = i, d, false
+			}
+			if n.reversible(p) {
+				end := p.Points[len(p.Points)-1]
+				if d := math.Hypot(end.X-at.X, end.Y-at.Y); d < bestD {
+					best, bestD, flip = i, d, true
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		p := paths[best]
+		if flip {
+			p.Points = reversePoints(p.Points)
+			if p.Depths != nil {
+				p.Depths = slices.Clone(p.Depths)
+				slices.Reverse(p.Depths) // stay paired with the points
+			}
+			p.CompSide = otherSide(p.CompSide) // same wall, opposite travel
+			p.Comp = otherSide(p.Comp)
+		}
+		out = append(out, p)
+		at = p.Points[len(p.Points)-1]
+	}
+	for i, p := range paths {
+		if !used[i] {
+			out = append(out, p) // empty paths keep their place at the end
+		}
+	}
+	return out
+}
+
+// insideFirstOrder cuts the most deeply nested paths first, so holes and
+// details are done while the part around them is still held by the stock.
+// Paths at the same depth keep document order.
+type insideFirstOrder struct{}
+
+func (insideFirstOrder) Order(paths []Path, _ Point) []Path {
+	depth, _ := nesting(paths)
+	idx := order(len(paths))
+	sort.SliceStable(idx, func(a, b int) bool { return depth[idx[a]] > depth[idx[b]] })
+	return pick(paths, idx)
+}
+
+// perPartOrder finishes one part before starting the next: everything
+// inside an outermost closed path, innermost first, then the outline
+// itself. Parts follow the document order of their outlines.
+type perPartOrder struct{}
+
+func (perPartOrder) Order(paths []Path, _ Point) []Path {
+	depth, owner := nesting(paths)
+	rank := make([]int, len(paths)) // part sequence, by first appearance
+	seen := map[int]int{}
+	for i := range paths {
+		r, ok := seen[owner[i]]
+		if !ok {
+			r = len(seen)
+			seen[owner[i]] = r
+		}
+		rank[i] = r
+	}
+	idx := order(len(paths))
+	sort.SliceStable(idx, func(a, b int) bool {
+		i, j := idx[a], idx[b]
+		if rank[i] != rank[j] {
+			return rank[i] < rank[j]
+		}
+		return depth[i] > depth[j]
+	})
+	return pick(paths, idx)
+}
+
+// nesting reports, for every path, how many closed paths enclose it and
+// which outermost closed path does (the path itself if none does).
+func nesting(paths []Path) (depth, owner []int) {
+	depth = make([]int, len(paths))
+	owner = make([]int, len(paths))
+	bounds := make([]Rect, len(paths))
+	for i, p := range paths {
+		bounds[i], _ = pathBounds([]Path{p})
+	}
+	for i, p := range paths {
+		owner[i] = i
+		if len(p.Points) == 0 {
+			continue
+		}
+		outer := -1
+		for j, q := range paths {
+			if i == j || !q.Closed || !bounds[j].contains(bounds[i]) ||
+				!pointInPolygon(p.Points[0], q.Points) {
+				continue
+			}
+			if j > i && bounds[i].contains(bounds[j]) {
+				continue // duplicates: only the earlier one encloses
+			}
+			depth[i]++
+			if outer < 0 || bounds[j].contains(bounds[outer]) {
+				outer = j
+			}
+		}
+		if outer >= 0 {
+			owner[i] = outer
+		}
+	}
+	return depth, owner
+}
+
+// reversible reports whether p may be cut end to start. Closed paths get
+// their direction from -direction, and a dash pattern is laid out from the
+// drawn start, and a raster's powers belong to its moves as scanned, so
+// only plain open paths qualify.
+func (n nearestOrder) reversible(p Path) bool {
+	return !n.KeepDirection && !p.Closed && p.Dash == nil && p.Power == nil
+}
+
+func order(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+func pick(paths []Path, idx []int) []Path {
+	out := make([]Path, len(idx))
+	for k, i := range idx {
+		out[k] = paths[i]
+	}
+	return out
+}
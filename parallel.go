@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Traced artwork can hold tens of thousands of paths, and flattening and
+// offsetting them one at a time leaves most of the machine idle. The
+// per-path work is spread over a pool of goroutines; results are kept in
+// input order, so the G-code is the same however the work was scheduled.
+
+// parallelMap calls fn on every item using up to GOMAXPROCS goroutines
+// and returns the results in the order of items. fn must not touch
+// shared state.
+func parallelMap[T, R any](items []T, fn func(T) R) []R {
+	out := make([]R, len(items))
+	workers := min(runtime.GOMAXPROCS(0), len(items))
+	if workers <= 1 {
+		for i, it := range items {
+			out[i] = fn(it)
+		}
+		return out
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(items) {
+					return
+				}
+				out[i] = fn(items[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// flatPath is a path's d flattened into subpaths in its own coordinates.
+type flatPath struct {
+	Subs []subpath
+	Err  error
+}
+
+// flatPaths holds every <path> of a document flattened ahead of the
+// parse, keyed by d, so the parse itself only transforms.
+type flatPaths map[string]flatPath
+
+// flattenAll flattens the d of every <path> in an SVG in parallel.
+// Paths with commands the parser skips are left out; they are reported
+// when the parse reaches them.
+func flattenAll(data []byte) flatPaths {
+	seen := map[string]bool{}
+	var ds []string
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break // a broken document is reported by the real parse
+		}
+		t, ok := tok.(xml.StartElement)
+		if !ok || t.Name.Local != "path" {
+			continue
+		}
+		for _, a := range t.Attr {
+			if a.Name.Local != "d" || a.Name.Space != "" {
+				continue
+			}
+			d := strings.TrimSpace(a.Value)
+			if d != "" && !seen[d] && !hasUnsupportedCommands(d) {
+				seen[d] = true
+				ds = append(ds, d)
+			}
+		}
+	}
+	flat := parallelMap(ds, func(d string) flatPath {
+		subs, err := parseSimplePath(d)
+		return flatPath{subs, err}
+	})
+	out := make(flatPaths, len(ds))
+	for i, d := range ds {
+		out[d] = flat[i]
+	}
+	return out
+}
+
+// parse returns d flattened, from the table when it's there. The points
+// are the caller's own to transform: a d drawn more than once, by <use>,
+// is copied each time.
+func (f flatPaths) parse(d string) ([]subpath, error) {
+	fp, ok := f[d]
+	if !ok {
+		return parseSimplePath(d)
+	}
+	if fp.Err != nil {
+		return nil, fp.Err
+	}
+	subs := make([]subpath, len(fp.Subs))
+	for i, sp := range fp.Subs {
+		subs[i] = subpath{Points: append([]Point(nil), sp.Points...), Closed: sp.Closed}
+	}
+	return subs, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The seeds are the inputs that found the Bézier and <use> bounds, and
+// the number forms the tokenizer has to split: exponents, compact numbers
+// and arc flags run together. Run longer with, e.g.,
+//
+//	go test -fuzz FuzzParseSimplePath -fuzztime 1m
+
+func FuzzParseSimplePath(f *testing.F) {
+	for _, seed := range []string{
+		"M0 0 L10 0 L10 10 Z",
+		"m1,1 h5 v5 h-5 z m10 0 l5 5",
+		"M0 0 C1e99 1e99 -1e99 -1e99 1 1",
+		"M0 0 C1e99 0 0 1e99 1e99 1e99 C-1e99 1e99 1e99 -1e99 0 0",
+		"M0 0 C100 0 -50 0 10 0",
+		"M1e2 1E-2 L-1.5e+1 .5e1",
+		"M.5.5.5.5L-.5-.5",
+		"M0 0 A10 10 0 1 1 20 20",
+		"M0 0 a5 5 0 0120 0 a5,5,0,1,0,-20,0",
+		"M0 0 C1 1",
+		"Z L1 2",
+		"M0 0 L1 NaN",
+		"M0 0 L1 Inf C0 0 +Inf -Inf 1 1",
+		"\x00M\xff0 0",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, d string) {
+		subs, err := parseSimplePath(d)
+		if err != nil {
+			return
+		}
+		// no command adds more points than a curve flattened to the
+		// subdivision limit, and every command takes at least a byte
+		n := 0
+		for _, s := range subs {
+			if len(s.Points) < 2 {
+				t.Fatalf("subpath of %d points", len(s.Points))
+			}
+			n += len(s.Points)
+		}
+		if limit := (1<<maxBezierDepth + 1) * (len(d) + 1); n > limit {
+			t.Fatalf("%d bytes of path data made %d points", len(d), n)
+		}
+	})
+}
+
+func FuzzParsePointsList(f *testing.F) {
+	for _, seed := range []string{
+		"0,0 10,0 10,10",
+		"1e2 1E-2, -1.5e+1 .5e1",
+		"1e99,-1e99 1e308,1e308",
+		" 1 , 2 ",
+		"1 2 3",
+		"1,,2",
+		"NaN Inf",
+		"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		pts, err := parsePointsList(s)
+		if err != nil {
+			return
+		}
+		// what was parsed reads back the same once written out
+		var b strings.Builder
+		for _, p := range pts {
+			b.WriteString(strconv.FormatFloat(p.X, 'g', -1, 64) + "," + strconv.FormatFloat(p.Y, 'g', -1, 64) + " ")
+		}
+		again, err := parsePointsList(b.String())
+		if err != nil {
+			t.Fatalf("%q: written back as %q, which fails: %v", s, b.String(), err)
+		}
+		if len(again) != len(pts) {
+			t.Fatalf("%q: %d points, %d after writing back", s, len(pts), len(again))
+		}
+		for i := range pts {
+			if !samePoint(pts[i], again[i]) {
+				t.Fatalf("%q: point %d is %v, %v after writing back", s, i, pts[i], again[i])
+			}
+		}
+	})
+}
+
+func FuzzParseTransformAttr(f *testing.F) {
+	for _, seed := range []string{
+		"translate(10,20) rotate(45) scale(2)",
+		"matrix(1 0 0 1 1e2 -1E-2)",
+		"rotate(30 5 5) skewX(10) skewY(-10)",
+		"scale(1e99) translate(1e99)",
+		"scale(.5.5)",
+		"translate(1 2",
+		")(",
+		"rotate()",
+		"foo(1,2) , scale(2)",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		m := parseTransformAttr(s)
+		// without parentheses there is no function to apply
+		if strings.ContainsAny(s, "()") {
+			return
+		}
+		if m != identityTransform() {
+			t.Fatalf("%q has no function but parsed to %+v", s, m)
+		}
+	})
+}
+
+func FuzzParseSVG(f *testing.F) {
+	// each group draws the one before it twice: 2^20 copies of a line
+	var doubling strings.Builder
+	doubling.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><path id="g0" d="M0 0 L1 1"/>`)
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&doubling, `<g id="g%d"><use href="#g%d"/><use xlink:href="#g%d" x="1"/></g>`, i, i-1, i-1)
+	}
+	doubling.WriteString(`</defs><use href="#g20"/></svg>`)
+
+	for _, seed := range []string{
+		doubling.String(),
+		`<svg xmlns="http://www.w3.org/2000/svg"><path d="M0 0 C1e99 1e99 -1e99 -1e99 1 1"/></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><g id="a"><use href="#a"/></g></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><g transform="scale(2)"><rect width="1" height="1"/><circle r="1"/></g></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><polygon points="0,0 1,0 1"/><svg x="1" viewBox="0 0 0 0"><line x2="1"/></svg></svg>`,
+		`<svg><style>.a{stroke:red}</style><path class="a" d="M0 0L1 1"/></svg>`,
+		`<svg`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, doc string) {
+		paths, _, _, err := parseSVG(strings.NewReader(doc), nil, &Warnings{Out: io.Discard})
+		if err != nil {
+			return
+		}
+		// each element is drawn once, and again for each <use> instance
+		if limit := (maxUses + 1) * (strings.Count(doc, "<") + 1); len(paths) > limit {
+			t.Fatalf("%d bytes of SVG made %d paths", len(doc), len(paths))
+		}
+	})
+}
+
+// samePoint compares coordinates exactly, with NaN equal to itself.
+func samePoint(a, b Point) bool {
+	same := func(x, y float64) bool { return x == y || math.IsNaN(x) && math.IsNaN(y) }
+	return same(a.X, b.X) && same(a.Y, b.Y)
+}
@@ -1,37 +1,213 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-func parseSVG(r io.Reader) (paths []Path, w, h float64, err error) {
-	dec := xml.NewDecoder(r)
+// parseSVG reads paths, polylines and polygons from r, applying group and
+// element transforms. Skipped elements are reported to warn.
+func parseSVG(r io.Reader, fonts *FontSet, warn *Warnings) (paths []Path, w, h float64, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sheet := readStyleSheet(data) // <style> may come after what it styles
+	ids := indexIDs(data)         // <use> may refer forward too
+	flat := flattenAll(data)
+	root := xml.NewDecoder(bytes.NewReader(data))
+	mux := &tokenMux{srcs: []xml.TokenReader{root}}
+	dec := xml.NewTokenDecoder(mux)
 	var result []Path
 
 	colorStack := []string{""}
+	widthStack := []string{""} // stroke-width as given, in the element's own units
+	layerStack := []string{""} // innermost Inkscape layer, see Path.Layer
+	depthStack := []*DepthOverride{nil}
+	fillStack := []svgFill{{Rule: "nonzero"}}
+	shape := 0 // counts drawing elements, see Path.Shape
 	transformStack := []Transform{identityTransform()}
+	var viewports []viewport // the root <svg>'s and each nested one's
+	var using []string       // ids of the <use>s being read, innermost last
+	uses := 0                // <use> instances drawn so far, see maxUses
+	line := 0                // source line of the last tag read from the document itself
+
+	// Clip regions may be defined after their first use, so clipping
+	// happens once the whole document is read. refs[i] lists the regions
+	// result[i] is clipped by, its own and its groups'.
+	clipStack := [][]clipRef{nil}
+	clips := map[string][][]Point{}
+	var refs [][]clipRef
+	markClips := func(own []clipRef) {
+		all := append(append([]clipRef(nil), clipStack[len(clipStack)-1]...), own...)
+		for len(refs) < len(result) {
+			refs = append(refs, all)
+		}
+	}
+	readClip := func(t xml.StartElement) error {
+		var id string
+		for _, a := range t.Attr {
+			if a.Name.Local == "id" {
+				id = a.Value
+			}
+		}
+		shapes, err := readClipShapes(dec, t, warn)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			clips[id] = shapes
+		}
+		return nil
+	}
+
+	// pushGroup starts a <g>, or a nested <svg> whose viewport maps into
+	// its parent by vt: what it sets is inherited until popGroup.
+	pushGroup := func(t xml.StartElement, vt Transform) {
+		// stroke / style on group
+		var strokeAttr, widthAttr, styleAttr, transformAttr, clipAttr, maskAttr, fillAttr, ruleAttr string
+		for _, a := range t.Attr {
+			switch a.Name.Local {
+			case "fill":
+				fillAttr = a.Value
+			case "fill-rule":
+				ruleAttr = a.Value
+			case "clip-path":
+				clipAttr = a.Value
+			case "mask":
+				maskAttr = a.Value
+			case "stroke":
+				strokeAttr = a.Value
+			case "stroke-width":
+				widthAttr = a.Value
+			case "style":
+				styleAttr = a.Value
+			case "transform":
+				transformAttr = a.Value
+			}
+		}
+		layer := layerStack[len(layerStack)-1]
+		if name, ok := layerName(t.Attr); ok {
+			layer = name
+		}
+		layerStack = append(layerStack, layer)
+		depthStack = append(depthStack, elementDepth(t.Attr, depthStack[len(depthStack)-1], warn))
+		groupColor := extractStrokeColor(strokeAttr, styleAttr)
+		if groupColor == "" {
+			groupColor = colorStack[len(colorStack)-1]
+		}
+		colorStack = append(colorStack, groupColor)
+		widthStack = append(widthStack, cmp.Or(styleProp(styleAttr, "stroke-width"), widthAttr, widthStack[len(widthStack)-1]))
+		fillStack = append(fillStack, inheritFill(fillStack[len(fillStack)-1], fillAttr, ruleAttr, styleAttr))
+
+		parentT := transformStack[len(transformStack)-1].Mul(vt)
+		groupT := parseTransformAttr(transformAttr)
+		transformStack = append(transformStack, parentT.Mul(groupT))
+		groupClips := append([]clipRef(nil), clipStack[len(clipStack)-1]...)
+		groupClips = append(groupClips, parseClipRefs(parentT.Mul(groupT), clipAttr, maskAttr)...)
+		clipStack = append(clipStack, groupClips)
+	}
+	popGroup := func() {
+		if len(colorStack) > 1 {
+			colorStack = colorStack[:len(colorStack)-1]
+		}
+		if len(widthStack) > 1 {
+			widthStack = widthStack[:len(widthStack)-1]
+		}
+		if len(layerStack) > 1 {
+			layerStack = layerStack[:len(layerStack)-1]
+		}
+		if len(depthStack) > 1 {
+			depthStack = depthStack[:len(depthStack)-1]
+		}
+		if len(fillStack) > 1 {
+			fillStack = fillStack[:len(fillStack)-1]
+		}
+		if len(transformStack) > 1 {
+			transformStack = transformStack[:len(transformStack)-1]
+		}
+		if len(clipStack) > 1 {
+			clipStack = clipStack[:len(clipStack)-1]
+		}
+	}
+
+	// skipDefs passes over <defs> and a <symbol> not drawn by a <use>:
+	// their shapes are drawn only where something refers to them, but the
+	// clip paths and masks inside are read.
+	skipDefs := func(start xml.StartElement) error {
+		for depth := 1; depth > 0; {
+			tok, err := dec.Token()
+			if err != nil {
+				return fmt.Errorf("decode <%s>: %w", start.Name.Local, err)
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "clipPath" || t.Name.Local == "mask" {
+					if err := readClip(t); err != nil {
+						return err
+					}
+					continue
+				}
+				depth++
+			case xml.EndElement:
+				depth--
+			}
+		}
+		return nil
+	}
 
 	for {
 		tok, err := dec.Token()
 		if err == io.EOF {
 			break
 		}
+		if len(mux.srcs) == 1 {
+			line, _ = root.InputPos()
+		}
 		if err != nil {
 			return nil, w, h, fmt.Errorf("decode token: %w", err)
 		}
 
 		switch t := tok.(type) {
 		case xml.StartElement:
+			if foreignElement(t.Name) || unrendered[t.Name.Local] {
+				// editor metadata, embedded HTML and the like
+				if err := dec.Skip(); err != nil {
+					return nil, w, h, fmt.Errorf("decode <%s>: %w", t.Name.Local, err)
+				}
+				continue
+			}
+			t = sheet.apply(svgAttrs(t))
+			if drawable[t.Name.Local] && isHidden(t.Attr) {
+				// hidden layers in Inkscape are groups with display:none
+				if err := dec.Skip(); err != nil {
+					return nil, w, h, fmt.Errorf("decode <%s>: %w", t.Name.Local, err)
+				}
+				continue
+			}
 			switch t.Name.Local {
 			case "svg":
-				var vb string
+				if len(viewports) > 0 {
+					vt, vp := viewportTransform(t.Attr, viewports[len(viewports)-1])
+					viewports = append(viewports, vp)
+					pushGroup(t, vt)
+					continue
+				}
+				var vb, width, height string
 				for _, a := range t.Attr {
-					if a.Name.Local == "viewBox" {
+					switch a.Name.Local {
+					case "viewBox":
 						vb = a.Value
+					case "width":
+						width = a.Value
+					case "height":
+						height = a.Value
 					}
 				}
 				if vb != "" {
@@ -42,54 +218,70 @@ func parseSVG(r io.Reader) (paths []Path, w, h float64, err error) {
 						h, _ = strconv.ParseFloat(parts[3], 64)
 					}
 				}
+				vp := viewport{w, h}
+				if vb == "" {
+					vp = viewport{parseLength(width, 0), parseLength(height, 0)}
+				}
+				viewports = append(viewports, vp)
 			case "g":
-				// stroke / style on group
-				var strokeAttr, styleAttr, transformAttr string
-				for _, a := range t.Attr {
-					switch a.Name.Local {
-					case "stroke":
-						strokeAttr = a.Value
-					case "style":
-						styleAttr = a.Value
-					case "transform":
-						transformAttr = a.Value
+				pushGroup(t, identityTransform())
+
+			case "use":
+				using = using[:len(mux.srcs)-1] // drop the <use>s read through
+				if err := dec.Skip(); err != nil {
+					return nil, w, h, fmt.Errorf("decode <use>: %w", err)
+				}
+				id := useRef(t.Attr)
+				span, ok := ids[id]
+				switch {
+				case !ok:
+					warn.Add(WUnknownReference, 0, "<use> refers to unknown id %q; skipped", id)
+				case slices.Contains(using, id):
+					warn.Add(WUnknownReference, 0, "<use> of %q refers back to itself; skipped", id)
+				case len(using) >= maxUseDepth:
+					warn.Add(WUnknownReference, 0, "<use> of %q nested more than %d deep; skipped", id, maxUseDepth)
+				case uses >= maxUses:
+					if uses == maxUses {
+						warn.Add(WUnknownReference, 0, "more than %d <use> instances; the rest skipped", maxUses)
 					}
+					uses++
+				default:
+					uses++
+					using = append(using, id)
+					mux.push(useTokens(t, data[span[0]:span[1]]))
 				}
-				groupColor := extractStrokeColor(strokeAttr, styleAttr)
-				if groupColor == "" {
-					groupColor = colorStack[len(colorStack)-1]
+
+			case "defs", "symbol":
+				if err := skipDefs(t); err != nil {
+					return nil, w, h, err
 				}
-				colorStack = append(colorStack, groupColor)
 
-				parentT := transformStack[len(transformStack)-1]
-				groupT := parseTransformAttr(transformAttr)
-				transformStack = append(transformStack, parentT.Mul(groupT))
+			case "clipPath", "mask":
+				if err := readClip(t); err != nil {
+					return nil, w, h, err
+				}
 
 			case "path":
 				currentGroupColor := colorStack[len(colorStack)-1]
 				currentT := transformStack[len(transformStack)-1]
+				depth := elementDepth(t.Attr, depthStack[len(depthStack)-1], warn)
 
 				var raw svgPath
 				if err := dec.DecodeElement(&raw, &t); err != nil {
 					return nil, w, h, fmt.Errorf("decode <path>: %w", err)
 				}
+				currentT = currentT.Mul(parseTransformAttr(raw.Transform))
 				d := strings.TrimSpace(raw.D)
 				if d == "" {
 					continue
 				}
 				if hasUnsupportedCommands(d) {
+					warn.Add(WUnsupportedCommand, 0, "<path d=%q> uses an unsupported command; skipped", truncate(d, 40))
 					continue
 				}
-				pts, closed, err := parseSimplePath(d)
+				subs, err := flat.parse(d)
 				if err != nil {
-					return nil, w, h, fmt.Errorf("parse path d=%q: %w", truncate(d, 40), err)
-				}
-				if len(pts) == 0 {
-					continue
-				}
-				// apply current transform
-				for i := range pts {
-					pts[i] = currentT.Apply(pts[i])
+					return nil, w, h, newElementError(ErrMalformedPath, t, line, truncate(d, 40), err)
 				}
 
 				strokeCol := extractStrokeColor(raw.Stroke, raw.Style)
@@ -97,15 +289,39 @@ func parseSVG(r io.Reader) (paths []Path, w, h float64, err error) {
 					strokeCol = currentGroupColor
 				}
 
-				result = append(result, Path{
-					Points: pts,
-					Closed: closed,
-					Stroke: strokeCol,
-				})
+				dash, dashOff := parseDash(raw.DashArray, raw.DashOffset, raw.Style, currentT)
+				width := strokeWidth(cmp.Or(styleProp(raw.Style, "stroke-width"), raw.Width, widthStack[len(widthStack)-1]), currentT)
+				fill := inheritFill(fillStack[len(fillStack)-1], raw.Fill, raw.FillRule, raw.Style)
+				shape++
+				for _, sp := range subs {
+					pts := sp.Points
+					// apply current transform
+					for i := range pts {
+						pts[i] = currentT.Apply(pts[i])
+					}
+					pts = dedupePoints(pts)
+					result = append(result, Path{
+						Points:     pts,
+						Closed:     sp.Closed,
+						Stroke:     strokeCol,
+						Transform:  currentT,
+						Index:      len(result) + 1,
+						Shape:      shape,
+						Layer:      layerStack[len(layerStack)-1],
+						Override:   depth,
+						Dash:       dash,
+						DashOffset: dashOff,
+						Width:      width,
+						Fill:       fill.paint(),
+						FillRule:   fill.Rule,
+					})
+				}
+				markClips(parseClipRefs(currentT, raw.ClipPath, raw.Mask))
 
 			case "polyline":
 				currentGroupColor := colorStack[len(colorStack)-1]
 				currentT := transformStack[len(transformStack)-1]
+				depth := elementDepth(t.Attr, depthStack[len(depthStack)-1], warn)
 
 				var raw svgPolyLine
 				if err := dec.DecodeElement(&raw, &t); err != nil {
@@ -113,28 +329,46 @@ func parseSVG(r io.Reader) (paths []Path, w, h float64, err error) {
 				}
 				pts, err := parsePointsList(raw.Points)
 				if err != nil {
-					return nil, w, h, fmt.Errorf("parse polyline points: %w", err)
+					return nil, w, h, newElementError(ErrMalformedPath, t, line, truncate(raw.Points, 40), err)
 				}
+				currentT = currentT.Mul(parseTransformAttr(raw.Transform))
 				if len(pts) == 0 {
 					continue
 				}
 				for i := range pts {
 					pts[i] = currentT.Apply(pts[i])
 				}
+				pts = dedupePoints(pts)
 				strokeCol := extractStrokeColor(raw.Stroke, raw.Style)
 				if strokeCol == "" {
 					strokeCol = currentGroupColor
 				}
 
+				fill := inheritFill(fillStack[len(fillStack)-1], raw.Fill, raw.FillRule, raw.Style)
+				dash, dashOff := parseDash(raw.DashArray, raw.DashOffset, raw.Style, currentT)
+				width := strokeWidth(cmp.Or(styleProp(raw.Style, "stroke-width"), raw.Width, widthStack[len(widthStack)-1]), currentT)
+				shape++
 				result = append(result, Path{
-					Points: pts,
-					Closed: false,
-					Stroke: strokeCol,
+					Points:     pts,
+					Closed:     false,
+					Stroke:     strokeCol,
+					Transform:  currentT,
+					Index:      len(result) + 1,
+					Shape:      shape,
+					Layer:      layerStack[len(layerStack)-1],
+					Override:   depth,
+					Dash:       dash,
+					DashOffset: dashOff,
+					Width:      width,
+					Fill:       fill.paint(),
+					FillRule:   fill.Rule,
 				})
+				markClips(parseClipRefs(currentT, raw.ClipPath, raw.Mask))
 
 			case "polygon":
 				currentGroupColor := colorStack[len(colorStack)-1]
 				currentT := transformStack[len(transformStack)-1]
+				depth := elementDepth(t.Attr, depthStack[len(depthStack)-1], warn)
 
 				var raw svgPolyLine
 				if err := dec.DecodeElement(&raw, &t); err != nil {
@@ -142,14 +376,16 @@ func parseSVG(r io.Reader) (paths []Path, w, h float64, err error) {
 				}
 				pts, err := parsePointsList(raw.Points)
 				if err != nil {
-					return nil, w, h, fmt.Errorf("parse polygon points: %w", err)
+					return nil, w, h, newElementError(ErrMalformedPath, t, line, truncate(raw.Points, 40), err)
 				}
+				currentT = currentT.Mul(parseTransformAttr(raw.Transform))
 				if len(pts) == 0 {
 					continue
 				}
 				for i := range pts {
 					pts[i] = currentT.Apply(pts[i])
 				}
+				pts = dedupePoints(pts)
 				if !almostEqualPoint(pts[0], pts[len(pts)-1]) {
 					pts = append(pts, pts[0])
 				}
@@ -158,24 +394,310 @@ func parseSVG(r io.Reader) (paths []Path, w, h float64, err error) {
 					strokeCol = currentGroupColor
 				}
 
+				fill := inheritFill(fillStack[len(fillStack)-1], raw.Fill, raw.FillRule, raw.Style)
+				dash, dashOff := parseDash(raw.DashArray, raw.DashOffset, raw.Style, currentT)
+				width := strokeWidth(cmp.Or(styleProp(raw.Style, "stroke-width"), raw.Width, widthStack[len(widthStack)-1]), currentT)
+				shape++
+				result = append(result, Path{
+					Points:     pts,
+					Closed:     true,
+					Stroke:     strokeCol,
+					Transform:  currentT,
+					Index:      len(result) + 1,
+					Shape:      shape,
+					Layer:      layerStack[len(layerStack)-1],
+					Override:   depth,
+					Dash:       dash,
+					DashOffset: dashOff,
+					Width:      width,
+					Fill:       fill.paint(),
+					FillRule:   fill.Rule,
+				})
+				markClips(parseClipRefs(currentT, raw.ClipPath, raw.Mask))
+
+			case "text":
+				currentGroupColor := colorStack[len(colorStack)-1]
+				currentT := transformStack[len(transformStack)-1]
+				depth := elementDepth(t.Attr, depthStack[len(depthStack)-1], warn)
+
+				var raw svgText
+				if err := dec.DecodeElement(&raw, &t); err != nil {
+					return nil, w, h, fmt.Errorf("decode <text>: %w", err)
+				}
+				content := raw.Content
+				for _, sp := range raw.Spans {
+					content += " " + sp.Content
+				}
+				content = strings.Join(strings.Fields(content), " ")
+				if content == "" {
+					continue
+				}
+				family := cmp.Or(styleProp(raw.Style, "font-family"), raw.FontFamily)
+				f := fonts.lookup(family)
+				stroke := fonts != nil && fonts.Stroke != nil
+				if f == nil && !stroke {
+					warn.Add(WNoFont, 0, "<text> %q skipped: no font for %q (use -font or -font-dir)",
+						truncate(content, 30), family)
+					continue
+				}
+				size := parseFontSize(cmp.Or(styleProp(raw.Style, "font-size"), raw.FontSize))
+				if size == 0 {
+					size = 16 // CSS default
+				}
+				anchor := cmp.Or(styleProp(raw.Style, "text-anchor"), raw.TextAnchor)
+				x, y := firstCoord(raw.X), firstCoord(raw.Y)
+				var outlines [][]Point
+				if stroke {
+					outlines = fonts.Stroke.textStrokes(content, x, y, size, anchor)
+				} else if outlines, err = textOutlines(f, content, x, y, size, anchor); err != nil {
+					return nil, w, h, fmt.Errorf("<text> %q: %w", truncate(content, 30), err)
+				}
+				currentT = currentT.Mul(parseTransformAttr(raw.Transform))
+				strokeCol := extractStrokeColor(raw.Stroke, raw.Style)
+				if strokeCol == "" {
+					strokeCol = currentGroupColor
+				}
+				fill := inheritFill(fillStack[len(fillStack)-1], raw.Fill, raw.FillRule, raw.Style)
+				shape++
+				for i, pts := range outlines {
+					for j := range pts {
+						pts[j] = currentT.Apply(pts[j])
+					}
+					p := Path{
+						Points:    pts,
+						Closed:    !stroke,
+						Stroke:    strokeCol,
+						Transform: currentT,
+						Index:     len(result) + 1,
+						Shape:     shape,
+						Layer:     layerStack[len(layerStack)-1],
+						Override:  depth,
+					}
+					if !stroke {
+						p.Fill, p.FillRule = fill.paint(), fill.Rule
+					}
+					if i == 0 {
+						p.Label = "text " + strconv.Quote(content)
+					}
+					result = append(result, p)
+				}
+				markClips(parseClipRefs(currentT, raw.ClipPath, raw.Mask))
+
+			case "image":
+				currentT := transformStack[len(transformStack)-1]
+				depth := elementDepth(t.Attr, depthStack[len(depthStack)-1], warn)
+				if err := dec.Skip(); err != nil {
+					return nil, w, h, fmt.Errorf("decode <image>: %w", err)
+				}
+				var x, y, width, height, href, aspect, transformAttr string
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "x":
+						x = a.Value
+					case "y":
+						y = a.Value
+					case "width":
+						width = a.Value
+					case "height":
+						height = a.Value
+					case "href":
+						href = strings.TrimSpace(a.Value)
+					case "preserveAspectRatio":
+						aspect = a.Value
+					case "transform":
+						transformAttr = a.Value
+					}
+				}
+				var vp viewport
+				if len(viewports) > 0 {
+					vp = viewports[len(viewports)-1]
+				}
+				bx, by := parseLength(x, vp.W), parseLength(y, vp.H)
+				bw, bh := parseLength(width, vp.W), parseLength(height, vp.H)
+				if href == "" || bw <= 0 || bh <= 0 {
+					warn.Add(WIgnoredOption, 0, "<image> needs href, width and height; skipped")
+					continue
+				}
+				currentT = currentT.Mul(parseTransformAttr(transformAttr))
+				pts := []Point{{X: bx, Y: by}, {X: bx + bw, Y: by}, {X: bx + bw, Y: by + bh}, {X: bx, Y: by + bh}}
+				for i := range pts {
+					pts[i] = currentT.Apply(pts[i])
+				}
+				shape++
+				markClips(nil)
 				result = append(result, Path{
-					Points: pts,
-					Closed: true,
-					Stroke: strokeCol,
+					Points:    pts,
+					Closed:    true,
+					Transform: currentT,
+					Index:     len(result) + 1,
+					Shape:     shape,
+					Label:     "image",
+					Layer:     layerStack[len(layerStack)-1],
+					Override:  depth,
+					Image:     &svgImage{Href: href, Aspect: aspect, W: bw, H: bh},
 				})
+				refs = append(refs, nil) // clip-path and mask don't apply to images
+
+			case "circle":
+				currentGroupColor := colorStack[len(colorStack)-1]
+				currentT := transformStack[len(transformStack)-1]
+				depth := elementDepth(t.Attr, depthStack[len(depthStack)-1], warn)
+
+				var raw svgCircle
+				if err := dec.DecodeElement(&raw, &t); err != nil {
+					return nil, w, h, fmt.Errorf("decode <circle>: %w", err)
+				}
+				if raw.R <= 0 {
+					continue
+				}
+				currentT = currentT.Mul(parseTransformAttr(raw.Transform))
+				pts := circlePoints(Point{X: raw.CX, Y: raw.CY}, raw.R, 0.1)
+				for i := range pts {
+					pts[i] = currentT.Apply(pts[i])
+				}
+				strokeCol := extractStrokeColor(raw.Stroke, raw.Style)
+				if strokeCol == "" {
+					strokeCol = currentGroupColor
+				}
+
+				fill := inheritFill(fillStack[len(fillStack)-1], raw.Fill, raw.FillRule, raw.Style)
+				dash, dashOff := parseDash(raw.DashArray, raw.DashOffset, raw.Style, currentT)
+				width := strokeWidth(cmp.Or(styleProp(raw.Style, "stroke-width"), raw.Width, widthStack[len(widthStack)-1]), currentT)
+				shape++
+				result = append(result, Path{
+					Points:     pts,
+					Closed:     true,
+					Stroke:     strokeCol,
+					Transform:  currentT,
+					Index:      len(result) + 1,
+					Shape:      shape,
+					Layer:      layerStack[len(layerStack)-1],
+					Override:   depth,
+					Dash:       dash,
+					DashOffset: dashOff,
+					Width:      width,
+					Fill:       fill.paint(),
+					FillRule:   fill.Rule,
+				})
+				markClips(parseClipRefs(currentT, raw.ClipPath, raw.Mask))
 			}
 
 		case xml.EndElement:
-			if t.Name.Local == "g" {
-				if len(colorStack) > 1 {
-					colorStack = colorStack[:len(colorStack)-1]
-				}
-				if len(transformStack) > 1 {
-					transformStack = transformStack[:len(transformStack)-1]
+			switch t.Name.Local {
+			case "g":
+				popGroup()
+			case "svg":
+				if len(viewports) > 1 {
+					popGroup()
 				}
+				viewports = viewports[:max(len(viewports)-1, 0)]
 			}
 		}
 	}
 
+	markClips(nil)
+	result = applyClips(result, refs, clips, warn)
 	return result, w, h, nil
 }
+
+// layerName returns the name of the Inkscape layer a <g> element starts,
+// its label or else its id, and false for an ordinary group.
+func layerName(attrs []xml.Attr) (string, bool) {
+	var mode, label, id string
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "groupmode":
+			mode = a.Value
+		case "label":
+			label = a.Value
+		case "id":
+			id = a.Value
+		}
+	}
+	if mode != "layer" {
+		return "", false
+	}
+	return cmp.Or(strings.TrimSpace(label), id), true
+}
+
+// Namespaces the parser reads. Other elements are skipped with their
+// subtrees, and attributes in other namespaces are dropped, so that an
+// editor's own sodipodi:cx or the like never stands in for the SVG one.
+// Inkscape's are kept for layer names. A prefix the document never
+// declares is left as it is by the decoder, so the usual ones are
+// accepted by name too.
+const (
+	svgNS      = "http://www.w3.org/2000/svg"
+	xlinkNS    = "http://www.w3.org/1999/xlink"
+	xmlNS      = "http://www.w3.org/XML/1998/namespace"
+	inkscapeNS = "http://www.inkscape.org/namespaces/inkscape"
+)
+
+// foreignElement reports whether an element belongs to another XML
+// vocabulary, such as sodipodi:namedview or an RDF block.
+func foreignElement(n xml.Name) bool {
+	return n.Space != "" && n.Space != svgNS
+}
+
+// unrendered lists the SVG elements that hold no geometry to cut, and
+// may hold markup that looks like it.
+var unrendered = map[string]bool{
+	"foreignObject": true, "metadata": true, "desc": true, "title": true,
+}
+
+// svgAttrs drops the attributes of an element in namespaces the parser
+// doesn't read.
+func svgAttrs(t xml.StartElement) xml.StartElement {
+	keep := t.Attr[:0:0]
+	for _, a := range t.Attr {
+		switch a.Name.Space {
+		case "", svgNS, xlinkNS, xmlNS, inkscapeNS, "xlink", "inkscape", "xmlns":
+			keep = append(keep, a)
+		}
+	}
+	t.Attr = keep
+	return t
+}
+
+// drawable lists the elements that produce geometry (or contain it).
+var drawable = map[string]bool{
+	"g": true, "path": true, "polyline": true, "polygon": true, "circle": true, "text": true,
+	"svg": true, "use": true, "image": true,
+}
+
+// isHidden reports whether an element is not rendered: display:none,
+// visibility:hidden or collapse, or zero opacity, given as attributes or
+// in style. Hidden groups take their whole subtree with them.
+func isHidden(attrs []xml.Attr) bool {
+	props := map[string]string{}
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "display", "visibility", "opacity":
+			props[a.Name.Local] = a.Value
+		}
+	}
+	for _, a := range attrs {
+		if a.Name.Local == "style" {
+			for _, k := range []string{"display", "visibility", "opacity"} {
+				if v := styleProp(a.Value, k); v != "" {
+					props[k] = v // style beats presentation attributes
+				}
+			}
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(props["display"])) {
+	case "none":
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(props["visibility"])) {
+	case "hidden", "collapse":
+		return true
+	}
+	if op := strings.TrimSpace(props["opacity"]); op != "" {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(op, "%"), 64)
+		if err == nil && v <= 0 {
+			return true
+		}
+	}
+	return false
+}
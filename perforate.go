@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseDash reads stroke-dasharray and stroke-dashoffset (attributes, or
+// the style properties, which win) and scales them by the element
+// transform into root user units. An invalid or all-zero pattern, or
+// "none", means a solid stroke.
+func parseDash(arrayAttr, offsetAttr, style string, t Transform) (dash []float64, offset float64) {
+	if v := styleProp(style, "stroke-dasharray"); v != "" {
+		arrayAttr = v
+	}
+	if v := styleProp(style, "stroke-dashoffset"); v != "" {
+		offsetAttr = v
+	}
+	dash, err := parseDashList(arrayAttr)
+	if err != nil || dash == nil {
+		return nil, 0
+	}
+	// lengths along the stroke scale with the transform's linear part;
+	// for a non-uniform transform the average is the best single value
+	s := math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
+	for i := range dash {
+		dash[i] *= s
+	}
+	offset, _ = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(offsetAttr), "px"), 64)
+	return dash, offset * s
+}
+
+// parseDashList parses a comma/space separated dash list. An odd-length
+// list is repeated to make it even, as SVG specifies.
+func parseDashList(s string) ([]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return nil, nil
+	}
+	var dash []float64
+	sum := 0.0
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(f, "px"), 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid dash length %q", f)
+		}
+		dash = append(dash, v)
+		sum += v
+	}
+	if sum <= 0 {
+		return nil, nil
+	}
+	if len(dash)%2 == 1 {
+		dash = append(dash, dash...)
+	}
+	return dash, nil
+}
+
+// dashPaths splits dashed paths into one open path per dash, so the tool
+// lifts out over every gap. Paths without a pattern get def, if set.
+func dashPaths(paths []Path, def []float64) []Path {
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		dash, off := p.Dash, p.DashOffset
+		if dash == nil {
+			dash, off = def, 0
+		}
+		if dash == nil || p.Hole != nil || p.Slot != nil || p.Depths != nil || p.Power != nil || len(p.Points) < 2 {
+			out = append(out, p)
+			continue
+		}
+		for _, pts := range dashPolyline(p.Points, dash, off) {
+			q := p
+			q.Points = pts
+			q.Closed = false
+			q.Dash = nil
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// dashPolyline returns the "on" pieces of a polyline under a dash
+// pattern that starts offset into the pattern.
+func dashPolyline(pts []Point, dash []float64, offset float64) [][]Point {
+	period := 0.0
+	for _, d := range dash {
+		period += d
+	}
+	// find where in the pattern the path starts
+	k := 0
+	rem := math.Mod(offset, period)
+	if rem < 0 {
+		rem += period
+	}
+	for rem >= dash[k] {
+		rem -= dash[k]
+		k = (k + 1) % len(dash)
+	}
+	left := dash[k] - rem // length left in the current dash or gap
+	on := k%2 == 0
+
+	var out [][]Point
+	var cur []Point
+	if on {
+		cur = []Point{pts[0]}
+	}
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		seg := math.Hypot(b.X-a.X, b.Y-a.Y)
+		pos := 0.0
+		for seg-pos > left {
+			pos += left
+			p := lerp(a, b, pos/seg)
+			if on {
+				cur = append(cur, p)
+				out = append(out, cur)
+				cur = nil
+			} else {
+				cur = []Point{p}
+			}
+			on = !on
+			k = (k + 1) % len(dash)
+			left = dash[k]
+		}
+		left -= seg - pos
+		if on {
+			cur = append(cur, b)
+		}
+	}
+	if len(cur) > 1 {
+		out = append(out, cur)
+	}
+	// zero-length dashes have nothing to cut
+	keep := out[:0]
+	for _, c := range out {
+		if polylineLength(c) > 1e-9 {
+			keep = append(keep, c)
+		}
+	}
+	return keep
+}
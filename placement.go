@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Job placement stages. These run on machine coordinates (mm, Y up) and
+// move the whole job, never individual paths.
+
+// translatePaths shifts every point by (dx, dy) in place.
+func translatePaths(paths []Path, dx, dy float64) {
+	for i := range paths {
+		for j := range paths[i].Points {
+			paths[i].Points[j].X += dx
+			paths[i].Points[j].Y += dy
+		}
+	}
+}
+
+// applyOrigin moves the work origin relative to the bounding box of the
+// geometry. "svg" keeps the SVG page origin.
+func applyOrigin(paths []Path, origin string) error {
+	b, ok := pathBounds(paths)
+	if !ok {
+		return nil
+	}
+	switch origin {
+	case "svg", "":
+	case "lower-left":
+		translatePaths(paths, -b.MinX, -b.MinY)
+	case "upper-left":
+		translatePaths(paths, -b.MinX, -b.MaxY)
+	case "center":
+		translatePaths(paths, -(b.MinX+b.MaxX)/2, -(b.MinY+b.MaxY)/2)
+	default:
+		return fmt.Errorf("invalid origin %q (must be svg, lower-left, upper-left, center)", origin)
+	}
+	return nil
+}
+
+// mirrorPaths mirrors the job about the vertical (axis "x": X -> -X) or
+// horizontal (axis "y": Y -> -Y) line through the middle of its bounding
+// box, so the job stays where it was on the table.
+func mirrorPaths(paths []Path, axis string) error {
+	if axis == "" || axis == "none" {
+		return nil
+	}
+	b, ok := pathBounds(paths)
+	if !ok {
+		return nil
+	}
+	return flipPaths(paths, axis, (b.MinX+b.MaxX)/2, (b.MinY+b.MaxY)/2)
+}
+
+// flipPaths mirrors paths about the vertical line X = cx (axis "x") or
+// the horizontal line Y = cy (axis "y").
+func flipPaths(paths []Path, axis string, cx, cy float64) error {
+	switch axis {
+	case "x":
+		mapPoints(paths, func(p Point) Point { return Point{X: 2*cx - p.X, Y: p.Y} })
+	case "y":
+		mapPoints(paths, func(p Point) Point { return Point{X: p.X, Y: 2*cy - p.Y} })
+	default:
+		return fmt.Errorf("invalid mirror axis %q (must be none, x, y)", axis)
+	}
+	// Mirroring flips orientation; reverse closed loops so a climb cut
+	// stays a climb cut when machining from the back side. An open path
+	// keeps its direction, so its wall is now on the other side.
+	for i := range paths {
+		if paths[i].Closed {
+			paths[i].Points = reversePoints(paths[i].Points)
+		} else {
+			paths[i].CompSide = otherSide(paths[i].CompSide)
+			paths[i].Comp = otherSide(paths[i].Comp)
+		}
+	}
+	return nil
+}
+
+// rotatePaths rotates the job counter-clockwise by a multiple of 90° about
+// the lower-left corner of its bounding box, keeping that corner in place.
+func rotatePaths(paths []Path, degrees int) error {
+	b, ok := pathBounds(paths)
+	if !ok {
+		return nil
+	}
+	var f func(Point) Point
+	switch degrees {
+	case 0:
+		return nil
+	case 90:
+		f = func(p Point) Point { return Point{X: b.MinX + (b.MaxY - p.Y), Y: b.MinY + (p.X - b.MinX)} }
+	case 180:
+		f = func(p Point) Point { return Point{X: b.MaxX - (p.X - b.MinX), Y: b.MaxY - (p.Y - b.MinY)} }
+	case 270:
+		f = func(p Point) Point { return Point{X: b.MinX + (p.Y - b.MinY), Y: b.MinY + (b.MaxX - p.X)} }
+	default:
+		return fmt.Errorf("invalid rotation %d (must be 0, 90, 180, 270)", degrees)
+	}
+	mapPoints(paths, f)
+	return nil
+}
+
+func mapPoints(paths []Path, f func(Point) Point) {
+	for i := range paths {
+		for j, pt := range paths[i].Points {
+			paths[i].Points[j] = f(pt)
+		}
+	}
+}
+
+// parseSize parses "WxH" (e.g. "300x180") into positive width and height.
+func parseSize(s string) (w, h float64, err error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid size %q (want WxH)", s)
+	}
+	w, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	h, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("invalid size %q (want positive WxH)", s)
+	}
+	return w, h, nil
+}
+
+// fitPaths uniformly scales the job about the lower-left corner of its
+// bounding box so it is as large as possible while fitting inside w x h.
+// The aspect ratio is always kept; a job with no extent in either
+// direction cannot be fitted and is an error.
+func fitPaths(paths []Path, w, h float64) error {
+	b, ok := pathBounds(paths)
+	if !ok {
+		return nil
+	}
+	bw, bh := b.Width(), b.Height()
+	if bw <= 0 && bh <= 0 {
+		return fmt.Errorf("cannot fit %gx%g: job has no extent", w, h)
+	}
+	k := math.Inf(1)
+	if bw > 0 {
+		k = w / bw
+	}
+	if bh > 0 {
+		k = math.Min(k, h/bh)
+	}
+	mapPoints(paths, func(p Point) Point {
+		return Point{X: b.MinX + (p.X-b.MinX)*k, Y: b.MinY + (p.Y-b.MinY)*k}
+	})
+	return nil
+}
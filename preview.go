@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// previewColors are the stroke colors of feed moves by operation.
+var previewColors = map[string]string{
+	"cut":    "#d62728",
+	"score":  "#2ca02c",
+	"finish": "#9467bd",
+	"bore":   "#ff7f0e",
+	"drill":  "#ff7f0e",
+	"slot":   "#ff7f0e",
+	"vcarve": "#1f77b4",
+	"relief": "#8c564b",
+	"raster": "#7f7f7f",
+}
+
+// writePreview draws planned moves as an SVG seen from above, machine
+// coordinates in mm with Y up: feeds colored by operation, rapids as
+// thin dashed lines, on the stock outline when it is known. Z is not
+// shown.
+func writePreview(w io.Writer, moves []Move, stock Stock) error {
+	b := estimateMoves(moves, 0).Bounds
+	if math.IsInf(b.MinX, 0) {
+		b = Rect{MaxX: 1, MaxY: 1}
+	}
+	if stock.known() {
+		b = Rect{math.Min(b.MinX, 0), math.Min(b.MinY, 0), math.Max(b.MaxX, stock.W), math.Max(b.MaxY, stock.H)}
+	}
+	margin := math.Max(b.Width(), b.Height())*0.02 + 1
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%.3f %.3f %.3f %.3f" width="100%%" height="100%%">`+"\n",
+		b.MinX-margin, -b.MaxY-margin, b.Width()+2*margin, b.Height()+2*margin)
+	fmt.Fprint(bw, `<g transform="scale(1,-1)" fill="none" stroke-linecap="round" stroke-linejoin="round">`+"\n")
+	if stock.known() {
+		fmt.Fprintf(bw, `<rect width="%.3f" height="%.3f" fill="#f5deb3" fill-opacity="0.4" stroke="#8b7355" stroke-width="1" vector-effect="non-scaling-stroke"/>`+"\n",
+			stock.W, stock.H)
+	}
+
+	var prev Move
+	started := false
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		if started && (m.X != prev.X || m.Y != prev.Y || m.isArc()) {
+			d := fmt.Sprintf("M%.3f %.3f ", prev.X, prev.Y)
+			if m.isArc() {
+				_, r, sweep := arcGeometry(prev, m)
+				ccw := 0
+				if m.Kind == MoveArcCCW {
+					ccw = 1 // positive angles in the flipped group are CCW
+				}
+				if sweep > 2*math.Pi-1e-9 {
+					// a full circle needs two half arcs
+					c := Point{X: prev.X + m.I, Y: prev.Y + m.J}
+					d += fmt.Sprintf("A%.3f %.3f 0 0 %d %.3f %.3f ", r, r, ccw, 2*c.X-prev.X, 2*c.Y-prev.Y)
+				}
+				large := 0
+				if sweep > math.Pi && sweep <= 2*math.Pi-1e-9 {
+					large = 1
+				}
+				d += fmt.Sprintf("A%.3f %.3f 0 %d %d %.3f %.3f", r, r, large, ccw, m.X, m.Y)
+			} else {
+				d += fmt.Sprintf("L%.3f %.3f", m.X, m.Y)
+			}
+			if m.Kind == MoveRapid {
+				fmt.Fprintf(bw, `<path d="%s" stroke="#999" stroke-width="0.5" stroke-dasharray="2 2" vector-effect="non-scaling-stroke"/>`+"\n", d)
+			} else {
+				color := previewColors[m.Op]
+				if color == "" {
+					color = "#d62728"
+				}
+				fmt.Fprintf(bw, `<path d="%s" stroke="%s" stroke-width="1.5" vector-effect="non-scaling-stroke"/>`+"\n", d, color)
+			}
+		}
+		prev, started = m, true
+	}
+	fmt.Fprint(bw, "</g>\n</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// Probe configures a touch-plate Z probing sequence at program start.
+type Probe struct {
+	Enabled   bool
+	Thickness float64 // touch plate thickness, mm
+	Feed      float64 // probing feed, mm/min
+	MaxTravel float64 // how far down to search, mm
+	Retract   float64 // lift after contact, mm
+	Zero      string  // "g10" (G10 L20, persistent WCS offset) or "g92"
+}
+
+// writeProbe appends the probing sequence: pause for the operator to set
+// up the plate, probe down with G38.2, make the contact point Z=Thickness
+// in the active work coordinate system, then lift clear.
+func writeProbe(prog *Program, pr Probe, f gcodeFormat, msg Messages) {
+	prog.Raw("")
+	prog.Comment(msg.T("probe"))
+	prog.Raw(fmt.Sprintf("M0  (%s)", msg.T("probe_ready")))
+	prog.Raw("G91  (relative)")
+	prog.Raw(fmt.Sprintf("G38.2 Z%s F%s", f.num(-pr.MaxTravel), f.num(pr.Feed)))
+	if pr.Zero == "g92" {
+		prog.Raw(fmt.Sprintf("G92 Z%s", f.num(pr.Thickness)))
+	} else {
+		prog.Raw(fmt.Sprintf("G10 L20 P0 Z%s", f.num(pr.Thickness)))
+	}
+	prog.Raw(fmt.Sprintf("G0 Z%s", f.num(pr.Retract)))
+	prog.Raw("G90  (absolute)")
+	prog.Raw(fmt.Sprintf("M0  (%s)", msg.T("probe_done")))
+}
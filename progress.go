@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// progressMoves marks where every path of a planned program starts with
+// how far the job has got: paths started out of the total, and the share
+// of the estimated run time already behind it. style says how: "comment"
+// writes a G-code comment for anyone reading along in a sender, "m117" a
+// Marlin M117 display message and "msg" a LinuxCNC (MSG, ...) line the
+// operator screen shows. rapid is the assumed G0 rate for the estimate.
+func progressMoves(moves []Move, style string, rapid float64, msg Messages) []Move {
+	// where each path's block starts, skipping the blank line before it
+	var starts []int
+	last := 0
+	for i, m := range moves {
+		if m.Path == 0 || m.Path == last {
+			continue
+		}
+		if m.Kind == MoveRaw && m.Text == "" {
+			continue
+		}
+		last = m.Path
+		starts = append(starts, i)
+	}
+	if len(starts) == 0 {
+		return moves
+	}
+
+	// run time up to every move
+	elapsed := make([]float64, len(moves)+1)
+	var prev Move
+	havePrev := false
+	for i, m := range moves {
+		elapsed[i+1] = elapsed[i]
+		if !m.isMotion() {
+			continue
+		}
+		if havePrev {
+			elapsed[i+1] += moveMinutes(prev, m, rapid)
+		}
+		prev, havePrev = m, true
+	}
+	total := elapsed[len(moves)]
+
+	out := make([]Move, 0, len(moves)+len(starts))
+	next := 0
+	for i, m := range moves {
+		if next < len(starts) && starts[next] == i {
+			pct := 0.0
+			if total > 0 {
+				pct = 100 * elapsed[i] / total
+			}
+			text := msg.T("progress", next+1, len(starts), pct)
+			at := m
+			if i > 0 {
+				at = moves[i-1] // a marker doesn't move the machine
+			}
+			mark := Move{Kind: MoveRaw, X: at.X, Y: at.Y, Z: at.Z, Path: m.Path, Op: m.Op}
+			switch style {
+			case "m117":
+				// ; and * would end the message early on Marlin
+				mark.Text = "M117 " + strings.NewReplacer(";", ",", "*", "x").Replace(text)
+			case "msg":
+				mark.Text = fmt.Sprintf("(MSG, %s)", commentSafe(text))
+			default:
+				mark.Kind = MoveComment
+				mark.Text = commentSafe(text)
+			}
+			out = append(out, mark)
+			next++
+		}
+		out = append(out, m)
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package main
+
+// repairPaths splits closed outlines that cross or touch themselves, as
+// traced artwork often does, into simple loops along the edge of the area
+// they fill under their fill rule. A figure eight becomes two loops, and
+// a loop that crosses back over itself loses the tangle. Compensation and
+// pocketing need simple outlines: orientation and inside are meaningless
+// for a polygon that crosses itself. The loops keep the original's
+// turning direction, holes turning the other way, and stay one shape, so
+// holes are still recognised as holes.
+func repairPaths(paths []Path, warn *Warnings) []Path {
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if !p.Closed || p.Hole != nil || p.Depths != nil || p.Filled || len(p.Points) < 4 {
+			out = append(out, p)
+			continue
+		}
+		in := func(w int) bool { return w != 0 }
+		if p.FillRule == "evenodd" {
+			in = func(w int) bool { return w%2 != 0 }
+		}
+		loops, split := regionLoops(dedupePoints(p.Points), in)
+		if !split || len(loops) == 0 {
+			out = append(out, p) // simple already, or no area to cut
+			continue
+		}
+		cw := signedArea(p.Points) < 0
+		for _, loop := range loops {
+			q := p
+			q.Points = loop
+			if cw {
+				q.Points = reversePoints(loop)
+			}
+			out = append(out, q)
+		}
+		warn.Add(WSelfIntersecting, p.Index, "outline crosses itself; split into %d simple loop(s)", len(loops))
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package main
+
+import "math"
+
+// restTol is how much material the previous tool may leave before the
+// rest pass bothers with it, mm. It also absorbs offset rounding.
+const restTol = 0.05
+
+// restSegments returns the parts of the toolpath small that cut material
+// a larger tool running on the loops big left behind. Cutting the same wall, the
+// two tool centres are gap = R - r apart; wherever small is farther than
+// that from big, the larger tool could not reach (inside corners, narrow
+// slots). Runs are extended by overlap at both ends so the rest cut
+// blends into the wall. A nil result means nothing is left to cut; a
+// single run covering all of small is returned as small itself.
+func restSegments(small []Point, big [][]Point, gap, overlap float64) [][]Point {
+	if len(small) < 2 {
+		return nil
+	}
+	if len(big) == 0 {
+		return [][]Point{small} // the large tool did not fit at all
+	}
+	// sample finely enough to find corner material of restTol
+	step := math.Max(restTol, gap/8)
+	var pts []Point
+	for i := 1; i < len(small); i++ {
+		a, b := small[i-1], small[i]
+		n := int(math.Ceil(dist(a, b) / step))
+		for k := 0; k < n; k++ {
+			pts = append(pts, lerp(a, b, float64(k)/float64(n)))
+		}
+	}
+	pts = append(pts, small[len(small)-1])
+
+	need := make([]bool, len(pts))
+	all := true
+	for i, q := range pts {
+		d := math.Inf(1)
+		for _, loop := range big {
+			for j := 1; j < len(loop); j++ {
+				d = math.Min(d, distPointToSegment(q, loop[j-1], loop[j]))
+			}
+		}
+		need[i] = d > gap+restTol
+		all = all && need[i]
+	}
+	if all {
+		return [][]Point{small}
+	}
+
+	// grow every run by overlap along the path
+	grown := append([]bool(nil), need...)
+	for i := range pts {
+		if !need[i] {
+			continue
+		}
+		for _, dir := range []int{-1, 1} {
+			walked := 0.0
+			for j := i + dir; j >= 0 && j < len(pts) && walked < overlap; j += dir {
+				walked += dist(pts[j], pts[j-dir])
+				grown[j] = true
+			}
+		}
+	}
+
+	var runs [][]Point
+	var run []Point
+	for i, q := range pts {
+		if grown[i] {
+			run = append(run, q)
+			continue
+		}
+		if len(run) > 1 {
+			runs = append(runs, simplifyPath(run, restTol/10))
+		}
+		run = nil
+	}
+	if len(run) > 1 {
+		if len(runs) > 0 && grown[0] && almostEqualPoint(pts[0], pts[len(pts)-1]) {
+			// a closed path: the last run continues into the first
+			runs[0] = simplifyPath(append(run, runs[0][1:]...), restTol/10)
+		} else {
+			runs = append(runs, simplifyPath(run, restTol/10))
+		}
+	}
+	return runs
+}
+
+// restOf cuts down one compensated toolpath of orig to the rest
+// machining runs. allowance is the radial stock the toolpath leaves,
+// which the larger tool is assumed to have left as well.
+func restOf(cut, orig Path, allowance float64, mode string, cfg Config) []Path {
+	if cfg.RestDia <= 0 {
+		return []Path{cut}
+	}
+	r, R := cfg.ToolDia/2, cfg.RestDia/2
+	big := offsetContours(orig.Points, R+allowance, mode, cfg.Join)
+	runs := restSegments(cut.Points, big, R-r, r)
+	if len(runs) == 1 && len(runs[0]) == len(cut.Points) {
+		return []Path{cut}
+	}
+	out := make([]Path, 0, len(runs))
+	for _, run := range runs {
+		p := cut
+		p.Points = run
+		p.Closed = false
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// grblRXBuffer is the size of GRBL's serial receive buffer. The sender
+// never has more unacknowledged bytes in flight than this.
+const grblRXBuffer = 128
+
+var errInterrupted = errors.New("interrupted; feed held, reset and retracted")
+
+// grblLines reduces a program to what the controller needs: comments and
+// blank lines cost buffer space and are dropped.
+func grblLines(program []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(strings.NewReader(string(program)))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		for {
+			i := strings.IndexByte(line, '(')
+			j := strings.IndexByte(line, ')')
+			if i < 0 || j < i {
+				break
+			}
+			line = line[:i] + line[j+1:]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// grblPort is an open connection to a GRBL controller.
+type grblPort struct {
+	f     *os.File
+	lines chan string // responses, one per line, without line ends
+	errc  chan error
+	done  chan struct{}
+
+	status string  // last status report
+	wco    float64 // last reported work coordinate Z offset
+}
+
+func openGRBL(name string, baud int) (*grblPort, error) {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := configurePort(f, baud); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("configure %s: %w", name, err)
+	}
+	g := &grblPort{f: f, lines: make(chan string, 64), errc: make(chan error, 1), done: make(chan struct{})}
+	go g.read()
+	return g, nil
+}
+
+// read splits controller output into lines. The port is configured with a
+// short read timeout, which shows up as io.EOF.
+func (g *grblPort) read() {
+	var line []byte
+	buf := make([]byte, 256)
+	for {
+		select {
+		case <-g.done:
+			return
+		default:
+		}
+		n, err := g.f.Read(buf)
+		for _, c := range buf[:n] {
+			switch c {
+			case '\n':
+				if s := strings.TrimSpace(string(line)); s != "" {
+					select {
+					case g.lines <- s:
+					case <-g.done:
+						return
+					}
+				}
+				line = line[:0]
+			case '\r':
+			default:
+				line = append(line, c)
+			}
+		}
+		if err != nil && err != io.EOF {
+			g.errc <- err
+			return
+		}
+	}
+}
+
+func (g *grblPort) Close() error {
+	close(g.done)
+	return g.f.Close()
+}
+
+func (g *grblPort) write(s string) error {
+	_, err := g.f.WriteString(s)
+	return err
+}
+
+// noteStatus remembers a status report such as
+// <Run|MPos:1.000,2.000,-3.000|FS:300,0|WCO:0.000,0.000,-10.000>.
+func (g *grblPort) noteStatus(s string) {
+	g.status = s
+	for _, field := range strings.Split(strings.Trim(s, "<>"), "|") {
+		if v, ok := strings.CutPrefix(field, "WCO:"); ok {
+			if z, ok := axisZ(v); ok {
+				g.wco = z
+			}
+		}
+	}
+}
+
+// workZ returns the work Z position from the last status report.
+func (g *grblPort) workZ() (float64, bool) {
+	for _, field := range strings.Split(strings.Trim(g.status, "<>"), "|") {
+		if v, ok := strings.CutPrefix(field, "WPos:"); ok {
+			return axisZ(v)
+		}
+		if v, ok := strings.CutPrefix(field, "MPos:"); ok {
+			z, ok := axisZ(v)
+			return z - g.wco, ok
+		}
+	}
+	return 0, false
+}
+
+func axisZ(xyz string) (float64, bool) {
+	parts := strings.Split(xyz, ",")
+	if len(parts) < 3 {
+		return 0, false
+	}
+	z, err := strconv.ParseFloat(parts[2], 64)
+	return z, err == nil
+}
+
+// state is the machine state from the last status report, e.g. "Idle",
+// "Run" or "Hold:0".
+func (g *grblPort) state() string {
+	s, _, _ := strings.Cut(strings.Trim(g.status, "<>"), "|")
+	return s
+}
+
+// waitFor reads responses until ok accepts one, polling status on the way.
+func (g *grblPort) waitFor(ok func(string) bool, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	poll := time.NewTicker(100 * time.Millisecond)
+	defer poll.Stop()
+	for {
+		select {
+		case l := <-g.lines:
+			if strings.HasPrefix(l, "<") {
+				g.noteStatus(l)
+			}
+			if ok(l) {
+				return true
+			}
+		case <-poll.C:
+			g.write("?")
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// abort stops the job: feed hold, then once motion has stopped a soft
+// reset to flush GRBL's queue (which keeps the position, since the
+// machine is standing still), then a lift to safeZ above the work zero.
+func (g *grblPort) abort(safeZ float64) {
+	g.write("!")
+	g.waitFor(func(l string) bool {
+		return strings.HasPrefix(l, "<Hold:0") || strings.HasPrefix(l, "<Idle")
+	}, 10*time.Second)
+	g.write("\x18")
+	g.waitFor(func(l string) bool { return strings.HasPrefix(l, "Grbl") }, 3*time.Second)
+
+	lift := safeZ
+	if z, ok := g.workZ(); ok {
+		lift = safeZ - z
+	}
+	if lift > 0 {
+		// a reset restores G21 G90, but say so
+		g.write(fmt.Sprintf("G21 G91 G0 Z%.3f\n", lift))
+		g.waitFor(func(l string) bool { return l == "ok" || strings.HasPrefix(l, "error") }, 30*time.Second)
+	}
+	g.write("G90\n")
+	g.waitFor(func(l string) bool { return l == "ok" || strings.HasPrefix(l, "error") }, 2*time.Second)
+}
+
+// console opens the terminal that answers M0 pauses: /dev/tty, so the
+// prompt works while the drawing comes in on stdin, or stdin itself
+// where there is no terminal.
+func console() io.ReadCloser {
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		return tty
+	}
+	return io.NopCloser(os.Stdin)
+}
+
+// sendGRBL streams program to a GRBL controller on port using the
+// character-counting protocol: lines are sent while they fit in the
+// controller's receive buffer and retired as each "ok" comes back.
+// Progress goes to stderr. M0 pauses wait for Enter on the terminal;
+// when there is no answer to wait for, the job aborts like Ctrl-C, which
+// holds the feed and retracts to safeZ before returning.
+func sendGRBL(port string, baud int, program []byte, safeZ float64) error {
+	g, err := openGRBL(port, baud)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	// wake the controller; opening the port usually resets it too
+	g.write("\r\n\r\n")
+	if !g.waitFor(func(l string) bool { return strings.HasPrefix(l, "Grbl") }, 3*time.Second) {
+		g.write("\x18")
+		if !g.waitFor(func(l string) bool { return strings.HasPrefix(l, "Grbl") }, 3*time.Second) {
+			return fmt.Errorf("no GRBL greeting on %s", port)
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	con := console()
+	defer con.Close()
+	answers := bufio.NewReader(con)
+	resume := make(chan error, 1)
+	prompted := false
+
+	lines := grblLines(program)
+	var inFlight []int // byte counts of unacknowledged lines
+	queued := 0
+	sent, acked := 0, 0
+	fresh := false // a status report arrived since the last "ok"
+	poll := time.NewTicker(250 * time.Millisecond)
+	defer poll.Stop()
+	progress := func() {
+		fmt.Fprintf(os.Stderr, "\rsent %d/%d lines, %s   ", acked, len(lines), g.state())
+	}
+	for acked < len(lines) || !fresh || g.state() != "Idle" {
+		for sent < len(lines) && queued+len(lines[sent])+1 <= grblRXBuffer {
+			if err := g.write(lines[sent] + "\n"); err != nil {
+				return err
+			}
+			inFlight = append(inFlight, len(lines[sent])+1)
+			queued += len(lines[sent]) + 1
+			sent++
+		}
+		select {
+		case l := <-g.lines:
+			switch {
+			case l == "ok":
+				if len(inFlight) == 0 {
+					continue
+				}
+				queued -= inFlight[0]
+				inFlight = inFlight[1:]
+				acked++
+				fresh = false
+			case strings.HasPrefix(l, "error"):
+				bad := lines[acked]
+				fmt.Fprintln(os.Stderr)
+				g.abort(safeZ)
+				return fmt.Errorf("GRBL rejected line %d %q: %s", acked+1, bad, l)
+			case strings.HasPrefix(l, "ALARM"):
+				fmt.Fprintln(os.Stderr)
+				return fmt.Errorf("GRBL %s after line %d; clear it at the machine", l, acked)
+			case strings.HasPrefix(l, "<"):
+				g.noteStatus(l)
+				fresh = true
+				if strings.HasPrefix(g.state(), "Hold:0") && !prompted {
+					// an M0 in the program
+					prompted = true
+					fmt.Fprint(os.Stderr, "\npaused: press Enter to continue\n")
+					go func() {
+						_, err := answers.ReadString('\n')
+						resume <- err
+					}()
+				}
+				progress()
+			}
+		case err := <-resume:
+			prompted = false
+			if err != nil {
+				// stdin closed or already read to the end as the input:
+				// nobody is there to say the machine is ready
+				fmt.Fprintln(os.Stderr)
+				g.abort(safeZ)
+				return fmt.Errorf("no answer at the M0 pause (%v); feed held, reset and retracted", err)
+			}
+			g.write("~")
+		case <-poll.C:
+			g.write("?")
+		case err := <-g.errc:
+			return err
+		case <-sig:
+			fmt.Fprintln(os.Stderr)
+			g.abort(safeZ)
+			return errInterrupted
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
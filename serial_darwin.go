@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var bauds = map[int]uint64{
+	9600: unix.B9600, 19200: unix.B19200, 38400: unix.B38400, 57600: unix.B57600,
+	115200: unix.B115200, 230400: unix.B230400,
+}
+
+// configurePort puts a serial device into raw mode at baud.
+func configurePort(f *os.File, baud int) error {
+	speed, ok := bauds[baud]
+	if !ok {
+		return fmt.Errorf("unsupported baud rate %d", baud)
+	}
+	fd := int(f.Fd())
+	t, err := unix.IoctlGetTermios(fd, unix.TIOCGETA)
+	if err != nil {
+		return err
+	}
+	makeRaw(t)
+	t.Ispeed, t.Ospeed = speed, speed
+	return unix.IoctlSetTermios(fd, unix.TIOCSETA, t)
+}
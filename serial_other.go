@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// configurePort is only implemented for Linux and macOS.
+func configurePort(f *os.File, baud int) error {
+	return errors.New("-send is not supported on this platform")
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// serve runs the web UI on addr. Every upload is converted with base,
+// the configuration from the command line, after applying the settings
+// changed on the page. flipY is the -flip-y mode, applied per upload.
+func serve(addr string, base Config, flipY string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, servePage(base))
+	})
+	mux.HandleFunc("POST /convert", func(w http.ResponseWriter, r *http.Request) {
+		res, err := convertUpload(r, base, flipY)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	})
+	fmt.Printf("svg2gcode: serving on %s\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// convertResult is what the page gets back for an upload.
+type convertResult struct {
+	Gcode    string    `json:"gcode"`
+	Preview  string    `json:"preview"`
+	Estimate string    `json:"estimate"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// pageSettings are the Config fields the page can change, by form name.
+var pageSettings = []struct {
+	Name, Label string
+	Field       func(*Config) *float64
+}{
+	{"cutz", "Cut depth (mm, negative)", func(c *Config) *float64 { return &c.CutDepth }},
+	{"stepdown", "Step-down (mm, 0 = one pass)", func(c *Config) *float64 { return &c.StepDown }},
+	{"feed", "Feed (mm/min)", func(c *Config) *float64 { return &c.CutFeed }},
+	{"plunge", "Plunge (mm/min)", func(c *Config) *float64 { return &c.PlungeFeed }},
+	{"safez", "Safe Z (mm)", func(c *Config) *float64 { return &c.SafeZ }},
+	{"tooldia", "Tool diameter (mm)", func(c *Config) *float64 { return &c.ToolDia }},
+	{"scale", "Scale (units → mm)", func(c *Config) *float64 { return &c.Scale }},
+}
+
+func convertUpload(r *http.Request, base Config, flipY string) (*convertResult, error) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, err
+	}
+	file, hdr, err := r.FormFile("drawing")
+	if err != nil {
+		return nil, fmt.Errorf("no drawing uploaded")
+	}
+	defer file.Close()
+
+	cfg := base
+	for _, s := range pageSettings {
+		if v := r.FormValue(s.Name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a number", s.Label, v)
+			}
+			*s.Field(&cfg) = f
+		}
+	}
+	switch comp := r.FormValue("comp"); comp {
+	case "":
+	case "none", "inside", "outside", "left", "right":
+		cfg.Compensation = comp
+	default:
+		return nil, fmt.Errorf("invalid compensation %q", comp)
+	}
+	if cfg.Compensation != "none" && cfg.ToolDia <= 0 {
+		return nil, fmt.Errorf("compensation needs a tool diameter")
+	}
+	if cfg.SafeZ <= 0 || cfg.CutFeed <= 0 || cfg.PlungeFeed <= 0 || cfg.Scale <= 0 {
+		return nil, fmt.Errorf("safe Z, feeds and scale must be positive")
+	}
+
+	warn := &Warnings{Out: io.Discard}
+	cfg.Warn = warn
+	// an upload has no directory: only embedded images are read
+	paths, cfg, err := loadInput(file, hdr.Filename, "", cfg, "auto", flipY)
+	if err != nil {
+		return nil, err
+	}
+
+	var gcode bytes.Buffer
+	if err := writeGcode(&gcode, paths, cfg); err != nil {
+		return nil, err
+	}
+	quiet := cfg
+	quiet.Warn = nil
+	body, err := planJob(paths, quiet)
+	if err != nil {
+		return nil, err
+	}
+	var preview bytes.Buffer
+	if err := writePreview(&preview, body.Moves, cfg.Stock); err != nil {
+		return nil, err
+	}
+	return &convertResult{
+		Gcode:    gcode.String(),
+		Preview:  preview.String(),
+		Estimate: estimateMoves(body.Moves, cfg.RapidFeed).String(),
+		Warnings: append([]Warning{}, warn.List...),
+	}, nil
+}
+
+// servePage renders the single page of the UI, with the command-line
+// settings as the initial values.
+func servePage(base Config) string {
+	var fields strings.Builder
+	for _, s := range pageSettings {
+		fmt.Fprintf(&fields, `<label>%s<input name="%s" type="number" step="any" value="%s"></label>`+"\n",
+			s.Label, s.Name, strconv.FormatFloat(*s.Field(&base), 'g', -1, 64))
+	}
+	comp := ""
+	for _, c := range []string{"none", "inside", "outside", "left", "right"} {
+		sel := ""
+		if c == base.Compensation {
+			sel = " selected"
+		}
+		comp += fmt.Sprintf(`<option%s>%s</option>`, sel, c)
+	}
+	return strings.NewReplacer("{{fields}}", fields.String(), "{{comp}}", comp).Replace(pageHTML)
+}
+
+const pageHTML = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>svg2gcode</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+form { width: 18em; padding: 1em; overflow-y: auto; background: #f4f4f4; }
+label { display: block; margin-bottom: .6em; font-size: .9em; }
+input, select, button { display: block; width: 100%; box-sizing: border-box; margin-top: .2em; }
+#drop { border: 2px dashed #999; padding: 1.5em .5em; text-align: center; margin-bottom: 1em; }
+#drop.over { border-color: #d62728; }
+main { flex: 1; display: flex; flex-direction: column; }
+#preview { flex: 1; min-height: 0; background: #fff; }
+pre { margin: 0; padding: .5em 1em; background: #222; color: #eee; max-height: 12em; overflow: auto; }
+</style>
+</head>
+<body>
+<form id="form">
+<div id="drop">Drop an SVG or DXF here<input id="file" name="drawing" type="file" accept=".svg,.dxf"></div>
+{{fields}}
+<label>Compensation<select name="comp">{{comp}}</select></label>
+<button id="download" type="button" disabled>Download G-code</button>
+</form>
+<main>
+<div id="preview"></div>
+<pre id="info">No drawing loaded.</pre>
+</main>
+<script>
+const form = document.getElementById("form"), file = document.getElementById("file");
+const drop = document.getElementById("drop"), info = document.getElementById("info");
+const download = document.getElementById("download");
+let drawing = null, gcode = "", timer = null;
+
+async function convert() {
+  if (!drawing) return;
+  const data = new FormData(form);
+  data.set("drawing", drawing, drawing.name);
+  const res = await fetch("convert", { method: "POST", body: data });
+  if (!res.ok) { info.textContent = "error: " + await res.text(); download.disabled = true; return; }
+  const out = await res.json();
+  gcode = out.gcode;
+  document.getElementById("preview").innerHTML = out.preview;
+  info.textContent = out.estimate + out.warnings.map(w => "\nwarning " + w.code + " " + w.name + ": " + w.message).join("");
+  download.disabled = false;
+}
+function later() { clearTimeout(timer); timer = setTimeout(convert, 300); }
+
+form.addEventListener("input", e => { if (e.target !== file) later(); });
+file.addEventListener("change", () => { drawing = file.files[0]; convert(); });
+drop.addEventListener("dragover", e => { e.preventDefault(); drop.classList.add("over"); });
+drop.addEventListener("dragleave", () => drop.classList.remove("over"));
+drop.addEventListener("drop", e => {
+  e.preventDefault(); drop.classList.remove("over");
+  drawing = e.dataTransfer.files[0]; convert();
+});
+download.addEventListener("click", () => {
+  const a = document.createElement("a");
+  a.href = URL.createObjectURL(new Blob([gcode], { type: "text/plain" }));
+  a.download = drawing.name.replace(/\.[^.]*$/, "") + ".nc";
+  a.click();
+});
+</script>
+</body>
+</html>
+`
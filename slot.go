@@ -0,0 +1,258 @@
+package main
+
+import (
+	"cmp"
+	"math"
+)
+
+// Slots are drawn three ways: as an outline with round ends, as two
+// parallel lines (the slot's walls), or as one line stroked as wide as
+// the slot. Profiled, each cuts both walls and leaves the middle, or
+// cuts a line twice; with -slots they are machined as what they are.
+
+// Slot is a straight slot recognised for -slots, in machine mm: the
+// tool clears everything within W/2 of the centreline A–B, leaving
+// round ends.
+type Slot struct {
+	A, B Point
+	W    float64
+}
+
+// findSlots marks slot outlines, wall pairs and wide strokes as slots.
+// Slots narrower than the tool are left to be profiled; everything else
+// passes through.
+func findSlots(paths []Path, cfg Config) []Path {
+	tol := cfg.CircleTol
+	fits := func(w float64) bool { return w >= cfg.ToolDia-boreTol }
+	plain := func(p Path) bool {
+		return p.Hole == nil && p.Slot == nil && p.Depths == nil && p.Image == nil && !p.Filled &&
+			cfg.ColorMap.rule(p.Stroke).Op == "cut"
+	}
+
+	out := paths[:0]
+	var walls []int // straight open paths in out, not yet paired
+	for _, p := range paths {
+		if !plain(p) {
+			out = append(out, p)
+			continue
+		}
+		if p.Closed {
+			if s, ok := slotOutline(p.Points, tol, 0.1*cfg.Scale); ok && fits(s.W) {
+				p.Slot = &s
+			}
+			out = append(out, p)
+			continue
+		}
+		a, b, ok := straight(p.Points, tol)
+		switch {
+		case ok && fits(p.Width):
+			p.Slot = &Slot{A: a, B: b, W: p.Width}
+		case ok:
+			walls = append(walls, len(out))
+		}
+		out = append(out, p)
+	}
+
+	// walls facing each other, end to end, become one slot, unless
+	// something else meets their ends: then they're sides of a bigger shape
+	var ends []Point
+	for _, p := range out {
+		if !p.Closed && len(p.Points) > 1 {
+			ends = append(ends, p.Points[0], p.Points[len(p.Points)-1])
+		}
+	}
+	capped := func(w int) bool {
+		n := 0
+		for _, e := range ends {
+			for _, pt := range []Point{out[w].Points[0], out[w].Points[len(out[w].Points)-1]} {
+				if math.Hypot(e.X-pt.X, e.Y-pt.Y) <= tol {
+					n++
+				}
+			}
+		}
+		return n > 2 // more than its own two ends
+	}
+	paired := map[int]bool{}
+	for i, wi := range walls {
+		for _, wj := range walls[i+1:] {
+			if paired[wi] || paired[wj] || out[wi].Stroke != out[wj].Stroke || capped(wi) || capped(wj) {
+				continue
+			}
+			s, outline, ok := slotBetween(out[wi].Points, out[wj].Points, tol)
+			if !ok || !fits(s.W) {
+				continue
+			}
+			paired[wi], paired[wj] = true, true
+			out[wi].Points, out[wi].Closed = outline, true
+			out[wi].Slot = &s
+		}
+	}
+	kept := out[:0]
+	for i, p := range out {
+		if !paired[i] || p.Slot != nil {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// straight reports whether an open polyline is a straight line, every
+// point within tol of the one from its first point to its last and
+// between them, and returns the ends.
+func straight(pts []Point, tol float64) (a, b Point, ok bool) {
+	if len(pts) < 2 {
+		return a, b, false
+	}
+	a, b = pts[0], pts[len(pts)-1]
+	l := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if l <= tol {
+		return a, b, false
+	}
+	for _, p := range pts[1 : len(pts)-1] {
+		if distPointToSegment(p, a, b) > tol {
+			return a, b, false
+		}
+	}
+	return a, b, true
+}
+
+// slotBetween pairs two straight walls into a slot: parallel, their ends
+// level with each other, and further apart than the tool needs. The
+// slot's round ends stay within the walls. outline is the rectangle they
+// bound, so the path keeps the walls' extent.
+func slotBetween(p, q []Point, tol float64) (s Slot, outline []Point, ok bool) {
+	a, b, _ := straight(p, tol)
+	c, d, _ := straight(q, tol)
+	l := math.Hypot(b.X-a.X, b.Y-a.Y)
+	u := Point{X: (b.X - a.X) / l, Y: (b.Y - a.Y) / l}
+	along := func(p Point) float64 { return (p.X-a.X)*u.X + (p.Y-a.Y)*u.Y }
+	if along(c) > along(d) {
+		c, d = d, c
+	}
+	w := math.Abs(cross(Point{X: c.X - a.X, Y: c.Y - a.Y}, u))
+	if math.Abs(along(c)) > tol || math.Abs(along(d)-l) > tol ||
+		math.Abs(math.Abs(cross(Point{X: d.X - a.X, Y: d.Y - a.Y}, u))-w) > tol ||
+		w >= l || w <= tol {
+		return s, nil, false
+	}
+	m0, m1 := lerp(a, c, 0.5), lerp(b, d, 0.5)
+	s = Slot{
+		A: Point{X: m0.X + u.X*w/2, Y: m0.Y + u.Y*w/2},
+		B: Point{X: m1.X - u.X*w/2, Y: m1.Y - u.Y*w/2},
+		W: w,
+	}
+	return s, []Point{a, b, d, c, a}, true
+}
+
+// slotOutline reports whether a closed polygon is the outline of a slot:
+// two straight sides joined by semicircles. Its vertices must lie within
+// tol of that shape and its edges no further inside than flat, and its
+// perimeter must match, which tells it from a rectangle or half a slot.
+func slotOutline(points []Point, tol, flat float64) (Slot, bool) {
+	pts := points
+	if len(pts) > 1 && almostEqualPoint(pts[0], pts[len(pts)-1]) {
+		pts = pts[:len(pts)-1]
+	}
+	if len(pts) < 6 {
+		return Slot{}, false
+	}
+
+	// the long axis, from the spread of the vertices
+	var m Point
+	for _, p := range pts {
+		m.X += p.X
+		m.Y += p.Y
+	}
+	m.X /= float64(len(pts))
+	m.Y /= float64(len(pts))
+	var sxx, sxy, syy float64
+	for _, p := range pts {
+		x, y := p.X-m.X, p.Y-m.Y
+		sxx += x * x
+		sxy += x * y
+		syy += y * y
+	}
+	th := math.Atan2(2*sxy, sxx-syy) / 2
+	u := Point{X: math.Cos(th), Y: math.Sin(th)}
+
+	lo, hi := Point{X: math.Inf(1), Y: math.Inf(1)}, Point{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, p := range pts {
+		x, y := (p.X-m.X)*u.X+(p.Y-m.Y)*u.Y, cross(u, Point{X: p.X - m.X, Y: p.Y - m.Y})
+		lo.X, lo.Y = math.Min(lo.X, x), math.Min(lo.Y, y)
+		hi.X, hi.Y = math.Max(hi.X, x), math.Max(hi.Y, y)
+	}
+	w := hi.Y - lo.Y
+	l := hi.X - lo.X - w // centreline length
+	if l <= tol || w <= tol {
+		return Slot{}, false // round: a hole, if anything
+	}
+	at := func(x, y float64) Point {
+		return Point{X: m.X + u.X*x - u.Y*y, Y: m.Y + u.Y*x + u.X*y}
+	}
+	mid := (lo.Y + hi.Y) / 2
+	s := Slot{A: at(lo.X+w/2, mid), B: at(hi.X-w/2, mid), W: w}
+
+	perimeter := 0.0
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		if math.Abs(distPointToSegment(p, s.A, s.B)-w/2) > tol ||
+			math.Abs(distPointToSegment(lerp(p, q, 0.5), s.A, s.B)-w/2) > max(flat, tol) {
+			return Slot{}, false
+		}
+		perimeter += math.Hypot(q.X-p.X, q.Y-p.Y)
+	}
+	if want := 2*l + math.Pi*w; math.Abs(perimeter-want) > 0.02*want {
+		return Slot{}, false
+	}
+	return s, true
+}
+
+// planSlot machines a slot, start being the end to begin at. A slot the
+// width of the tool is one pass down its centreline, back and forth as
+// it steps down; a wider one is cleared at each depth from the
+// centreline out in loops round the centreline, the last one at the
+// walls, counter-clockwise (climb) unless -direction conventional.
+func planSlot(prog *Program, s Slot, start Point, targetZ float64, cfg Config) {
+	prog.op = "slot"
+	prog.Comment(cfg.Msg.T("slot", s.W, math.Hypot(s.B.X-s.A.X, s.B.Y-s.A.Y)+s.W))
+	p, q := s.A, s.B
+	if math.Hypot(start.X-q.X, start.Y-q.Y) < math.Hypot(start.X-p.X, start.Y-p.Y) {
+		p, q = q, p
+	}
+	var rings []float64 // tool-centre distances from the centreline
+	if wall := s.W/2 - cfg.ToolDia/2; wall > boreTol/2 {
+		step := cmp.Or(cfg.FillSpacing, cfg.ToolDia*0.8)
+		for d := wall; d > 0; d -= step {
+			rings = append([]float64{d}, rings...)
+		}
+	}
+	cw := cfg.Direction == "conventional"
+
+	hop(prog, p.X, p.Y, cfg)
+	for i, z := range passDepths(targetZ, cfg.StepDown) {
+		if i > 0 && len(rings) > 0 {
+			prog.FeedXY(p.X, p.Y, cfg.CutFeed) // back along the cleared floor
+		}
+		prog.FeedZ(z, cfg.PlungeFeed)
+		prog.FeedXY(q.X, q.Y, cfg.CutFeed)
+		p, q = q, p
+		// each loop from p's end: down one side, round q, back, round p
+		l := math.Hypot(q.X-p.X, q.Y-p.Y)
+		n := Point{X: -(q.Y - p.Y) / l, Y: (q.X - p.X) / l} // left of p→q
+		for _, d := range rings {
+			if cw {
+				d = -d // the other side first turns the loop round
+			}
+			p0, p1 := Point{X: p.X - n.X*d, Y: p.Y - n.Y*d}, Point{X: q.X - n.X*d, Y: q.Y - n.Y*d}
+			p2, p3 := Point{X: q.X + n.X*d, Y: q.Y + n.Y*d}, Point{X: p.X + n.X*d, Y: p.Y + n.Y*d}
+			prog.FeedXY(p0.X, p0.Y, cfg.CutFeed)
+			prog.FeedXY(p1.X, p1.Y, cfg.CutFeed)
+			prog.ArcXY(cw, p2.X, p2.Y, z, q.X, q.Y, cfg.CutFeed)
+			prog.FeedXY(p3.X, p3.Y, cfg.CutFeed)
+			prog.ArcXY(cw, p0.X, p0.Y, z, p.X, p.Y, cfg.CutFeed)
+		}
+	}
+	if len(rings) > 0 {
+		prog.FeedXY(p.X, p.Y, cfg.CutFeed) // off the wall before retracting
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// With -split-by the job is written as one program per tool, color or
+// operation instead of one long program, for machines without a tool
+// changer: each file is run on its own with the right cutter in. Files
+// are named after -out with the part's name added before the extension,
+// job.nc becoming job_T1.nc and job_T2.nc.
+
+// jobPart is the prepared paths of one output file.
+type jobPart struct {
+	Name  string
+	Paths []Path
+}
+
+// splitName is the part of the job a prepared path goes to. Tools are
+// numbered T1 for the end mill of -tooldia and T2 for the V-bit.
+func splitName(p Path, by string, cm ColorMap) string {
+	switch by {
+	case "tool":
+		if p.Depths != nil {
+			return "T2"
+		}
+		return "T1"
+	case "operation":
+		if p.Operation != "" {
+			return p.Operation
+		}
+		return operationOf(p, cm)
+	}
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, p.Stroke)
+	if name == "" {
+		return "nocolor"
+	}
+	return name
+}
+
+// splitJob prepares paths once and shares them out by part, keeping the
+// cutting order within each. Tools and operations come in their fixed
+// order, colors in the order they are first cut.
+func splitJob(paths []Path, by string, cfg Config) ([]jobPart, error) {
+	if cfg.CutDepth >= 0 {
+		return nil, fmt.Errorf("cut depth (cutz) must be negative, got %.3f", cfg.CutDepth)
+	}
+	paths, err := preparePaths(paths, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var parts []jobPart
+	for _, p := range paths {
+		name := splitName(p, by, cfg.ColorMap)
+		i := slices.IndexFunc(parts, func(jp jobPart) bool { return jp.Name == name })
+		if i < 0 {
+			i = len(parts)
+			parts = append(parts, jobPart{Name: name})
+		}
+		parts[i].Paths = append(parts[i].Paths, p)
+	}
+	var order []string
+	switch by {
+	case "tool":
+		order = []string{"T1", "T2"}
+	case "operation":
+		order = operationOrder
+	}
+	if order != nil {
+		slices.SortStableFunc(parts, func(a, b jobPart) int {
+			return slices.Index(order, a.Name) - slices.Index(order, b.Name)
+		})
+	}
+	return parts, nil
+}
+
+// splitFile names the file of one part: out with "_name" before its
+// extension.
+func splitFile(out, name string) string {
+	ext := filepath.Ext(out)
+	return strings.TrimSuffix(out, ext) + "_" + name + ext
+}
+
+// writeSplit writes one program per part next to out and returns the
+// files written. emit writes the planned moves of one part.
+func writeSplit(out, by string, paths []Path, cfg Config, emit func(io.Writer, []Move, Config) error) ([]string, error) {
+	parts, err := splitJob(paths, by, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, part := range parts {
+		body := newProgram(cfg.SafeZ)
+		planPaths(body, part.Paths, cfg)
+		body.Moves = slowCorners(body.Moves, cfg.CornerFeed, cfg.CornerAngle, cornerZone(cfg))
+		if err := checkLimits(body.Moves, cfg.Limits, cfg.Warn); err != nil {
+			return files, err
+		}
+		checkStock(body.Moves, cfg, cfg.Warn)
+		name := splitFile(out, part.Name)
+		err := writeFile(name, nil, cfg, func(w io.Writer, _ []Path, cfg Config) error {
+			return emit(w, body.Moves, cfg)
+		})
+		if err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
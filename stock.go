@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The stock is a block from the origin, W x H in X and Y, T thick below
+// Z0. Knowing it lets -cutz through cut just past the bottom, and shows
+// cuts that would miss the stock or run into the spoilboard.
+
+// Stock is the material being cut. Zero sizes are unknown.
+type Stock struct {
+	W, H float64 // extent in X and Y from the origin, mm
+}
+
+func (s Stock) known() bool { return s.W > 0 && s.H > 0 }
+
+// parseStock parses "WxHxT" (e.g. "300x200x18") into positive sizes.
+func parseStock(s string) (w, h, t float64, err error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "x")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("invalid stock %q (want WxHxT)", s)
+	}
+	var v [3]float64
+	for i, p := range parts {
+		v[i], err = strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || v[i] <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid stock %q (want positive WxHxT)", s)
+		}
+	}
+	return v[0], v[1], v[2], nil
+}
+
+// cutDepth resolves -cutz: a negative depth in mm, or "through" for the
+// stock thickness plus the breakthrough allowance.
+func cutDepth(s string, thickness, breakthrough float64) (float64, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "through") {
+		if thickness <= 0 {
+			return 0, fmt.Errorf("-cutz through needs -stock or -stock-thickness")
+		}
+		return -(thickness + breakthrough), nil
+	}
+	z, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid -cutz %q (must be a negative depth in mm, or through)", s)
+	}
+	return z, nil
+}
+
+// checkStock warns about cutting moves outside the -stock block: beyond
+// its edges in X and Y, or deeper than its thickness plus the
+// breakthrough allowance. Moves above Z0 are in the air and never count.
+func checkStock(moves []Move, cfg Config, warn *Warnings) {
+	if !cfg.Stock.known() {
+		return // -stock-thickness alone only sets depths
+	}
+	floor := -(cfg.StockThickness + cfg.Breakthrough)
+	found := map[int][]string{} // path index → first problem per side
+	seen := map[string]bool{}
+	var prev Move
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		from := prev
+		prev = m
+		if m.Kind == MoveRapid || m.Z >= 0 && from.Z >= 0 {
+			continue
+		}
+		report := func(side, what string) {
+			key := fmt.Sprintf("%d/%s", m.Path, side)
+			if !seen[key] {
+				seen[key] = true
+				found[m.Path] = append(found[m.Path], what)
+			}
+		}
+		x0, x1 := math.Min(from.X, m.X), math.Max(from.X, m.X)
+		y0, y1 := math.Min(from.Y, m.Y), math.Max(from.Y, m.Y)
+		if m.isArc() {
+			c, r, _ := arcGeometry(from, m) // conservative: the whole circle
+			x0, x1 = math.Min(x0, c.X-r), math.Max(x1, c.X+r)
+			y0, y1 = math.Min(y0, c.Y-r), math.Max(y1, c.Y+r)
+		}
+		const eps = 1e-6
+		if x0 < -eps {
+			report("-X", fmt.Sprintf("X%.3f left of the stock", x0))
+		}
+		if x1 > cfg.Stock.W+eps {
+			report("+X", fmt.Sprintf("X%.3f right of the stock (%.3f)", x1, cfg.Stock.W))
+		}
+		if y0 < -eps {
+			report("-Y", fmt.Sprintf("Y%.3f below the stock", y0))
+		}
+		if y1 > cfg.Stock.H+eps {
+			report("+Y", fmt.Sprintf("Y%.3f above the stock (%.3f)", y1, cfg.Stock.H))
+		}
+		if m.Z < floor-eps {
+			report("Z", fmt.Sprintf("Z%.3f through the stock and allowance (%.3f)", m.Z, floor))
+		}
+	}
+
+	idx := make([]int, 0, len(found))
+	for i := range found {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	for _, i := range idx {
+		warn.Add(WOutOfStock, i, "cuts outside the stock: %s", strings.Join(found[i], ", "))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// With -stroke-as-width a stroke wider than the tool is taken as the
+// width of the slot to cut: the tool runs the centreline and then loops
+// at ever larger offsets out to the stroke's edges, so the machined slot
+// is what the drawing shows. Narrower strokes are cut as lines as usual.
+
+// strokeWidth reads a stroke-width value in user units (plain or px) and
+// scales it by the element's transform, like dash lengths. Percentages
+// and other units give 0, as does a missing value.
+func strokeWidth(v string, t Transform) float64 {
+	v = strings.TrimSuffix(strings.TrimSpace(v), "px")
+	w, err := strconv.ParseFloat(v, 64)
+	if err != nil || w <= 0 {
+		return 0
+	}
+	return w * math.Sqrt(math.Abs(t.A*t.D-t.B*t.C))
+}
+
+// widePaths replaces every path whose stroke is wider than the tool with
+// the tool-centre paths that clear a slot of that width: the centreline
+// first, then loops around it stepping out by -stepover, the last
+// one a tool radius inside the stroke's edge. Like fills, the results are
+// at the tool centre already and are not compensated.
+func widePaths(paths []Path, cfg Config) []Path {
+	radius := cfg.ToolDia / 2
+	step := cmp.Or(cfg.FillSpacing, cfg.ToolDia*0.8)
+	out := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		edge := p.Width/2 - radius
+		if edge <= 1e-9 || p.Hole != nil || p.Slot != nil || p.Depths != nil || p.Filled || len(p.Points) < 2 {
+			out = append(out, p)
+			continue
+		}
+		// offsets from the outermost in, until the centreline is no
+		// further than a step away
+		var offsets []float64
+		for d := edge; d > 0; d -= step {
+			offsets = append(offsets, d)
+			if d <= step {
+				break
+			}
+		}
+		q := p
+		q.Dash = nil
+		q.Filled = true
+		out = append(out, q) // the centreline
+		for i := len(offsets) - 1; i >= 0; i-- {
+			for _, loop := range strokeOutline(p.Points, p.Closed, offsets[i]) {
+				q := q
+				q.Points = loop
+				q.Closed = true
+				out = append(out, q)
+			}
+		}
+	}
+	return out
+}
+
+// strokeOutline is the edge of the area within d of a polyline: for a
+// closed one the loops d outside and d inside it, for an open one a loop
+// round it with semicircular ends. Corners are rounded.
+func strokeOutline(pts []Point, closed bool, d float64) [][]Point {
+	round := Join{Style: "round"}
+	if closed {
+		loops := offsetContours(pts, d, "outside", round)
+		return append(loops, offsetContours(pts, d, "inside", round)...)
+	}
+	pts = dedupePoints(append([]Point(nil), pts...))
+	if len(pts) < 2 {
+		return nil
+	}
+	// down the left side, round the end, back up the other side and
+	// round the start
+	raw := sideOffset(pts, d)
+	raw = append(raw, sideOffset(reversePoints(pts), d)...)
+	raw = append(raw, raw[0])
+	loops, _ := regionLoops(raw, func(w int) bool { return w != 0 })
+
+	// the polyline as a closed polygon, there and back, for distances
+	back := append(append([]Point(nil), pts...), reversePoints(pts[1:len(pts)-1])...)
+	grid := newSegGrid(back)
+	tol := d*1e-3 + 0.01
+	var out [][]Point
+	for _, loop := range loops {
+		close := false
+		for _, q := range loop {
+			if close = grid.closerThan(q, d-tol); close {
+				break
+			}
+		}
+		if !close && len(loop) >= 4 {
+			out = append(out, loop) // not a leftover of an inside corner
+		}
+	}
+	return out
+}
+
+// sideOffset walks the left side of an open polyline at distance d, from
+// beside its first point to beside its last and on round that end. Outside
+// corners are rounded; at inside corners the offset edges are left to
+// cross, for regionLoops to sort out.
+func sideOffset(pts []Point, d float64) []Point {
+	n := len(pts) - 1 // segments
+	norms := make([]Point, n)
+	for i := range n {
+		e := Point{X: pts[i+1].X - pts[i].X, Y: pts[i+1].Y - pts[i].Y}
+		l := math.Hypot(e.X, e.Y)
+		norms[i] = Point{X: -e.Y / l, Y: e.X / l}
+	}
+	at := func(p, nv Point) Point { return Point{X: p.X + nv.X*d, Y: p.Y + nv.Y*d} }
+	out := []Point{at(pts[0], norms[0])}
+	for i := 1; i < n; i++ {
+		n0, n1 := norms[i-1], norms[i]
+		e1 := Point{X: n1.Y, Y: -n1.X} // direction of the next segment
+		if e1.X*n0.X+e1.Y*n0.Y < 0 {
+			// turning right: the left side is the outside
+			a0 := math.Atan2(n0.Y, n0.X)
+			sweep := math.Remainder(math.Atan2(n1.Y, n1.X)-a0, 2*math.Pi)
+			out = append(out, arcPoints(pts[i], d, a0, sweep, 0.01)...)
+		} else {
+			out = append(out, at(pts[i], n0), at(pts[i], n1))
+		}
+	}
+	// round the end, clockwise from the left side to the right
+	a0 := math.Atan2(norms[n-1].Y, norms[n-1].X)
+	return append(out, arcPoints(pts[n], d, a0, -math.Pi, 0.01)...)
+}
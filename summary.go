@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Summary is the machine-readable report written by -json-summary. Unlike
+// the manifest it describes the job rather than how it was made, so build
+// scripts can check a job (does it fit, did anything get dropped) without
+// parsing G-code.
+type Summary struct {
+	Input      string             `json:"input"`
+	Output     string             `json:"output,omitempty"`
+	Paths      int                `json:"paths"`      // paths read from the drawing
+	Operations []SummaryOperation `json:"operations"` // in order of first use
+	Bounds     SummaryBounds      `json:"bounds"`     // machine coordinates, mm
+	Estimate   ManifestEstimate   `json:"estimate"`   // same figures as -estimate
+	Warnings   []Warning          `json:"warnings"`   // everything reported, skipped elements included
+	Skipped    []Warning          `json:"skipped"`    // input the job leaves out
+}
+
+// SummaryOperation totals the motion of one operation ("cut", "score", ...).
+type SummaryOperation struct {
+	Op        string  `json:"op"`
+	Paths     int     `json:"paths"`
+	CutLength float64 `json:"cut_length_mm"`
+}
+
+type SummaryBounds struct {
+	MinX float64 `json:"min_x"`
+	MinY float64 `json:"min_y"`
+	MaxX float64 `json:"max_x"`
+	MaxY float64 `json:"max_y"`
+	MinZ float64 `json:"min_z"`
+}
+
+// skippedCodes are the warnings that mean part of the input was dropped.
+var skippedCodes = map[string]bool{
+	WUnsupportedCommand: true,
+	WNoFont:             true,
+}
+
+// buildSummary reports on a planned job. moves is the planned body and
+// paths the drawing as read.
+func buildSummary(inPath, outPath string, paths []Path, moves []Move, cfg Config) *Summary {
+	s := &Summary{
+		Input:    inPath,
+		Output:   outPath,
+		Paths:    len(paths),
+		Warnings: []Warning{},
+		Skipped:  []Warning{},
+	}
+	if cfg.Warn != nil {
+		for _, w := range cfg.Warn.List {
+			s.Warnings = append(s.Warnings, w)
+			if skippedCodes[w.Code] {
+				s.Skipped = append(s.Skipped, w)
+			}
+		}
+	}
+
+	est := estimateMoves(moves, cfg.RapidFeed)
+	s.Estimate = ManifestEstimate{
+		Paths:       est.Paths,
+		CutLength:   est.CutLength,
+		RapidLength: est.RapidLength,
+		Seconds:     est.Time.Seconds(),
+	}
+	s.Bounds = SummaryBounds{
+		MinX: est.Bounds.MinX, MinY: est.Bounds.MinY,
+		MaxX: est.Bounds.MaxX, MaxY: est.Bounds.MaxY,
+		MinZ: est.MinZ,
+	}
+
+	// per operation: distinct paths and length at feed
+	byOp := map[string]*SummaryOperation{}
+	opPaths := map[string]map[int]bool{}
+	var order []string
+	var prev Move
+	havePrev := false
+	for _, m := range moves {
+		if !m.isMotion() {
+			continue
+		}
+		if m.Op != "" {
+			op := byOp[m.Op]
+			if op == nil {
+				op = &SummaryOperation{Op: m.Op}
+				byOp[m.Op] = op
+				opPaths[m.Op] = map[int]bool{}
+				order = append(order, m.Op)
+			}
+			if havePrev && m.Kind != MoveRapid {
+				op.CutLength += moveLength(prev, m)
+			}
+			if m.Path > 0 && !opPaths[m.Op][m.Path] {
+				opPaths[m.Op][m.Path] = true
+				op.Paths++
+			}
+		}
+		prev, havePrev = m, true
+	}
+	s.Operations = []SummaryOperation{}
+	for _, name := range order {
+		s.Operations = append(s.Operations, *byOp[name])
+	}
+	return s
+}
+
+// writeSummary writes s as indented JSON.
+func writeSummary(w io.Writer, s *Summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"encoding/xml"
 	"errors"
 	"flag"
@@ -8,17 +12,19 @@ import (
 	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
+// normalizeColor returns a color as #rrggbb (see parseColor), so that
+// red, #f00 and rgb(255,0,0) compare equal. Values that are not colors,
+// such as none or url(#grad), come back lower-cased.
 func normalizeColor(c string) string {
 	s := strings.TrimSpace(strings.ToLower(c))
-	if s == "" {
-		return ""
-	}
-	if !strings.HasPrefix(s, "#") {
-		s = "#" + s
+	if hex, ok := parseColor(s); ok {
+		return hex
 	}
 	return s
 }
@@ -47,9 +53,36 @@ func extractStrokeColor(strokeAttr, styleAttr string) string {
 }
 
 type Path struct {
-	Points []Point
-	Closed bool
-	Stroke string
+	Points    []Point
+	Closed    bool
+	Stroke    string
+	Transform Transform // accumulated SVG transform applied to Points
+	Index     int       // 1-based position in the source document
+	Shape     int       // drawing element the path came from; subpaths of one <path> share it
+	Finish    bool      // full-depth finishing pass at the true profile
+	Label     string    // optional operator comment for this path
+	Hole      *Hole     // set when -bore recognised the path as a circle
+	Slot      *Slot     // set when -slots recognised the path as a slot
+	Depths    []float64 // per-point Z for variable-depth paths (v-carve), nil = pass depths
+	Feeds     []float64 // per-point feed of a path read from G-code, which is cut as posted
+	Power     []float64 // per-point laser S word of a -laser raster, for the move to the point; < 0 = rapid there
+
+	Dash       []float64 // stroke-dasharray in root units (mm after toMachine), nil = solid
+	DashOffset float64
+	Width      float64 // stroke-width in root units (mm after toMachine), 0 = not given
+
+	Fill     string // explicit fill color, "" = unfilled
+	FillRule string // "nonzero" or "evenodd"
+	Filled   bool   // generated by -fill-mode; already at the tool centre
+
+	CompSide string // "left" or "right" of travel for G41/G42 (controller compensation), "" = none
+	Comp     string // side the path was offset to: "inside"/"outside" of a loop after the hole flip, "left"/"right" of travel; "" = cut as drawn
+
+	Layer     string // Inkscape layer (or DXF layer) the path was drawn on, "" = none
+	Operation string // "engrave", "drill", "pocket" or "profile" when -operations groups the job
+
+	Override *DepthOverride // data-depth / data-passes from the drawing, nil = none
+	Image    *svgImage      // an <image>, for -heightmap and -laser; Points are its box corners
 }
 
 type svgRoot struct {
@@ -60,15 +93,69 @@ type svgRoot struct {
 }
 
 type svgPath struct {
-	D      string `xml:"d,attr"`
-	Stroke string `xml:"stroke,attr"`
-	Style  string `xml:"style,attr"`
+	ClipPath   string `xml:"clip-path,attr"`
+	Mask       string `xml:"mask,attr"`
+	DashArray  string `xml:"stroke-dasharray,attr"`
+	DashOffset string `xml:"stroke-dashoffset,attr"`
+	D          string `xml:"d,attr"`
+	Fill       string `xml:"fill,attr"`
+	FillRule   string `xml:"fill-rule,attr"`
+	Stroke     string `xml:"stroke,attr"`
+	Width      string `xml:"stroke-width,attr"`
+	Style      string `xml:"style,attr"`
+	Transform  string `xml:"transform,attr"`
 }
 
 type svgPolyLine struct {
-	Points string `xml:"points,attr"`
-	Stroke string `xml:"stroke,attr"`
-	Style  string `xml:"style,attr"`
+	ClipPath   string `xml:"clip-path,attr"`
+	Mask       string `xml:"mask,attr"`
+	DashArray  string `xml:"stroke-dasharray,attr"`
+	DashOffset string `xml:"stroke-dashoffset,attr"`
+	Points     string `xml:"points,attr"`
+	Fill       string `xml:"fill,attr"`
+	FillRule   string `xml:"fill-rule,attr"`
+	Stroke     string `xml:"stroke,attr"`
+	Width      string `xml:"stroke-width,attr"`
+	Style      string `xml:"style,attr"`
+	Transform  string `xml:"transform,attr"`
+}
+
+type svgCircle struct {
+	ClipPath   string  `xml:"clip-path,attr"`
+	Mask       string  `xml:"mask,attr"`
+	DashArray  string  `xml:"stroke-dasharray,attr"`
+	DashOffset string  `xml:"stroke-dashoffset,attr"`
+	CX         float64 `xml:"cx,attr"`
+	CY         float64 `xml:"cy,attr"`
+	R          float64 `xml:"r,attr"`
+	Fill       string  `xml:"fill,attr"`
+	FillRule   string  `xml:"fill-rule,attr"`
+	Stroke     string  `xml:"stroke,attr"`
+	Width      string  `xml:"stroke-width,attr"`
+	Style      string  `xml:"style,attr"`
+	Transform  string  `xml:"transform,attr"`
+}
+
+type svgText struct {
+	ClipPath   string     `xml:"clip-path,attr"`
+	Mask       string     `xml:"mask,attr"`
+	X          string     `xml:"x,attr"`
+	Y          string     `xml:"y,attr"`
+	FontSize   string     `xml:"font-size,attr"`
+	FontFamily string     `xml:"font-family,attr"`
+	TextAnchor string     `xml:"text-anchor,attr"`
+	Fill       string     `xml:"fill,attr"`
+	FillRule   string     `xml:"fill-rule,attr"`
+	Stroke     string     `xml:"stroke,attr"`
+	Style      string     `xml:"style,attr"`
+	Transform  string     `xml:"transform,attr"`
+	Content    string     `xml:",chardata"`
+	Spans      []svgTSpan `xml:"tspan"`
+}
+
+// svgTSpan contributes its text to the run; its own positioning is ignored.
+type svgTSpan struct {
+	Content string `xml:",chardata"`
 }
 
 type Config struct {
@@ -77,100 +164,1405 @@ type Config struct {
 	StepDown   float64
 	CutFeed    float64
 	PlungeFeed float64
-	Scale      float64
+	RapidFeed  float64 // assumed G0 rate for time estimates, mm/min
+
+	SpindleRPM float64 // M3 S word at the start, 0 = no spindle control
+	Flutes     int     // cutting edges, with ChipLoad
+	ChipLoad   float64 // mm per tooth; when set, CutFeed was derived from it
+	Material   string  // -material preset the defaults came from, "" = none
+
+	// RetractZ, when > 0, is the lower clearance height used between
+	// passes and for hops of at most RetractDist; SafeZ still starts and
+	// ends the job, precedes pauses and covers long rapids.
+	RetractZ    float64
+	RetractDist float64
+
+	FeedDepthFactor float64 // XY feed multiplier at full depth, 0 = constant feed
+	CornerFeed      float64 // feed multiplier around sharp corners, 0 = constant feed
+	CornerAngle     float64 // smallest turn, degrees, that counts as a sharp corner
+	Scale           float64
 
 	ToolDia           float64
-	Compensation      string // "none", "inside", "outside"
-	ConstructionColor string // normalized "#rrggbb", empty = disabled
+	Compensation      string        // "none", "inside", "outside", "left", "right"
+	CompMode          string        // "software" offsets the geometry, "controller" emits G41/G42
+	CompD             int           // tool table entry for the G41/G42 D word
+	CompLead          float64       // lead-in and lead-out length for controller compensation, mm
+	Dogbone           string        // inside-corner overcut: "" = off, "bisector" (dog-bone), "x" or "y" (T-bone)
+	Strict            bool          // features too small for the tool are errors, not warnings
+	Join              Join          // how compensated outlines turn outside corners
+	Direction         string        // "as-drawn", "climb", "conventional" (compensated paths)
+	FinishAllowance   float64       // radial stock left by roughing, mm; 0 = no finish pass
+	RestDia           float64       // tool that already cut the job, mm; 0 = no rest machining
+	FinishFeed        float64       // XY feed for the finish pass, 0 = CutFeed
+	SpringPass        bool          // repeat the final depth pass once
+	PingPong          bool          // alternate direction on open-path passes instead of retracting
+	PauseBetweenPaths bool          // stop (M0/M1) before every path after the first
+	OptionalStop      bool          // pauses use M1 instead of M0
+	CircleTol         float64       // how far a flattened circle may stray from a true one, mm
+	Slots             bool          // machine slot outlines, wall pairs and wide strokes as slots
+	Bore              bool          // helix-bore or peck-drill circles instead of profiling them
+	Order             Orderer       // cutting sequence; nil = document order
+	Operations        bool          // cut engraving, drilling, pockets and profiles in that order
+	Hooks             Hooks         // user G-code around the job and every path
+	Progress          string        // progress markers at every path: "none", "comment", "m117", "msg"
+	ResumeFrom        int           // skip the paths before this "Path N" comment number, 0 = cut everything
+	AirCut            float64       // trace the job this high above the stock before cutting it, mm; 0 = off
+	AirCutOnly        bool          // write only the air cut
+	TraceBounds       bool          // trace the job's XY extent at safe Z and stop before cutting
+	Entry             EntryStrategy // how each pass enters the material; nil = straight plunge
+	Fonts             *FontSet      // fonts for <text>; nil = text is skipped
+	Perforate         []float64     // dash pattern in mm for paths without stroke-dasharray
+	VCarve            bool          // carve closed outlines at variable depth with a V-bit
+	VBitAngle         float64       // included angle of the V-bit in degrees
+	VCarveStep        float64       // outline sampling distance for -vcarve, mm
+	ArrayCols         int           // -array copies of the job across; 0 = one
+	ArrayRows         int           // -array copies up; 0 = one
+	Spacing           float64       // gap between -array copies and -nest parts, mm
+	Heightmap         bool          // engrave <image> elements as reliefs, darker deeper
+	HeightmapStep     float64       // relief row and sample spacing, mm
+	HeightmapInvert   bool          // lighter deeper
+	Dither            string        // "none", "threshold", "ordered", "floyd-steinberg": reliefs and rasters in black and white
+	Laser             Laser         // laser mode: no Z, power in S words, rasters of images; zero = milling
+	FillMode          string        // "none", "hatch", "cross", "concentric": how filled shapes are cleared
+	FillSpacing       float64       // distance between fill lines, mm
+	FillAngle         float64       // hatch direction in degrees from +X
+	StrokeAsWidth     bool          // cut strokes wider than the tool as slots of their width
+	Trochoid          Trochoid      // trochoidal slotting; zero Width = off
+	Construction      Construction  // colors and layers that are never cut
+	OnlyColors        []string      // normalized colors of the only paths cut, nil = all
+	Simplify          float64       // RDP tolerance in mm, 0 = disabled
+	Units             string        // output units: "mm" or "inch"; input is always mm
+	WCS               string        // work coordinate system word, e.g. "G55"; "" = don't emit
+	ChainTol          float64       // endpoint join tolerance in mm, 0 = disabled
+	Origin            string        // "svg", "lower-left", "upper-left", "center"
+	Mirror            string        // "none", "x", "y"
+	Rotate            int           // degrees counter-clockwise: 0, 90, 180, 270
+	FitW, FitH        float64       // fit job inside this envelope in mm, 0 = off
+	OffsetX, OffsetY  float64       // final shift of the whole job in mm
+	TwoSided          TwoSided      // which side of a two-sided job to cut; zero = one-sided
 
 	SvgWidth  float64
 	SvgHeight float64
+	FlipY     bool // mirror Y about SvgHeight so the origin is bottom-left
+
+	SizeFromExtents bool // SvgWidth/SvgHeight were guessed from geometry
+
+	StockThickness float64  // mm, 0 = unknown
+	Stock          Stock    // XY extent of the stock from the origin; zero = unknown
+	Breakthrough   float64  // how far -cutz through goes below the stock, mm
+	ColorMap       ColorMap // per-color operations; nil = cut everything
+
+	Probe     Probe  // touch-plate probing preamble
+	Limits    Limits // machine envelope; zero values disable checks
+	GrblHints bool   // emit suggested GRBL settings as comments
+
+	Precision   int  // decimal places in the output, 0 = 3 for mm, 4 for inch
+	Modal       bool // omit repeated motion words and feeds
+	LineNumbers bool // N words on every block
+	Checksums   bool // *NN checksums after every block; implies LineNumbers
+
+	Warn *Warnings // collects non-fatal problems; nil discards them
+	Msg  Messages  // operator-facing comment text; nil = English
 }
 
 func main() {
-	inPath := flag.String("in", "", "input SVG file")
+	inPath := flag.String("in", "", "input SVG or DXF file, or a glob pattern; more files may follow the flags")
+	outDir := flag.String("outdir", "", "directory for one program per input file")
+	combine := flag.String("combine", "", "also write all inputs into this one program, stopping for a tool change between files")
+	inFormat := flag.String("informat", "auto", "input format: svg, dxf, gcode, or auto (by file extension)")
 	outPath := flag.String("out", "", "output G-code file (default: stdout)")
+	serveAddr := flag.String("serve", "", "run a web UI on this address, e.g. :8080, instead of converting -in")
+	sendPort := flag.String("send", "", "stream the program to a GRBL controller on this serial port, e.g. /dev/ttyUSB0")
+	baud := flag.Int("baud", 115200, "serial baud rate for -send")
+	outFormat := flag.String("outformat", "gcode", "output format: gcode, hpgl for vinyl cutters and pen plotters, or svg or dxf for the toolpath geometry")
 	safeZ := flag.Float64("safez", 5.0, "safe Z height (mm)")
-	cutZ := flag.Float64("cutz", -1.0, "target cut depth (negative, mm)")
-	stepDown := flag.Float64("stepdown", 0.0, "step-down per pass (mm, positive). If 0, do it in a single pass")
+	retractZ := flag.Float64("retract-z", 0.0,
+		"lower clearance height for hops between passes and nearby paths (mm above stock, 0 = always -safez)")
+	retractDist := flag.Float64("retract-dist", 25.0, "longest rapid made at -retract-z; longer hops go up to -safez (mm)")
+	cutZFlag := flag.String("cutz", "-1.0", `target cut depth (negative, mm), or "through" for the stock thickness plus -breakthrough`)
+	stepDownFlag := flag.String("stepdown", "0",
+		"step-down per pass: mm, or N% of -tooldia (e.g. 50%). If 0, do it in a single pass")
 	feed := flag.Float64("feed", 300.0, "XY cutting feed rate (mm/min)")
+	rpm := flag.Float64("rpm", 0, "spindle speed; starts the spindle with M3 S<rpm> after the preamble (0 = leave the spindle alone)")
+	flutes := flag.Int("flutes", 0, "number of cutting edges on the tool, for -chipload")
+	chipLoad := flag.Float64("chipload", 0, "chip load per tooth in mm; sets -feed to rpm x flutes x chipload")
+	material := flag.String("material", "",
+		"preset stepdown, feed, plunge and rpm for the -tooldia: plywood, mdf, acrylic, aluminum, brass (explicit flags win)")
+	feedDepthFactor := flag.Float64("feed-depth-factor", 0.0,
+		"XY feed multiplier reached at full depth, scaled linearly per pass (e.g. 0.6; 0 = constant feed)")
+	cornerFeedFactor := flag.Float64("corner-feed-factor", 0.0,
+		"XY feed multiplier for one tool diameter either side of sharp corners (e.g. 0.5; 0 = constant feed)")
+	cornerAngle := flag.Float64("corner-angle", 60.0,
+		"smallest change of direction in degrees that -corner-feed-factor slows down for")
+	rapidFeed := flag.Float64("rapid-feed", 1000.0, "machine rapid (G0) rate in mm/min, used for time estimates")
+	estimate := flag.Bool("estimate", false, "print cut length, time and extent instead of G-code")
+	manifest := flag.Bool("manifest", false, "write a JSON manifest (input hash, flags, tools, estimate, warnings) to <out>.json")
+	jsonSummary := flag.String("json-summary", "",
+		"also write a JSON job summary (operations, bounds, estimate, warnings, skipped input) to this file, or - for stdout")
 	plunge := flag.Float64("plunge", 120.0, "Z plunge feed rate (mm/min)")
 	scale := flag.Float64("scale", 1.0, "coordinate scale factor (SVG units → mm)")
-	comp := flag.String("comp", "none", "cutter compensation: none, inside, outside (closed paths only)")
+	comp := flag.String("comp", "none", "cutter compensation: none, inside, outside (closed paths), left, right (side of travel)")
+	compMode := flag.String("comp-mode", "software",
+		"how -comp is applied: software (offset the toolpath) or controller (G41/G42 on the drawn path, radius from the tool table)")
+	compD := flag.Int("comp-d", 1, "tool table entry for the G41/G42 D word with -comp-mode controller")
+	dogbone := flag.Bool("dogbone", false, "overcut inside corners of compensated outlines along the bisector so square parts fit")
+	tbone := flag.String("tbone", "", "overcut inside corners along an axis instead: x or y")
+	strict := flag.Bool("strict", false, "fail instead of warning when compensation finds features too small for the tool")
+	join := flag.String("join", "miter", "how compensated outlines turn outside corners: miter, round, arc (round, cut as G2/G3), bevel")
+	miterLimit := flag.Float64("miter-limit", 4, "cut a miter off square beyond this many tool radii from the corner (0 = never)")
+	flipY := flag.String("flip-y", "auto",
+		"flip the Y axis so SVG top-left becomes machine bottom-left: auto, yes, no (auto = flip when the viewBox height is known)")
+	origin := flag.String("origin", "svg",
+		"work origin: svg (page corner), lower-left, upper-left, center (of the geometry bounding box)")
+	mirror := flag.String("mirror", "none", "mirror the job: none, x (left-right), y (top-bottom)")
+	rotate := flag.Int("rotate", 0, "rotate the job counter-clockwise: 0, 90, 180, 270 degrees")
+	offsetX := flag.Float64("offset-x", 0.0, "shift the whole job along X (mm)")
+	offsetY := flag.Float64("offset-y", 0.0, "shift the whole job along Y (mm)")
+	array := flag.String("array", "", "repeat the job in a grid of CxR copies (e.g. 3x2), -spacing apart")
+	spacing := flag.Float64("spacing", 5, "gap in mm between -array copies and -nest parts")
+	side := flag.String("side", "", "cut one side of a two-sided job on the -stock: front, or back (mirrored about -flip-axis)")
+	backSide := flag.String("back-side", "back", "comma-separated colors and layers cut from the back with -side (as for -construction)")
+	flipAxis := flag.String("flip-axis", "x", "how the stock is turned over for -side back: x (left to right), y (top to bottom)")
+	pinDia := flag.Float64("pin-dia", 0, "drill two alignment pin holes of this diameter in mm on the flip axis with -side front (0 = none)")
+	pinInset := flag.Float64("pin-inset", 10, "distance of the -pin-dia holes from the stock edges, mm")
+	nest := flag.String("nest", "", "lay several inputs out side by side on a WxH mm sheet (e.g. 600x400); needs -combine or -outdir")
+	fit := flag.String("fit", "", "uniformly scale the job to fit inside WxH mm (e.g. 280x180)")
+	chain := flag.Float64("chain", 0.0, "join open paths whose ends meet within this distance in mm (0 = off)")
+	direction := flag.String("direction", "as-drawn",
+		"cut direction for compensated paths: as-drawn, climb, conventional (assumes M3/clockwise spindle)")
+	finishAllowance := flag.Float64("finish-allowance", 0.0,
+		"radial stock (mm) roughing leaves on compensated paths for a final finish pass (0 = off)")
+	restDia := flag.Float64("rest-previous-dia", 0.0,
+		"diameter of the larger tool that already cut the job: cut only what it could not reach (0 = off)")
+	finishFeed := flag.Float64("finish-feed", 0.0, "XY feed for the finish pass (mm/min, 0 = -feed)")
+	springPass := flag.Bool("spring-pass", false, "repeat the final full-depth pass once to clean up tool deflection")
+	pingPong := flag.Bool("pingpong", true,
+		"cut open paths back and forth on successive depth passes instead of retracting to the start")
+	pauseBetween := flag.Bool("pause-between-paths", false, "stop the program before every path after the first")
+	optionalStop := flag.Bool("optional-stop", false, "use M1 (optional stop) instead of M0 for pauses")
+	entry := flag.String("entry", "straight", "how passes enter the material: "+strings.Join(entryNames, ", "))
+	rampAngle := flag.Float64("ramp-angle", 3, "descent angle in degrees for -entry ramp and helix")
+	leadIn := flag.Float64("lead-in", 0, "lead-in length in mm for -entry lead-in and -comp-mode controller (0 = tool diameter)")
+	fontPath := flag.String("font", "", "TrueType/OpenType font for <text> whose font-family is not found")
+	textMode := flag.String("text-mode", "outline", "how <text> is cut: outline (font glyph outlines) or stroke (single-stroke Hershey)")
+	textFont := flag.String("text-font", "futural", "Hershey font for -text-mode stroke: a .jhf file, or a name looked up as NAME.jhf in -font-dir")
+	fontDir := flag.String("font-dir", "", "directory of fonts matched to <text> by font-family name, and of .jhf Hershey fonts")
+	vcarve := flag.Bool("vcarve", false, "carve closed outlines with a V-bit, depth following the local width (max depth -cutz)")
+	vbitAngle := flag.Float64("vbit-angle", 60, "included angle of the V-bit in degrees for -vcarve")
+	vcarveStep := flag.Float64("vcarve-step", 0.2, "outline sampling distance in mm for -vcarve")
+	heightmap := flag.Bool("heightmap", false, "engrave <image> elements (PNG, JPEG) as reliefs: black at -cutz, white at the stock top")
+	heightmapStep := flag.Float64("heightmap-step", 0.2, "row and sample spacing in mm for -heightmap")
+	heightmapInvert := flag.Bool("heightmap-invert", false, "cut lighter pixels deeper with -heightmap")
+	ditherMode := flag.String("dither", "none",
+		"black-and-white -heightmap reliefs and -laser rasters for machines without depth or power modulation: none, threshold, ordered, floyd-steinberg")
+	laser := flag.Bool("laser", false,
+		"plan for a laser: no Z moves, M4 dynamic power, vectors cut at -laser-power and <image> elements raster-engraved")
+	laserPower := flag.Float64("laser-power", 1000, "S word for full laser power, used for vectors and black pixels (GRBL $30)")
+	laserMinPower := flag.Float64("laser-min-power", 0, "S word for the lightest shade -laser engraves; white stays off")
+	laserDPI := flag.Float64("laser-dpi", 254, "raster lines and samples per inch for -laser images (254 = 0.1 mm line interval)")
+	overscan := flag.Float64("overscan", 3, "mm the head runs on, unlit, past both ends of a -laser raster line to burn at a steady speed")
+	laserUnidirectional := flag.Bool("laser-unidirectional", false, "scan every -laser raster line the same way instead of back and forth")
+	fillMode := flag.String("fill-mode", "none",
+		"engrave filled, unstroked shapes by clearing their area: none, hatch, cross, concentric")
+	fillSpacing := flag.Float64("fill-spacing", 0, "distance between fill lines in mm (0 = 80% of -tooldia)")
+	stepOverFlag := flag.String("stepover", "", "distance between fill lines: mm, or N% of -tooldia (e.g. 40%); same as -fill-spacing")
+	fillAngle := flag.Float64("fill-angle", 45, "hatch line direction in degrees for -fill-mode hatch and cross")
+	strokeAsWidth := flag.Bool("stroke-as-width", false,
+		"cut strokes wider than the tool as slots as wide as the stroke, stepping out by -stepover (needs -tooldia)")
+	trochoidal := flag.Bool("trochoidal", false,
+		"cut slots and fill lines with small circular loops instead of full-width moves (needs -tooldia)")
+	trochWidth := flag.String("troch-width", "150%", "slot width for -trochoidal: mm, or N% of -tooldia")
+	trochStep := flag.String("troch-step", "10%", "advance per loop for -trochoidal: mm, or N% of -tooldia")
+	perforate := flag.String("perforate", "", "cut every undashed path as a perforation: LENGTH,GAP in mm (more pairs allowed)")
+	order := flag.String("order", "document",
+		"path cutting order: document, nearest, inside-first, per-part")
+	operations := flag.Bool("operations", false,
+		"group paths into operations and cut engraving, then drilling, pockets and profiles last (layer names or -colormap operation= assign them)")
+	keepDirection := flag.Bool("keep-direction", false,
+		"never cut open paths end to start when ordering (drag knives, brushing passes)")
+	bore := flag.Bool("bore", false,
+		"helix-bore circles larger than the tool and peck-drill tool-sized ones (needs -tooldia)")
+	circleTol := flag.Float64("circle-tol", 0.05,
+		"how far in mm a flattened path may stray from a true circle and still count as one for -bore")
+	slots := flag.Bool("slots", false,
+		"cut slot outlines, parallel wall pairs and wide straight strokes as slots: one pass if tool-wide, cleared otherwise (needs -tooldia)")
 	toolDia := flag.Float64("tooldia", 0.0, "tool diameter in mm (required for inside/outside compensation)")
 	construction := flag.String("construction", "#0000ff",
-		"hex color (e.g. #0000ff) for construction geometry to ignore; empty or 'none' to disable")
+		"comma-separated colors (e.g. #0000ff or blue) and layer names of construction geometry to ignore; empty or 'none' to disable")
+	onlyColors := flag.String("only-colors", "", "comma-separated colors; cut only the paths stroked (or, unstroked, filled) in them")
+	maxX := flag.Float64("max-x", 0.0, "machine X travel limit in mm (0 = unchecked)")
+	maxY := flag.Float64("max-y", 0.0, "machine Y travel limit in mm (0 = unchecked)")
+	minZ := flag.Float64("min-z", 0.0, "lowest allowed Z in mm, negative (0 = unchecked)")
+	softLimits := flag.String("soft-limits", "error", "what to do when the job exceeds -max-x/-max-y/-min-z: error, warn")
+	verifyCmd := flag.String("verify-cmd", "",
+		"shell command that receives the program on stdin; a non-zero exit aborts and is passed through")
+	stockThickness := flag.Float64("stock-thickness", 0.0, "stock thickness in mm (needed for percentage depths)")
+	stockFlag := flag.String("stock", "", "stock size WxHxT in mm from the origin (e.g. 300x200x18): sets -stock-thickness and warns about cuts outside it")
+	breakthrough := flag.Float64("breakthrough", 0.3, "how far -cutz through cuts below the stock, mm")
+	colorMap := flag.String("colormap", "",
+		"per-color operations, e.g. '#00ff00:op=score,depth=30%; #ff0000:depth=-3'")
+	units := flag.String("units", "mm", "output units: mm (G21) or inch (G20); flag values stay in mm")
+	precision := flag.Int("precision", 0, "decimal places for coordinates and feeds (0 = 3 for mm, 4 for inch)")
+	modal := flag.Bool("modal", false, "leave out motion words (G0/G1/...) and feeds that repeat the previous block")
+	lineNumbers := flag.Bool("line-numbers", false, "number every G-code block with an N word")
+	checksums := flag.Bool("checksums", false, "append a *NN checksum to every block, Marlin style (implies -line-numbers)")
+	grblHintsFlag := flag.Bool("grbl-hints", false, "add suggested GRBL acceleration/junction settings for the job as comments")
+	probe := flag.Bool("probe", false, "start with a G38.2 touch-plate probe that sets work Z zero")
+	probeThickness := flag.Float64("probe-thickness", 0.0, "touch plate thickness (mm)")
+	probeFeed := flag.Float64("probe-feed", 50.0, "probing feed (mm/min)")
+	probeTravel := flag.Float64("probe-travel", 25.0, "maximum probing travel downward (mm)")
+	probeRetract := flag.Float64("probe-retract", 2.0, "lift after the probe touches (mm)")
+	probeZero := flag.String("probe-zero", "g10", "how to set Z zero after probing: g10 (G10 L20 P0) or g92")
+	wcs := flag.String("wcs", "", "work coordinate system to select in the preamble: G54-G59 (default: leave as is)")
+	airCut := flag.Float64("aircut", 0, "trace the whole job this many mm above the stock first, then stop before cutting (0 = off)")
+	airCutOnly := flag.Bool("aircut-only", false, "write only the -aircut trace, no cutting")
+	traceBoundsFlag := flag.Bool("trace-bounds", false, "start by tracing the job's XY extent at safe Z, then stop so the stock placement can be checked")
+	resumeFrom := flag.Int("resume-from", 0, `start an interrupted job again at the path with this "Path N" comment`)
+	splitBy := flag.String("split-by", "", "write one program per tool, color or operation next to -out (out_T1.nc, ...) instead of one")
+	progress := flag.String("progress", "none",
+		"mark each path with the job's progress: none, comment, m117 (Marlin display), msg (LinuxCNC (MSG, ...))")
+	preJob := flag.String("pre-job", "", `G-code to run before the job starts ("\n" separates lines, @FILE reads a file)`)
+	postJob := flag.String("post-job", "", "G-code to run after the spindle stops at the end of the job")
+	prePath := flag.String("pre-path", "", "G-code to run before moving to each path (e.g. M8 for air assist)")
+	postPath := flag.String("post-path", "", "G-code to run after each path, with the tool lifted out of the cut (e.g. M9)")
+	messages := flag.String("messages", "", "JSON message catalog for G-code comments (localization)")
+	simplify := flag.Float64("simplify", 0.0, "path simplification tolerance in mm (Douglas-Peucker, 0 = off)")
+
+	kerfTest := flag.String("kerf-test", "",
+		"instead of reading an SVG, generate a kerf test coupon for nominal kerfs min:max:step (mm)")
+	kerfSize := flag.Float64("kerf-size", 10.0, "nominal peg/hole size for -kerf-test (mm)")
 
 	flag.Parse()
 
-	if *inPath == "" {
-		fmt.Fprintln(os.Stderr, "error: -in SVG file is required")
+	setFlags := map[string]bool{} // flags given on the command line
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	stepDown, err := toolRelative("-stepdown", *stepDownFlag, *toolDia)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	var stock Stock
+	if *stockFlag != "" {
+		var t float64
+		if stock.W, stock.H, t, err = parseStock(*stockFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "error: -stock: %v\n", err)
+			os.Exit(1)
+		}
+		if setFlags["stock-thickness"] && *stockThickness != t {
+			fmt.Fprintln(os.Stderr, "error: -stock and -stock-thickness give different thicknesses")
+			os.Exit(1)
+		}
+		*stockThickness = t
+	}
+	if *breakthrough < 0 {
+		fmt.Fprintln(os.Stderr, "error: -breakthrough must be >= 0")
+		os.Exit(1)
+	}
+	cutZ, err := cutDepth(*cutZFlag, *stockThickness, *breakthrough)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if *stepOverFlag != "" {
+		if *fillSpacing != 0 {
+			fmt.Fprintln(os.Stderr, "error: give -stepover or -fill-spacing, not both")
+			os.Exit(1)
+		}
+		if *fillSpacing, err = toolRelative("-stepover", *stepOverFlag, *toolDia); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if *fillSpacing == 0 {
+			fmt.Fprintln(os.Stderr, "error: -stepover must be > 0")
+			os.Exit(1)
+		}
+	}
+
+	if *kerfTest != "" {
+		kerfs, err := parseKerfRange(*kerfTest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -kerf-test: %v\n", err)
+			os.Exit(1)
+		}
+		if *kerfSize <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -kerf-size must be > 0")
+			os.Exit(1)
+		}
+		cfg := Config{
+			SafeZ:        *safeZ,
+			CutDepth:     cutZ,
+			StepDown:     stepDown,
+			CutFeed:      *feed,
+			PlungeFeed:   *plunge,
+			Scale:        1,
+			Compensation: "none", // each shape is already offset by its kerf
+			Units:        "mm",
+			Warn:         &Warnings{},
+		}
+		if strings.EqualFold(*units, "inch") || strings.EqualFold(*units, "in") {
+			cfg.Units = "inch"
+		}
+		out := os.Stdout
+		if *outPath != "" && *outPath != "-" {
+			f, err := os.Create(*outPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := writeGcode(out, kerfCoupon(kerfs, *kerfSize), cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing G-code: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	inputs, err := expandInputs(*inPath, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: -in: %v\n", err)
+		os.Exit(1)
+	}
+	if len(inputs) == 0 && *serveAddr == "" {
+		if !stdinPiped() {
+			fmt.Fprintln(os.Stderr, "error: -in SVG file is required (or pipe one to stdin)")
+			os.Exit(1)
+		}
+		inputs = []string{"-"}
+	}
+	batch := len(inputs) > 1 || *outDir != "" || *combine != ""
+	if batch && *outDir == "" && *combine == "" {
+		fmt.Fprintln(os.Stderr, "error: several input files need -outdir and/or -combine")
 		os.Exit(1)
 	}
 
-	svgFile, err := os.Open(*inPath)
+	warn := &Warnings{}
+	fonts, err := loadFonts(*fontPath, *fontDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error opening SVG: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error loading fonts: %v\n", err)
+		os.Exit(1)
+	}
+	switch strings.ToLower(*textMode) {
+	case "outline":
+	case "stroke":
+		if fonts.Stroke, err = loadHershey(*textFont, *fontDir); err != nil {
+			fmt.Fprintf(os.Stderr, "error loading Hershey font: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -text-mode %q (must be outline, stroke)\n", *textMode)
+		os.Exit(1)
+	}
+	var paths []Path
+	var w, h float64
+	var format string
+	sizeGuessed := false
+	if *serveAddr == "" && !batch {
+		*inPath = inputs[0]
+		paths, w, h, format, err = readInput(*inPath, *inFormat, fonts, warn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		w, h, sizeGuessed = documentSize(paths, w, h, warn)
+	}
+
+	var msg Messages
+	if *messages != "" {
+		msg, err = loadMessages(*messages)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading messages: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *retractZ < 0 || (*retractZ > 0 && *retractZ >= *safeZ) {
+		fmt.Fprintf(os.Stderr, "error: invalid -retract-z %.3f (must be above the stock, Z > 0, and below -safez)\n", *retractZ)
+		os.Exit(1)
+	}
+	if *retractDist < 0 {
+		fmt.Fprintln(os.Stderr, "error: -retract-dist must be >= 0")
+		os.Exit(1)
+	}
+
+	cfg := Config{
+		SafeZ:        *safeZ,
+		RetractZ:     *retractZ,
+		RetractDist:  *retractDist,
+		CutDepth:     cutZ,
+		StepDown:     stepDown,
+		CutFeed:      *feed,
+		PlungeFeed:   *plunge,
+		RapidFeed:    *rapidFeed,
+		Scale:        *scale,
+		ToolDia:      *toolDia,
+		Compensation: strings.ToLower(*comp),
+		CompMode:     strings.ToLower(*compMode),
+		CompD:        *compD,
+		Strict:       *strict,
+		Simplify:     *simplify,
+		Units:        strings.ToLower(*units),
+		Direction:    strings.ToLower(*direction),
+		FinishFeed:   *finishFeed,
+		SpringPass:   *springPass,
+		PingPong:     *pingPong,
+
+		PauseBetweenPaths: *pauseBetween,
+		OptionalStop:      *optionalStop,
+		Bore:              *bore,
+		CircleTol:         *circleTol,
+		Slots:             *slots,
+		VCarve:            *vcarve,
+		VBitAngle:         *vbitAngle,
+		VCarveStep:        *vcarveStep,
+		Heightmap:         *heightmap,
+		HeightmapStep:     *heightmapStep,
+		HeightmapInvert:   *heightmapInvert,
+		Dither:            strings.ToLower(*ditherMode),
+		FillMode:          strings.ToLower(*fillMode),
+		FillSpacing:       *fillSpacing,
+		FillAngle:         *fillAngle,
+		StrokeAsWidth:     *strokeAsWidth,
+		ChainTol:          *chain,
+		Origin:            strings.ToLower(*origin),
+		Mirror:            strings.ToLower(*mirror),
+		Rotate:            *rotate,
+		OffsetX:           *offsetX,
+		OffsetY:           *offsetY,
+		SvgWidth:          w,
+		SvgHeight:         h,
+
+		SizeFromExtents: sizeGuessed,
+		Fonts:           fonts,
+		Warn:            warn,
+		Msg:             msg,
+	}
+
+	if *feedDepthFactor < 0 || *feedDepthFactor > 1 {
+		fmt.Fprintln(os.Stderr, "error: -feed-depth-factor must be between 0 and 1")
+		os.Exit(1)
+	}
+	cfg.FeedDepthFactor = *feedDepthFactor
+	if *cornerFeedFactor < 0 || *cornerFeedFactor > 1 {
+		fmt.Fprintln(os.Stderr, "error: -corner-feed-factor must be between 0 and 1")
+		os.Exit(1)
+	}
+	if *cornerAngle <= 0 || *cornerAngle > 180 {
+		fmt.Fprintln(os.Stderr, "error: -corner-angle must be between 0 and 180 degrees")
+		os.Exit(1)
+	}
+	cfg.CornerFeed, cfg.CornerAngle = *cornerFeedFactor, *cornerAngle
+
+	// A material preset fills in whatever was not given explicitly.
+	var mat *Material
+	if *material != "" {
+		m, err := lookupMaterial(*material)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if *toolDia <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -material scales its feeds to the tool: it needs -tooldia")
+			os.Exit(1)
+		}
+		mat = &m
+		cfg.Material = strings.ToLower(*material)
+		if !setFlags["rpm"] {
+			*rpm = m.RPM
+		}
+		if !setFlags["flutes"] {
+			*flutes = 2
+		}
+		if !setFlags["chipload"] && !setFlags["feed"] {
+			*chipLoad = m.chipLoad(*toolDia)
+		}
+		if !setFlags["stepdown"] {
+			cfg.StepDown = m.stepDown(*toolDia)
+		}
+	}
+	if err := checkChipLoad(*rpm, *flutes, *chipLoad); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.SpindleRPM = *rpm
+	if *chipLoad > 0 {
+		if setFlags["feed"] {
+			warn.Add(WIgnoredOption, 0, "-feed is replaced by the feed computed from -chipload")
+		}
+		cfg.Flutes, cfg.ChipLoad = *flutes, *chipLoad
+		cfg.CutFeed = chipLoadFeed(*rpm, *flutes, *chipLoad)
+	}
+	if mat != nil && !setFlags["plunge"] {
+		cfg.PlungeFeed = cfg.CutFeed * mat.PlungeRatio
+	}
+	if *wcs != "" {
+		cfg.WCS, err = parseWCS(*wcs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -wcs: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	cfg.StockThickness = *stockThickness
+	cfg.Stock = stock
+	cfg.Breakthrough = *breakthrough
+	cfg.GrblHints = *grblHintsFlag
+	if *precision < 0 || *precision > 8 {
+		fmt.Fprintf(os.Stderr, "error: invalid -precision %d (must be 0-8, 0 = by units)\n", *precision)
+		os.Exit(1)
+	}
+	cfg.Precision = *precision
+	cfg.Modal = *modal
+	cfg.LineNumbers = *lineNumbers
+	cfg.Checksums = *checksums
+	if *colorMap != "" {
+		cfg.ColorMap, err = parseColorMap(*colorMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -colormap: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.ColorMap.usesStock() && cfg.StockThickness <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -colormap uses a percentage depth; -stock-thickness is required")
+			os.Exit(1)
+		}
+	}
+
+	cfg.Probe = Probe{
+		Enabled:   *probe,
+		Thickness: *probeThickness,
+		Feed:      *probeFeed,
+		MaxTravel: *probeTravel,
+		Retract:   *probeRetract,
+		Zero:      strings.ToLower(*probeZero),
+	}
+	if cfg.Probe.Enabled {
+		if cfg.Probe.Feed <= 0 || cfg.Probe.MaxTravel <= 0 || cfg.Probe.Thickness < 0 || cfg.Probe.Retract < 0 {
+			fmt.Fprintln(os.Stderr, "error: -probe-feed and -probe-travel must be > 0, -probe-thickness and -probe-retract >= 0")
+			os.Exit(1)
+		}
+		if cfg.Probe.Zero != "g10" && cfg.Probe.Zero != "g92" {
+			fmt.Fprintf(os.Stderr, "error: invalid -probe-zero %q (must be g10, g92)\n", *probeZero)
+			os.Exit(1)
+		}
+	}
+
+	cfg.Limits = Limits{MaxX: *maxX, MaxY: *maxY, MinZ: *minZ}
+	switch strings.ToLower(*softLimits) {
+	case "error", "":
+	case "warn":
+		cfg.Limits.Soft = true
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -soft-limits %q (must be error, warn)\n", *softLimits)
+		os.Exit(1)
+	}
+
+	if *fit != "" {
+		cfg.FitW, cfg.FitH, err = parseSize(*fit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -fit: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *array != "" {
+		cfg.ArrayCols, cfg.ArrayRows, err = parseGrid(*array)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -array: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *spacing < 0 {
+		fmt.Fprintln(os.Stderr, "error: -spacing must be >= 0")
+		os.Exit(1)
+	}
+	cfg.Spacing = *spacing
+	switch s := strings.ToLower(*side); s {
+	case "":
+	case "front", "back":
+		cfg.TwoSided = TwoSided{Side: s, Axis: strings.ToLower(*flipAxis), PinDia: *pinDia, PinInset: *pinInset}
+		if !cfg.Stock.known() {
+			fmt.Fprintln(os.Stderr, "error: -side flips the job about the middle of the stock; give -stock")
+			os.Exit(1)
+		}
+		if cfg.TwoSided.Axis != "x" && cfg.TwoSided.Axis != "y" {
+			fmt.Fprintf(os.Stderr, "error: invalid -flip-axis %q (must be x, y)\n", *flipAxis)
+			os.Exit(1)
+		}
+		if cfg.TwoSided.Back, err = parseConstruction(*backSide); err != nil {
+			fmt.Fprintf(os.Stderr, "error: -back-side: %v\n", err)
+			os.Exit(1)
+		}
+		if *pinDia > 0 {
+			if *pinDia < cfg.ToolDia-boreTol {
+				fmt.Fprintf(os.Stderr, "error: -pin-dia %.3f is smaller than the %.3f mm tool\n", *pinDia, cfg.ToolDia)
+				os.Exit(1)
+			}
+			if s == "back" {
+				warn.Add(WIgnoredOption, 0, "-pin-dia: the pins are drilled with -side front")
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -side %q (must be front, back)\n", *side)
+		os.Exit(1)
+	}
+	var nestW, nestH float64
+	if *nest != "" {
+		if !batch {
+			fmt.Fprintln(os.Stderr, "error: -nest lays out several inputs; use -array for copies of one")
+			os.Exit(1)
+		}
+		if nestW, nestH, err = parseSize(*nest); err != nil {
+			fmt.Fprintf(os.Stderr, "error: -nest: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *serveAddr != "" || batch {
+		_, err = resolveFlipY(*flipY, 1, "svg", nil) // applied to each input
+	} else {
+		cfg.FlipY, err = resolveFlipY(*flipY, h, format, warn)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.Construction, err = parseConstruction(*construction); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.OnlyColors, err = parseColorList(*onlyColors); err != nil {
+		fmt.Fprintf(os.Stderr, "error: -only-colors: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch cfg.Compensation {
+	case "none", "":
+		cfg.Compensation = "none"
+	case "inside", "outside", "left", "right":
+		if cfg.ToolDia <= 0 {
+			fmt.Fprintf(os.Stderr, "error: -tooldia must be > 0 when -comp is %s\n", cfg.Compensation)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -comp %q (must be none, inside, outside, left, right)\n", *comp)
+		os.Exit(1)
+	}
+
+	if *perforate != "" {
+		dash, err := parseDashList(*perforate)
+		if err != nil || dash == nil {
+			fmt.Fprintf(os.Stderr, "error: invalid -perforate %q (want LENGTH,GAP in mm)\n", *perforate)
+			os.Exit(1)
+		}
+		cfg.Perforate = dash
+	}
+
+	o, err := parseOrder(*order)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if n, ok := o.(nearestOrder); ok {
+		n.KeepDirection = *keepDirection
+		o = n
+	}
+	cfg.Order = o
+	cfg.Operations = *operations || cfg.ColorMap.usesOperations()
+
+	switch p := strings.ToLower(*progress); p {
+	case "none", "comment", "m117", "msg":
+		cfg.Progress = p
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -progress %q (must be none, comment, m117, msg)\n", *progress)
+		os.Exit(1)
+	}
+	for _, h := range []struct {
+		name string
+		val  string
+		dst  *string
+	}{
+		{"pre-job", *preJob, &cfg.Hooks.PreJob},
+		{"post-job", *postJob, &cfg.Hooks.PostJob},
+		{"pre-path", *prePath, &cfg.Hooks.PrePath},
+		{"post-path", *postPath, &cfg.Hooks.PostPath},
+	} {
+		snippet, err := parseSnippet(h.val)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -%s: %v\n", h.name, err)
+			os.Exit(1)
+		}
+		*h.dst = snippet
+	}
+
+	en, err := parseEntry(*entry, *rampAngle, *leadIn, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Entry = en
+
+	if cfg.VCarve {
+		if cfg.VBitAngle <= 0 || cfg.VBitAngle >= 180 {
+			fmt.Fprintln(os.Stderr, "error: -vbit-angle must be in (0,180)")
+			os.Exit(1)
+		}
+		if cfg.VCarveStep <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -vcarve-step must be > 0")
+			os.Exit(1)
+		}
+		if cfg.Compensation != "none" {
+			warn.Add(WIgnoredOption, 0, "-comp does not apply to v-carved outlines")
+		}
+	}
+
+	if *laser {
+		l := Laser{Power: *laserPower, MinPower: *laserMinPower, Overscan: *overscan, Unidirectional: *laserUnidirectional}
+		if err := enableLaser(&cfg, l, *laserDPI, *outFormat, setFlags); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		*airCut, *airCutOnly = 0, false
+		paths = loadImages(paths, filepath.Dir(*inPath), warn)
+	} else if setFlags["laser-power"] || setFlags["laser-min-power"] || setFlags["laser-dpi"] || setFlags["overscan"] || *laserUnidirectional {
+		warn.Add(WIgnoredOption, 0, "-laser-power, -laser-min-power, -laser-dpi, -overscan and -laser-unidirectional only apply with -laser")
+	}
+
+	if cfg.Heightmap {
+		if cfg.HeightmapStep <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -heightmap-step must be > 0")
+			os.Exit(1)
+		}
+		paths = loadImages(paths, filepath.Dir(*inPath), warn)
+	}
+	switch cfg.Dither {
+	case "none":
+	case "threshold", "ordered", "floyd-steinberg":
+		if !cfg.Heightmap && !cfg.Laser.Enabled {
+			warn.Add(WIgnoredOption, 0, "-dither only applies with -heightmap or -laser")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -dither %q (must be none, threshold, ordered, floyd-steinberg)\n", cfg.Dither)
+		os.Exit(1)
+	}
+
+	switch cfg.FillMode {
+	case "none":
+	case "hatch", "cross", "concentric":
+		if cfg.FillSpacing < 0 {
+			fmt.Fprintln(os.Stderr, "error: -fill-spacing must be >= 0")
+			os.Exit(1)
+		}
+		if cfg.FillSpacing == 0 {
+			if cfg.ToolDia <= 0 {
+				fmt.Fprintln(os.Stderr, "error: -fill-mode needs -fill-spacing or -tooldia")
+				os.Exit(1)
+			}
+			cfg.FillSpacing = cfg.ToolDia * 0.8
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -fill-mode %q (must be none, hatch, cross, concentric)\n", *fillMode)
+		os.Exit(1)
+	}
+
+	if cfg.StrokeAsWidth && cfg.ToolDia <= 0 {
+		fmt.Fprintln(os.Stderr, "error: -stroke-as-width needs -tooldia to tell wide strokes from lines")
+		os.Exit(1)
+	}
+
+	if *trochoidal {
+		if cfg.ToolDia <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -trochoidal needs -tooldia")
+			os.Exit(1)
+		}
+		w, err := toolRelative("-troch-width", *trochWidth, cfg.ToolDia)
+		if err == nil && w <= cfg.ToolDia {
+			err = fmt.Errorf("-troch-width %s must be wider than the tool", *trochWidth)
+		}
+		step, serr := toolRelative("-troch-step", *trochStep, cfg.ToolDia)
+		if err == nil && (serr != nil || step <= 0) {
+			err = fmt.Errorf("-troch-step %s must be > 0", *trochStep)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Trochoid = Trochoid{Width: w, Step: step}
+	}
+
+	if cfg.Bore && cfg.ToolDia <= 0 {
+		fmt.Fprintln(os.Stderr, "error: -bore needs -tooldia")
+		os.Exit(1)
+	}
+	if cfg.Slots && cfg.ToolDia <= 0 {
+		fmt.Fprintln(os.Stderr, "error: -slots needs -tooldia")
+		os.Exit(1)
+	}
+	if cfg.CircleTol <= 0 {
+		fmt.Fprintln(os.Stderr, "error: -circle-tol must be > 0")
+		os.Exit(1)
+	}
+
+	if *finishAllowance < 0 {
+		fmt.Fprintln(os.Stderr, "error: -finish-allowance must be >= 0")
+		os.Exit(1)
+	}
+	cfg.FinishAllowance = *finishAllowance
+	if cfg.FinishAllowance > 0 && cfg.Compensation == "none" {
+		warn.Add(WIgnoredOption, 0, "-finish-allowance has no effect without -comp inside/outside")
+	}
+	if *restDia != 0 {
+		if *restDia <= cfg.ToolDia || cfg.ToolDia <= 0 {
+			fmt.Fprintln(os.Stderr, "error: -rest-previous-dia must be larger than -tooldia")
+			os.Exit(1)
+		}
+		if cfg.Compensation == "none" {
+			warn.Add(WIgnoredOption, 0, "-rest-previous-dia has no effect without -comp inside/outside")
+		}
+		cfg.RestDia = *restDia
+	}
+
+	switch j := strings.ToLower(*join); j {
+	case "miter", "round", "arc", "bevel":
+		if *miterLimit != 0 && *miterLimit < 1 {
+			fmt.Fprintln(os.Stderr, "error: -miter-limit must be 0 or >= 1")
+			os.Exit(1)
+		}
+		cfg.Join = Join{Style: j, Limit: *miterLimit}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -join %q (must be miter, round, arc, bevel)\n", *join)
+		os.Exit(1)
+	}
+
+	switch strings.ToLower(*tbone) {
+	case "":
+		if *dogbone {
+			cfg.Dogbone = "bisector"
+		}
+	case "x", "y":
+		cfg.Dogbone = strings.ToLower(*tbone)
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -tbone %q (must be x, y)\n", *tbone)
+		os.Exit(1)
+	}
+	if cfg.Dogbone != "" && cfg.Compensation == "none" {
+		warn.Add(WIgnoredOption, 0, "-dogbone and -tbone have no effect without -comp")
+		cfg.Dogbone = ""
+	}
+
+	switch cfg.CompMode {
+	case "software", "":
+		cfg.CompMode = "software"
+	case "controller":
+		if cfg.Compensation == "none" {
+			fmt.Fprintln(os.Stderr, "error: -comp-mode controller needs -comp")
+			os.Exit(1)
+		}
+		if cfg.FinishAllowance > 0 || cfg.RestDia > 0 {
+			fmt.Fprintln(os.Stderr, "error: -finish-allowance and -rest-previous-dia need -comp-mode software")
+			os.Exit(1)
+		}
+		if cfg.CompD < 0 {
+			fmt.Fprintln(os.Stderr, "error: -comp-d must be >= 0")
+			os.Exit(1)
+		}
+		if setFlags["entry"] {
+			warn.Add(WIgnoredOption, 0, "-entry does not apply with -comp-mode controller; passes plunge at the lead-in")
+		}
+		if cfg.Dogbone != "" {
+			fmt.Fprintln(os.Stderr, "error: -dogbone and -tbone need -comp-mode software")
+			os.Exit(1)
+		}
+		// the lead-in must be longer than the radius for the controller
+		// to take up the offset
+		cfg.CompLead = *leadIn
+		if cfg.CompLead <= 0 {
+			cfg.CompLead = cfg.ToolDia
+		}
+		if cfg.CompLead <= cfg.ToolDia/2 {
+			fmt.Fprintln(os.Stderr, "error: -lead-in must be longer than the tool radius with -comp-mode controller")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -comp-mode %q (must be software, controller)\n", *compMode)
+		os.Exit(1)
+	}
+
+	switch cfg.Units {
+	case "mm", "":
+		cfg.Units = "mm"
+	case "inch", "in":
+		cfg.Units = "inch"
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -units %q (must be mm, inch)\n", *units)
+		os.Exit(1)
+	}
+
+	switch cfg.Direction {
+	case "as-drawn", "":
+		cfg.Direction = "as-drawn"
+	case "climb", "conventional":
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -direction %q (must be as-drawn, climb, conventional)\n", *direction)
 		os.Exit(1)
 	}
-	defer svgFile.Close()
 
-	paths, w, h, err := parseSVG(svgFile)
+	if *serveAddr != "" {
+		if err := serve(*serveAddr, cfg, *flipY); err != nil {
+			fmt.Fprintf(os.Stderr, "error: -serve: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	write := writeGcode
+	emit := emitProgram
+	switch strings.ToLower(*outFormat) {
+	case "gcode":
+	case "hpgl":
+		write = writeHPGL
+		emit = func(w io.Writer, moves []Move, _ Config) error { return emitHPGL(w, moves) }
+		if cfg.Units == "inch" {
+			warn.Add(WIgnoredOption, 0, "-units does not apply to HP-GL output")
+		}
+		if cfg.Probe.Enabled || cfg.GrblHints || cfg.SpindleRPM > 0 {
+			warn.Add(WIgnoredOption, 0, "-probe, -rpm and -grbl-hints do not apply to HP-GL output")
+		}
+		if cfg.LineNumbers || cfg.Checksums || cfg.Modal || cfg.Precision > 0 {
+			warn.Add(WIgnoredOption, 0, "-precision, -modal, -line-numbers and -checksums do not apply to HP-GL output")
+		}
+		if cfg.Progress != "none" {
+			warn.Add(WIgnoredOption, 0, "-progress does not apply to HP-GL output")
+		}
+		if cfg.AirCut > 0 || cfg.TraceBounds {
+			warn.Add(WIgnoredOption, 0, "-aircut and -trace-bounds do not apply to HP-GL output")
+		}
+		if cfg.Hooks != (Hooks{}) {
+			warn.Add(WIgnoredOption, 0, "-pre-job, -post-job, -pre-path and -post-path do not apply to HP-GL output")
+		}
+		if cfg.CompMode == "controller" {
+			fmt.Fprintln(os.Stderr, "error: -comp-mode controller emits G41/G42; it needs -outformat gcode")
+			os.Exit(1)
+		}
+	case "svg", "dxf":
+		write, emit = writeSVGPaths, nil
+		if strings.ToLower(*outFormat) == "dxf" {
+			write = writeDXFPaths
+		}
+		if cfg.Units == "inch" {
+			warn.Add(WIgnoredOption, 0, "-units does not apply to %s output, which is in mm", *outFormat)
+		}
+		if cfg.Probe.Enabled || cfg.GrblHints || cfg.Progress != "none" || cfg.AirCut > 0 || cfg.TraceBounds || cfg.Hooks != (Hooks{}) ||
+			cfg.LineNumbers || cfg.Checksums || cfg.Modal || cfg.Precision > 0 {
+			warn.Add(WIgnoredOption, 0, "program options such as -probe, -progress, -aircut and -line-numbers do not apply to %s output", *outFormat)
+		}
+		if cfg.CompMode == "controller" {
+			fmt.Fprintln(os.Stderr, "error: -comp-mode controller leaves the offset to the controller; it needs -outformat gcode")
+			os.Exit(1)
+		}
+		if *splitBy != "" {
+			fmt.Fprintln(os.Stderr, "error: -split-by writes one program per part; it needs -outformat gcode or hpgl")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -outformat %q (must be gcode, hpgl, svg, dxf)\n", *outFormat)
+		os.Exit(1)
+	}
+	if *sendPort != "" && strings.ToLower(*outFormat) != "gcode" {
+		fmt.Fprintf(os.Stderr, "error: -send streams G-code; it cannot be combined with -outformat %s\n", *outFormat)
+		os.Exit(1)
+	}
+	if *sendPort != "" && cfg.Checksums {
+		fmt.Fprintln(os.Stderr, "error: -checksums: GRBL rejects checksummed lines; leave it off with -send")
+		os.Exit(1)
+	}
+
+	if *airCut < 0 {
+		fmt.Fprintln(os.Stderr, "error: -aircut is a height above the stock; it must be > 0")
+		os.Exit(1)
+	}
+	if *airCutOnly && *airCut == 0 {
+		fmt.Fprintln(os.Stderr, "error: -aircut-only needs -aircut to give the height")
+		os.Exit(1)
+	}
+	cfg.AirCut, cfg.AirCutOnly = *airCut, *airCutOnly
+	cfg.TraceBounds = *traceBoundsFlag
+	if *resumeFrom < 0 {
+		fmt.Fprintln(os.Stderr, "error: -resume-from must be a path number from the program's \"Path N\" comments")
+		os.Exit(1)
+	}
+	cfg.ResumeFrom = *resumeFrom
+	if cfg.ResumeFrom > 0 && (batch || *splitBy != "") {
+		fmt.Fprintln(os.Stderr, "error: -resume-from restarts one program; it cannot be combined with -split-by or several inputs")
+		os.Exit(1)
+	}
+	switch *splitBy {
+	case "", "tool", "color", "operation":
+	default:
+		fmt.Fprintf(os.Stderr, "error: invalid -split-by %q (must be tool, color, operation)\n", *splitBy)
+		os.Exit(1)
+	}
+	if *splitBy != "" && (batch || *sendPort != "" || *verifyCmd != "" || *estimate || *manifest) {
+		fmt.Fprintln(os.Stderr, "error: -split-by writes several files; it cannot be combined with -send, -verify-cmd, -estimate, -manifest or several inputs")
+		os.Exit(1)
+	}
+	if *splitBy != "" && (*outPath == "" || *outPath == "-") {
+		fmt.Fprintln(os.Stderr, "error: -split-by needs -out to name the files")
+		os.Exit(1)
+	}
+
+	if batch {
+		if *sendPort != "" || *verifyCmd != "" || *estimate || *manifest || *jsonSummary != "" || *outPath != "" {
+			fmt.Fprintln(os.Stderr, "error: -out, -send, -verify-cmd, -estimate, -manifest and -json-summary take a single input file")
+			os.Exit(1)
+		}
+		if *combine != "" && strings.ToLower(*outFormat) != "gcode" {
+			fmt.Fprintf(os.Stderr, "error: -combine writes G-code; it cannot be combined with -outformat %s\n", *outFormat)
+			os.Exit(1)
+		}
+		ext := ".nc"
+		switch f := strings.ToLower(*outFormat); f {
+		case "hpgl":
+			ext = ".plt"
+		case "svg", "dxf":
+			ext = "." + f
+		}
+		b := Batch{
+			Inputs: inputs, InFormat: *inFormat, FlipY: *flipY,
+			OutDir: *outDir, Ext: ext, Write: write, Combined: *combine,
+			NestW: nestW, NestH: nestH,
+		}
+		if err := b.Run(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// openOutput is called only once there is something worth writing.
+	openOutput := func() io.Writer {
+		if *outPath == "" || *outPath == "-" {
+			return os.Stdout
+		}
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		return f
+	}
+
+	if *manifest && (*outPath == "" || *outPath == "-") {
+		fmt.Fprintln(os.Stderr, "error: -manifest needs -out to name the G-code file")
+		os.Exit(1)
+	}
+	if *manifest && *inPath == "-" {
+		fmt.Fprintln(os.Stderr, "error: -manifest records the input file's hash; it cannot read stdin")
+		os.Exit(1)
+	}
+	// saveManifest writes <out>.json once the G-code is safely written.
+	saveManifest := func(outSum []byte) {
+		if !*manifest {
+			return
+		}
+		quiet := cfg
+		quiet.Warn = nil // warnings were already collected by the real run
+		body, err := planJob(paths, quiet)
+		if err == nil {
+			var m *Manifest
+			if m, err = buildManifest(*inPath, *outPath, outSum, body.Moves, cfg); err == nil {
+				err = writeManifest(*outPath+".json", m)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing manifest: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	toStdout := (*outPath == "" || *outPath == "-") && (*sendPort == "" || *outPath != "")
+	if *jsonSummary == "-" && toStdout {
+		fmt.Fprintln(os.Stderr, "error: -json-summary - needs -out (or -send) to keep the program off stdout")
+		os.Exit(1)
+	}
+	// saveSummary writes the -json-summary report once the job is out.
+	saveSummary := func() {
+		if *jsonSummary == "" {
+			return
+		}
+		quiet := cfg
+		quiet.Warn = nil
+		body, err := planJob(paths, quiet)
+		if err == nil {
+			f := os.Stdout
+			if *jsonSummary != "-" {
+				f, err = os.Create(*jsonSummary)
+			}
+			if err == nil {
+				err = writeSummary(f, buildSummary(*inPath, *outPath, paths, body.Moves, cfg))
+				if f != os.Stdout {
+					if cerr := f.Close(); err == nil {
+						err = cerr
+					}
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing summary: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *splitBy != "" {
+		if _, err := writeSplit(*outPath, *splitBy, paths, cfg, emit); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing G-code: %v\n", err)
+			os.Exit(1)
+		}
+		saveSummary()
+		return
+	}
+
+	if *estimate {
+		body, err := planJob(paths, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		out := openOutput()
+		defer closeOutput(out)
+		fmt.Fprint(out, estimateMoves(body.Moves, cfg.RapidFeed))
+		saveSummary()
+		return
+	}
+
+	if *verifyCmd == "" && *sendPort == "" {
+		out := openOutput()
+		defer closeOutput(out)
+		sum := sha256.New()
+		if err := write(io.MultiWriter(out, sum), paths, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing G-code: %v\n", err)
+			os.Exit(1)
+		}
+		saveManifest(sum.Sum(nil))
+		saveSummary()
+		return
+	}
+
+	// Verify before writing or sending, so a rejected program never
+	// reaches the output or the machine.
+	var buf bytes.Buffer
+	if err := write(&buf, paths, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing G-code: %v\n", err)
+		os.Exit(1)
+	}
+	if *verifyCmd != "" {
+		code, err := runVerifier(*verifyCmd, buf.Bytes())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if code != 0 {
+			fmt.Fprintf(os.Stderr, "error: verifier rejected the program (exit status %d)\n", code)
+			os.Exit(code)
+		}
+	}
+	if *sendPort == "" || *outPath != "" {
+		// when sending, keep a copy only if asked to
+		out := openOutput()
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing G-code: %v\n", err)
+			os.Exit(1)
+		}
+		closeOutput(out)
+		sum := sha256.Sum256(buf.Bytes())
+		saveManifest(sum[:])
+	}
+	saveSummary()
+	if *sendPort != "" {
+		if err := sendGRBL(*sendPort, *baud, buf.Bytes(), cfg.SafeZ); err != nil {
+			fmt.Fprintf(os.Stderr, "error: -send: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// toolRelative parses a length flag given in mm or as a percentage of
+// the tool diameter ("50%"), the way machinists state step-down and
+// stepover. Negative lengths are rejected.
+func toolRelative(name, val string, toolDia float64) (float64, error) {
+	val = strings.TrimSpace(val)
+	if pct, ok := strings.CutSuffix(val, "%"); ok {
+		v, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil || v <= 0 {
+			return 0, fmt.Errorf("invalid %s %q (percentage must be > 0)", name, val)
+		}
+		if toolDia <= 0 {
+			return 0, fmt.Errorf("%s %s is relative to the tool: it needs -tooldia", name, val)
+		}
+		return toolDia * v / 100, nil
+	}
+	v, err := strconv.ParseFloat(val, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid %s %q (must be mm >= 0 or N%% of -tooldia)", name, val)
+	}
+	return v, nil
+}
+
+// resolveFlipY applies -flip-y to a document of height h.
+func resolveFlipY(mode string, h float64, format string, warn *Warnings) (bool, error) {
+	switch strings.ToLower(mode) {
+	case "auto", "":
+		if format == "dxf" || format == "gcode" {
+			return false, nil // DXF and G-code are Y up already
+		}
+		if h <= 0 {
+			warn.Add(WUnknownHeight, 0, "SVG height unknown (no viewBox); Y axis not flipped, use -flip-y yes to force")
+		}
+		return h > 0, nil
+	case "yes", "true", "on":
+		if h <= 0 {
+			warn.Add(WUnknownHeight, 0, "SVG height unknown (no viewBox); flipping about Y=0")
+		}
+		return true, nil
+	case "no", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid -flip-y %q (must be auto, yes, no)", mode)
+}
+
+// loadInput parses one drawing from r and completes cfg for it: document
+// size, whether Y is flipped. informat and flipY are the -informat and
+// -flip-y modes; name picks the format when informat is "auto". Linked
+// <image> files are read from dir, "" = none.
+func loadInput(r io.Reader, name, dir string, cfg Config, informat, flipY string) ([]Path, Config, error) {
+	paths, w, h, format, err := parseInput(r, name, informat, cfg.Fonts, cfg.Warn)
+	if err != nil {
+		return nil, cfg, err
+	}
+	if cfg.Heightmap || cfg.Laser.Enabled {
+		paths = loadImages(paths, dir, cfg.Warn)
+	}
+	w, h, cfg.SizeFromExtents = documentSize(paths, w, h, cfg.Warn)
+	cfg.SvgWidth, cfg.SvgHeight = w, h
+	if cfg.FlipY, err = resolveFlipY(flipY, h, format, cfg.Warn); err != nil {
+		return nil, cfg, err
+	}
+	if format == "gcode" {
+		// a program is in cutting order already, and its runs stay apart
+		cfg.Order, cfg.ChainTol = nil, 0
+	}
+	return paths, cfg, nil
+}
+
+// readInput opens and parses a drawing. format is "svg", "dxf", or
+// "auto" to go by the file extension; the format used is returned.
+func readInput(name, format string, fonts *FontSet, warn *Warnings) (paths []Path, w, h float64, used string, err error) {
+	f, err := openInput(name)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error parsing SVG: %v\n", err)
-		os.Exit(1)
+		return nil, 0, 0, "", err
 	}
-	if len(paths) == 0 {
-		fmt.Fprintln(os.Stderr, "warning: no paths / polylines / polygons found")
+	defer f.Close()
+	return parseInput(f, name, format, fonts, warn)
+}
+
+// openInput opens an input file; "-" is standard input.
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
 	}
+	return os.Open(name)
+}
 
-	var out io.Writer = os.Stdout
-	if *outPath != "" && *outPath != "-" {
-		f, err := os.Create(*outPath)
+// stdinPiped reports whether standard input is a pipe or file rather than
+// a terminal, i.e. whether a drawing could be waiting on it.
+func stdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
+}
+
+// parseInput parses a drawing read from r, which may be gzip-compressed
+// (.svgz). With format "auto" the format is picked by name's extension,
+// or for other names (such as "-") by the content.
+func parseInput(r io.Reader, name, format string, fonts *FontSet, warn *Warnings) (paths []Path, w, h float64, used string, err error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
-			os.Exit(1)
+			return nil, 0, 0, "", err
 		}
-		defer f.Close()
-		out = f
+		defer zr.Close()
+		br = bufio.NewReader(zr)
 	}
-
-	cfg := Config{
-		SafeZ:        *safeZ,
-		CutDepth:     *cutZ,
-		StepDown:     *stepDown,
-		CutFeed:      *feed,
-		PlungeFeed:   *plunge,
-		Scale:        *scale,
-		ToolDia:      *toolDia,
-		Compensation: strings.ToLower(*comp),
-		SvgWidth:     w,
-		SvgHeight:    h,
+	r = br
+
+	used = strings.ToLower(format)
+	if used == "auto" {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".dxf":
+			used = "dxf"
+		case ".svg", ".svgz":
+			used = "svg"
+		case ".nc", ".ngc", ".gcode", ".gc", ".tap":
+			used = "gcode"
+		default:
+			// SVG starts with markup; ASCII DXF with a group code;
+			// G-code with a block, a comment or a % tape mark
+			used = "svg"
+			head, _ := br.Peek(256)
+			if t := bytes.TrimLeft(head, " \t\r\n\ufeff"); len(t) > 0 {
+				switch c := t[0] | 0x20; {
+				case t[0] >= '0' && t[0] <= '9':
+					used = "dxf"
+				case c == 'g' || c == 'n' || c == 'm' || t[0] == '%' || t[0] == '(' || t[0] == ';':
+					used = "gcode"
+				}
+			}
+		}
 	}
-
-	cc := strings.TrimSpace(*construction)
-	if strings.EqualFold(cc, "none") || cc == "" {
-		cc = ""
-	} else {
-		cc = normalizeColor(cc)
+	switch used {
+	case "svg":
+		paths, w, h, err = parseSVG(r, fonts, warn)
+	case "dxf":
+		paths, w, h, err = parseDXF(r, warn)
+	case "gcode":
+		paths, w, h, err = parseGcode(r, warn)
+	default:
+		return nil, 0, 0, "", fmt.Errorf("invalid -informat %q (must be auto, svg, dxf, gcode)", format)
+	}
+	if err != nil {
+		return nil, 0, 0, "", fmt.Errorf("parsing %s: %w", strings.ToUpper(used), err)
 	}
+	if len(paths) == 0 {
+		warn.Add(WNoGeometry, 0, "no paths / polylines / polygons found")
+	}
+	return paths, w, h, used, nil
+}
 
-	switch cfg.Compensation {
-	case "none", "":
-		cfg.Compensation = "none"
-	case "inside", "outside":
-		if cfg.ToolDia <= 0 {
-			fmt.Fprintln(os.Stderr, "error: -tooldia must be > 0 when -comp is inside or outside")
-			os.Exit(1)
-		}
-	default:
-		fmt.Fprintf(os.Stderr, "error: invalid -comp %q (must be none, inside, outside)\n", *comp)
-		os.Exit(1)
+// documentSize fills in a missing document width or height: without a
+// usable viewBox the page is assumed to run from the origin to the far
+// corner of the drawing.
+func documentSize(paths []Path, w, h float64, warn *Warnings) (float64, float64, bool) {
+	if w > 0 && h > 0 {
+		return w, h, false
+	}
+	b, ok := pathBounds(paths)
+	if !ok {
+		return w, h, false
 	}
+	if w <= 0 {
+		w = math.Max(b.MaxX, 0)
+	}
+	if h <= 0 {
+		h = math.Max(b.MaxY, 0)
+	}
+	warn.Add(WNoViewBox, 0, "no viewBox; assuming document size %.3f x %.3f from geometry extents", w, h)
+	return w, h, true
+}
 
-	if err := writeGcode(out, paths, cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "error writing G-code: %v\n", err)
-		os.Exit(1)
+func closeOutput(w io.Writer) {
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		f.Close()
 	}
 }
 
@@ -205,23 +1597,56 @@ func parsePointsList(s string) ([]Point, error) {
 	return pts, nil
 }
 
+// subpath is one M-started run of a path's data.
+type subpath struct {
+	Points []Point
+	Closed bool
+}
+
 // parseSimplePath parses a very limited subset of SVG path syntax:
-// commands: M/m, L/l, H/h, V/v, Z/z, C/c.
-func parseSimplePath(d string) ([]Point, bool, error) {
+// commands: M/m, L/l, H/h, V/v, Z/z, C/c. Each moveto, and each drawing
+// command after a closepath, starts a new subpath.
+func parseSimplePath(d string) ([]subpath, error) {
 	tokens := tokenizePathData(d)
 	if len(tokens) == 0 {
-		return nil, false, nil
+		return nil, nil
 	}
 
+	var subs []subpath
 	var pts []Point
 	var cur Point
-	var start Point
+	var start Point // start of the current subpath, target of Z
 	var cmd rune
 	closed := false
 	i := 0
 
+	flush := func() {
+		if len(pts) > 1 {
+			subs = append(subs, subpath{Points: pts, Closed: closed})
+		}
+		pts, closed = nil, false
+	}
+
 	flatness := 0.1 // mm tolerance for curve flattening
 
+	// Every command ends up here, so the current point and subpath
+	// start are tracked the same way for lines and curves.
+	moveTo := func(p Point) {
+		flush()
+		cur = p
+		start = p
+		pts = append(pts, p)
+	}
+	lineTo := func(p Point) {
+		if len(pts) == 0 || closed {
+			// drawing on after Z starts a new subpath at the old start;
+			// without any M at all this keeps the subpath start honest
+			moveTo(cur)
+		}
+		cur = p
+		pts = append(pts, p)
+	}
+
 	for i < len(tokens) {
 		tok := tokens[i]
 
@@ -229,40 +1654,38 @@ func parseSimplePath(d string) ([]Point, bool, error) {
 			cmd = rune(tok[0])
 			i++
 			if cmd == 'Z' || cmd == 'z' {
-				if len(pts) > 0 {
-					pts = append(pts, start)
+				if len(pts) > 0 && !closed {
+					lineTo(start)
 					closed = true
 				}
-				continue
 			}
 			continue
 		}
 
 		if cmd == 0 {
-			return nil, false, errors.New("path data must start with a command (M/m)")
+			return nil, errors.New("path data must start with a command (M/m)")
 		}
 
 		switch cmd {
 		case 'M', 'm', 'L', 'l':
 			if i+1 >= len(tokens) {
-				return nil, false, errors.New("odd number of coordinates after M/L")
+				return nil, errors.New("odd number of coordinates after M/L")
 			}
 			x, err1 := strconv.ParseFloat(tokens[i], 64)
 			y, err2 := strconv.ParseFloat(tokens[i+1], 64)
 			if err1 != nil || err2 != nil {
-				return nil, false, fmt.Errorf("invalid coordinate pair %q,%q", tokens[i], tokens[i+1])
+				return nil, fmt.Errorf("invalid coordinate pair %q,%q", tokens[i], tokens[i+1])
 			}
 
+			p := Point{X: x, Y: y}
 			if cmd == 'm' || cmd == 'l' {
-				cur = Point{X: cur.X + x, Y: cur.Y + y}
-			} else {
-				cur = Point{X: x, Y: y}
+				p = Point{X: cur.X + x, Y: cur.Y + y}
 			}
-
-			if len(pts) == 0 {
-				start = cur
+			if cmd == 'M' || cmd == 'm' {
+				moveTo(p)
+			} else {
+				lineTo(p)
 			}
-			pts = append(pts, cur)
 			i += 2
 
 			// per SVG spec, subsequent coords after first M are treated as L
@@ -275,40 +1698,32 @@ func parseSimplePath(d string) ([]Point, bool, error) {
 		case 'H', 'h':
 			x, err := strconv.ParseFloat(tokens[i], 64)
 			if err != nil {
-				return nil, false, fmt.Errorf("invalid H coordinate %q", tokens[i])
+				return nil, fmt.Errorf("invalid H coordinate %q", tokens[i])
 			}
+			p := Point{X: x, Y: cur.Y}
 			if cmd == 'h' {
-				cur.X += x
-			} else {
-				cur.X = x
-			}
-			if len(pts) == 0 {
-				start = cur
+				p.X = cur.X + x
 			}
-			pts = append(pts, cur)
+			lineTo(p)
 			i++
 
 		case 'V', 'v':
 			y, err := strconv.ParseFloat(tokens[i], 64)
 			if err != nil {
-				return nil, false, fmt.Errorf("invalid V coordinate %q", tokens[i])
+				return nil, fmt.Errorf("invalid V coordinate %q", tokens[i])
 			}
+			p := Point{X: cur.X, Y: y}
 			if cmd == 'v' {
-				cur.Y += y
-			} else {
-				cur.Y = y
-			}
-			if len(pts) == 0 {
-				start = cur
+				p.Y = cur.Y + y
 			}
-			pts = append(pts, cur)
+			lineTo(p)
 			i++
 
 		case 'C', 'c':
 			// C/c takes sets of 6 numbers: x1 y1 x2 y2 x y
 			for {
 				if i+5 >= len(tokens) {
-					return nil, false, errors.New("incomplete C/c command; need 6 numbers")
+					return nil, errors.New("incomplete C/c command; need 6 numbers")
 				}
 				// If next token is a command, break so outer loop can handle it
 				if isCommand(tokens[i]) {
@@ -322,7 +1737,7 @@ func parseSimplePath(d string) ([]Point, bool, error) {
 				x, err5 := strconv.ParseFloat(tokens[i+4], 64)
 				y, err6 := strconv.ParseFloat(tokens[i+5], 64)
 				if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil {
-					return nil, false, fmt.Errorf("invalid C coordinates near %q", tokens[i])
+					return nil, fmt.Errorf("invalid C coordinates near %q", tokens[i])
 				}
 
 				var p1, p2, p3 Point
@@ -336,20 +1751,12 @@ func parseSimplePath(d string) ([]Point, bool, error) {
 					p3 = Point{X: x, Y: y}
 				}
 
-				// Flatten cubic from cur -> p3
+				// Flatten cubic from cur -> p3. The flattener emits only the
+				// points after cur and always ends exactly on p3.
 				var seg []Point
 				flattenCubicBezier(cur, p1, p2, p3, flatness, &seg)
-				if len(seg) == 0 {
-					// degenerate, but keep moving
-					cur = p3
-				} else {
-					for _, s := range seg {
-						cur = s
-						if len(pts) == 0 {
-							start = cur
-						}
-						pts = append(pts, cur)
-					}
+				for _, s := range seg {
+					lineTo(s)
 				}
 
 				i += 6
@@ -359,11 +1766,12 @@ func parseSimplePath(d string) ([]Point, bool, error) {
 			}
 
 		default:
-			return nil, false, fmt.Errorf("unsupported path command %q", string(cmd))
+			return nil, fmt.Errorf("%w %q", ErrUnsupportedCommand, string(cmd))
 		}
 	}
 
-	return pts, closed, nil
+	flush()
+	return subs, nil
 }
 
 func isCommand(tok string) bool {
@@ -379,152 +1787,480 @@ func isCommand(tok string) bool {
 }
 
 // hasUnsupportedCommands returns true if the path data contains
-// any SVG path commands we do not currently implement.
+// any SVG path commands we do not currently implement, or letters that
+// are no command at all.
 func hasUnsupportedCommands(d string) bool {
-	d = strings.TrimSpace(d)
-	if d == "" {
-		return false
-	}
-
-	for _, r := range d {
-		// SVG commands are alphabetic characters
-		if (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
-			switch r {
-			case 'M', 'm',
-				'L', 'l',
-				'H', 'h',
-				'V', 'v',
-				'Z', 'z',
-				'C', 'c': // we support cubic Bézier now
-				// allowed
-			default:
-				return true // unsupported command
-			}
+	for _, tok := range tokenizePathData(d) {
+		c := tok[0]
+		if (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') && !isCommand(tok) {
+			return true
 		}
 	}
 	return false
 }
 
+// pathCommands are all the SVG path commands, supported or not, so the
+// tokenizer splits data the same way whatever it holds.
+const pathCommands = "MmZzLlHhVvCcSsQqTtAa"
+
+// tokenizePathData splits path data into commands and numbers as the SVG
+// grammar reads it. A separator may be left out wherever the next number
+// can't continue the last one, as optimizers do: "-5-5" is -5 -5, ".5.5"
+// is .5 .5 and "1e-3-2" is 1e-3 -2. An arc's two flags are single digits
+// that may run into what follows ("a5 5 0 0110 10"). Anything else is
+// passed on a character at a time, for the parser to reject.
 func tokenizePathData(d string) []string {
-	// Insert spaces around command letters and replace commas with spaces
-	var b strings.Builder
-	commands := "MmLlHhVvZz"
-	for _, r := range d {
-		if strings.ContainsRune(commands, r) {
-			b.WriteRune(' ')
-			b.WriteRune(r)
-			b.WriteRune(' ')
-		} else if r == ',' {
-			b.WriteRune(' ')
-		} else {
-			b.WriteRune(r)
+	var tokens []string
+	var cmd byte
+	arg := 0 // numbers read since the command
+	for i := 0; i < len(d); {
+		c := d[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == ',':
+			i++
+		case strings.IndexByte(pathCommands, c) >= 0:
+			tokens = append(tokens, d[i:i+1])
+			cmd, arg = c, 0
+			i++
+		case (cmd == 'A' || cmd == 'a') && (arg%7 == 3 || arg%7 == 4) && (c == '0' || c == '1'):
+			tokens = append(tokens, d[i:i+1]) // large-arc or sweep flag
+			arg++
+			i++
+		default:
+			n := scanNumber(d[i:])
+			if n > 0 {
+				arg++
+			} else {
+				_, n = utf8.DecodeRuneInString(d[i:])
+			}
+			tokens = append(tokens, d[i:i+n])
+			i += n
 		}
 	}
-	return strings.Fields(b.String())
+	return tokens
 }
 
-func writeGcode(w io.Writer, paths []Path, cfg Config) error {
-	fmt.Fprintln(w, "(Generated by svg2gcode)")
-	fmt.Fprintln(w, "G21  (units in mm)")
-	fmt.Fprintln(w, "G90  (absolute coordinates)")
-	fmt.Fprintf(w, "G0 Z%.3f\n", cfg.SafeZ)
-
-	if cfg.CutDepth >= 0 {
-		return fmt.Errorf("cut depth (cutz) must be negative, got %.3f", cfg.CutDepth)
+// scanNumber returns the length of the number s starts with: a sign,
+// digits with at most one decimal point, and an exponent. It is 0 when s
+// doesn't start with a number.
+func scanNumber(s string) int {
+	digits := func(i int) int {
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i
+	}
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	j := digits(i)
+	mantissa := j - i
+	if j < len(s) && s[j] == '.' {
+		k := digits(j + 1)
+		mantissa += k - j - 1
+		j = k
+	}
+	if mantissa == 0 {
+		return 0
+	}
+	if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+		k := j + 1
+		if k < len(s) && (s[k] == '+' || s[k] == '-') {
+			k++
+		}
+		if e := digits(k); e > k {
+			j = e // an "e" without digits is not part of the number
+		}
 	}
-	targetZ := cfg.CutDepth
+	return j
+}
 
-	step := cfg.StepDown
-	if step <= 0 {
-		step = math.Abs(targetZ - cfg.SafeZ)
+// preparePaths runs the geometry pipeline: machine mapping, chaining,
+// simplification, placement and compensation.
+func preparePaths(paths []Path, cfg Config) ([]Path, error) {
+	// Everything below works in machine coordinates (mm, Y up), so the
+	// tool radius and tolerances are physical no matter what viewBox
+	// scaling or transforms the SVG used.
+	if err := checkOverrides(paths, cfg); err != nil {
+		return nil, err
+	}
+	paths = constructionPaths(paths, cfg.Construction, cfg.Warn)
+	paths = onlyColorPaths(paths, cfg.OnlyColors, cfg.Warn)
+	paths = toMachine(paths, cfg)
+	paths = chainPaths(paths, cfg.ChainTol)
+	if cfg.Simplify > 0 {
+		// on the drawn geometry, before offsets add tool-radius corners
+		// and dogbone notches it would flatten
+		for i := range paths {
+			if paths[i].Feeds != nil || paths[i].Image != nil {
+				continue // points and feeds must stay paired; an image is its box
+			}
+			paths[i].Points = simplifyPath(paths[i].Points, cfg.Simplify)
+		}
+	}
+	if cfg.Compensation != "none" || cfg.FillMode != "" && cfg.FillMode != "none" {
+		// offsets and fills need outlines that don't cross themselves
+		paths = repairPaths(paths, cfg.Warn)
+	}
+	if cfg.sideComp() && cfg.ToolDia > 0 {
+		paths = sidePaths(paths, cfg)
+	}
+	paths = reliefPaths(paths, cfg)
+	if err := mirrorPaths(paths, cfg.Mirror); err != nil {
+		return nil, err
+	}
+	if err := rotatePaths(paths, cfg.Rotate); err != nil {
+		return nil, err
+	}
+	if cfg.FitW > 0 && cfg.FitH > 0 {
+		if err := fitPaths(paths, cfg.FitW, cfg.FitH); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyOrigin(paths, cfg.Origin); err != nil {
+		return nil, err
+	}
+	translatePaths(paths, cfg.OffsetX, cfg.OffsetY)
+	if cfg.ArrayCols*cfg.ArrayRows > 1 {
+		paths = arrayPaths(paths, cfg.ArrayCols, cfg.ArrayRows, cfg.Spacing)
+	}
+	paths, err := twoSidedPaths(paths, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Bore {
+		paths = findHoles(paths, cfg)
+	}
+	if cfg.Slots {
+		paths = findSlots(paths, cfg)
+	}
+	if cfg.Order != nil {
+		// before compensation, so nesting is judged on the true outlines
+		// and finish passes stay right behind their roughing pass
+		paths = cfg.Order.Order(paths, Point{})
+	}
+	if cfg.VCarve {
+		paths = vcarvePaths(paths, cfg.VBitAngle, cfg.CutDepth, cfg.VCarveStep)
+	}
+	if cfg.FillMode != "" && cfg.FillMode != "none" {
+		inset := cfg.ToolDia / 2
+		if cfg.Trochoid.Width > 0 {
+			inset = cfg.Trochoid.Width / 2 // the loops swing out to half the slot
+		}
+		paths = fillPaths(paths, cfg.FillMode, cfg.FillSpacing, cfg.FillAngle, inset, cfg.Warn)
+	}
+	if cfg.StrokeAsWidth {
+		paths = widePaths(paths, cfg)
 	}
-	step = math.Abs(step)
 
-	// --- NEW: apply cutter compensation for closed paths ---
-	compPaths := make([]Path, 0, len(paths))
+	// apply cutter compensation for closed paths (open ones were offset
+	// to their side above)
 	if cfg.Compensation != "none" && cfg.ToolDia > 0 {
-		// tool radius in SVG units
-		radiusMM := cfg.ToolDia / 2.0
-		radiusSVG := radiusMM / cfg.Scale
-
-		for _, p := range paths {
-			if !p.Closed {
-				// leave open paths as-is
-				compPaths = append(compPaths, p)
-				continue
+		// each path is offset on its own, in parallel; warnings are
+		// replayed in path order so the output doesn't depend on timing
+		shapes := shapeRings(paths)
+		results := parallelMap(paths, func(p Path) compResult { return compensatePath(p, shapes, cfg) })
+		compPaths := make([]Path, 0, len(paths))
+		collapsed, first := 0, 0
+		for i, r := range results {
+			for _, w := range r.Warnings {
+				cfg.Warn.Add(w.Code, w.Path, "%s", w.Message)
 			}
-			offsetPts := offsetPolygon(p.Points, radiusSVG, cfg.Compensation)
-			if len(offsetPts) < 2 {
-				// degenerate, skip
-				continue
+			compPaths = append(compPaths, r.Paths...)
+			if r.Collapsed > 0 && collapsed == 0 {
+				first = paths[i].Index
 			}
-			compPaths = append(compPaths, Path{
-				Points: offsetPts,
-				Closed: true,
-				Stroke: p.Stroke,
-			})
+			collapsed += r.Collapsed
 		}
-	} else {
-		compPaths = paths
+		if cfg.Strict && collapsed > 0 {
+			return nil, &ElementError{Kind: ErrDegenerateOffset, Path: first,
+				Err: fmt.Errorf("%d feature(s) too small for the %.3f mm tool (-strict)", collapsed, cfg.ToolDia)}
+		}
+		paths = compPaths
 	}
 
-	paths = compPaths
-	// --- END NEW ---
+	// after compensation, so dashes are measured along the cut itself
+	paths = dashPaths(paths, cfg.Perforate)
 
-	for idx, p := range paths {
-		if len(p.Points) == 0 {
-			continue
+	if cfg.Trochoid.Width > 0 {
+		paths = trochoidPaths(paths, cfg)
+	}
+	if cfg.Operations {
+		paths = operationPaths(paths, cfg.ColorMap)
+	}
+
+	return paths, nil
+}
+
+// planJob prepares paths and plans the cutting moves (without header and
+// footer), checking them against the machine limits.
+func planJob(paths []Path, cfg Config) (*Program, error) {
+	if cfg.CutDepth >= 0 {
+		return nil, fmt.Errorf("cut depth (cutz) must be negative, got %.3f", cfg.CutDepth)
+	}
+	paths, err := preparePaths(paths, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ResumeFrom > len(paths) {
+		return nil, fmt.Errorf("-resume-from %d: the job has only %d paths", cfg.ResumeFrom, len(paths))
+	}
+	body := newProgram(cfg.SafeZ)
+	planPaths(body, paths, cfg)
+	body.Moves = slowCorners(body.Moves, cfg.CornerFeed, cfg.CornerAngle, cornerZone(cfg))
+	if err := checkLimits(body.Moves, cfg.Limits, cfg.Warn); err != nil {
+		return nil, err
+	}
+	checkStock(body.Moves, cfg, cfg.Warn)
+	return body, nil
+}
+
+func writeGcode(w io.Writer, paths []Path, cfg Config) error {
+	body, err := planJob(paths, cfg)
+	if err != nil {
+		return err
+	}
+	return emitProgram(w, body.Moves, cfg)
+}
+
+// emitProgram writes planned moves with the program header and footer.
+func emitProgram(w io.Writer, moves []Move, cfg Config) error {
+	// The header goes last so it can list every warning.
+	prog := newProgram(cfg.SafeZ)
+	prog.Raw(fmt.Sprintf("(%s)", cfg.Msg.T("header")))
+	format := formatMM
+	if cfg.Units == "inch" {
+		format = formatInch
+		prog.Raw(fmt.Sprintf("G20  (%s)", cfg.Msg.T("units_inch")))
+	} else {
+		prog.Raw(fmt.Sprintf("G21  (%s)", cfg.Msg.T("units_mm")))
+	}
+	if cfg.Precision > 0 {
+		format.Digits = cfg.Precision
+	}
+	format.Modal = cfg.Modal
+	format.LineNumbers = cfg.LineNumbers
+	format.Checksums = cfg.Checksums
+	prog.Raw(fmt.Sprintf("G90  (%s)", cfg.Msg.T("absolute")))
+	if cfg.WCS != "" {
+		prog.Raw(fmt.Sprintf("%s  (%s)", cfg.WCS, cfg.Msg.T("wcs")))
+	}
+	if cfg.SizeFromExtents {
+		prog.Raw(fmt.Sprintf("(%s)", cfg.Msg.T("size_assumed", cfg.SvgWidth, cfg.SvgHeight)))
+	}
+	if cfg.Warn != nil {
+		for _, wn := range cfg.Warn.List {
+			prog.Comment("warning " + commentSafe(wn.String()))
+		}
+	}
+	if cfg.GrblHints {
+		for _, line := range grblHints(moves) {
+			prog.Comment(line)
+		}
+	}
+	if cfg.Material != "" {
+		prog.Comment(cfg.Msg.T("material", cfg.Material, cfg.ToolDia))
+	}
+	if cfg.ChipLoad > 0 {
+		prog.Comment(cfg.Msg.T("chipload", cfg.CutFeed, cfg.SpindleRPM, cfg.Flutes, cfg.ChipLoad))
+	}
+	inject(prog, cfg.Hooks.PreJob)
+	if cfg.Probe.Enabled {
+		writeProbe(prog, cfg.Probe, format, cfg.Msg)
+	}
+	if cfg.Laser.Enabled {
+		// dynamic power: off until a move sets S, and during every G0
+		prog.Raw(fmt.Sprintf("M4 S0  (%s)", cfg.Msg.T("laser_on")))
+	} else {
+		if cfg.SpindleRPM > 0 {
+			// after probing: the touch plate must not meet a spinning tool
+			prog.Raw(fmt.Sprintf("M3 S%.0f  (%s)", cfg.SpindleRPM, cfg.Msg.T("spindle_on")))
 		}
-		fmt.Fprintf(w, "\n; Path %d stroke=%q\n", idx+1, p.Stroke)
+		prog.RapidZ(cfg.SafeZ)
+	}
+	if cfg.TraceBounds {
+		traceBounds(prog, moves, cfg)
+	}
+	body := moves
+	if cfg.Progress != "" && cfg.Progress != "none" {
+		moves = progressMoves(moves, cfg.Progress, cfg.RapidFeed, cfg.Msg)
+	}
+	if cfg.AirCut > 0 {
+		moves = withAirCut(body, moves, cfg)
+	}
+	prog.Moves = append(prog.Moves, moves...)
+	prog.Raw("")
+	prog.Raw(fmt.Sprintf("M5  (%s)", cfg.Msg.T("spindle_off")))
+	inject(prog, cfg.Hooks.PostJob)
+	prog.Raw(fmt.Sprintf("M2  (%s)", cfg.Msg.T("program_end")))
 
-		first := p.Points[0]
-		x0, y0 := writePoint(first, cfg)
+	return emitGcode(w, prog.Moves, format)
+}
 
-		fmt.Fprintf(w, "G0 X%.3f Y%.3f\n", x0, y0)
-		fmt.Fprintf(w, "G0 Z%.3f\n", cfg.SafeZ)
+func writePoint(pt Point, cfg Config) (float64, float64) {
+	x := pt.X * cfg.Scale
+	y := pt.Y * cfg.Scale
+	if cfg.FlipY {
+		y = (cfg.SvgHeight - pt.Y) * cfg.Scale
+	}
+	return x, y
+}
 
-		z := cfg.SafeZ
-		for {
-			nextZ := z - step
-			if nextZ < targetZ {
-				nextZ = targetZ
+// toMachine returns copies of paths mapped from SVG user units into
+// machine coordinates via writePoint.
+func toMachine(paths []Path, cfg Config) []Path {
+	return parallelMap(paths, func(p Path) Path {
+		pts := make([]Point, len(p.Points))
+		for j, pt := range p.Points {
+			pts[j].X, pts[j].Y = writePoint(pt, cfg)
+		}
+		p.Points = pts
+		if p.Dash != nil {
+			dash := make([]float64, len(p.Dash))
+			for j, d := range p.Dash {
+				dash[j] = d * cfg.Scale
 			}
+			p.Dash = dash
+			p.DashOffset *= cfg.Scale
+		}
+		p.Width *= cfg.Scale
+		return p
+	})
+}
 
-			fmt.Fprintf(w, "G1 Z%.3f F%.3f\n", nextZ, cfg.PlungeFeed)
+// compResult is what compensatePath makes of one path.
+type compResult struct {
+	Paths     []Path
+	Warnings  []Warning
+	Collapsed int // features too small for the tool
+}
 
-			for i := 1; i < len(p.Points); i++ {
-				pt := p.Points[i]
-				x, y := writePoint(pt, cfg)
-				fmt.Fprintf(w, "G1 X%.3f Y%.3f F%.3f\n", x, y, cfg.CutFeed)
-			}
+// compensatePath offsets one closed path by the tool radius (and the
+// finish allowance), for the compensation step of preparePaths. It only
+// reads shapes and cfg, so paths can be compensated concurrently; its
+// warnings are returned rather than added.
+func compensatePath(p Path, shapes map[int][][]Point, cfg Config) (r compResult) {
+	warn := &Warnings{Out: io.Discard}
+	cfg.Warn = warn
+	defer func() { r.Warnings = warn.List }()
+	radius := cfg.ToolDia / 2.0
+	if !p.Closed || p.Hole != nil || p.Slot != nil || p.Depths != nil || p.Filled {
+		// leave open paths, bored holes, slots and area fills as-is
+		r.Paths = []Path{p}
+		return r
+	}
+	if !p.Transform.IsSimilarity() {
+		// An offset in local units would be distorted by the
+		// transform; ours is exact because it happens after it.
+		cfg.Warn.Add(WSkewedTransform, p.Index,
+			"non-uniform or skewed transform; compensation applied to the transformed geometry")
+	}
+	mode := cfg.Compensation
+	if cfg.sideComp() {
+		// the loop's own direction already tells hole from island
+		mode = sideMode(p.Points, mode)
+	} else if isHole(p, shapes) {
+		// the wall of a hole in a compound shape faces the other way
+		mode = map[string]string{"inside": "outside", "outside": "inside"}[mode]
+	}
+	p.Comp = mode
+	if cfg.CompMode == "controller" {
+		// the controller offsets the drawn line by its own radius
+		p.Points = orientForCut(p.Points, mode, cfg.Direction)
+		p.CompSide = compSide(p.Points, mode)
+		r.Paths = append(r.Paths, p)
+		return r
+	}
+	for _, f := range narrowFeatures(p.Points, radius+cfg.FinishAllowance, mode) {
+		what := "inside radius smaller than the tool radius"
+		if f.Slot {
+			what = "slot narrower than the tool"
+		}
+		cfg.Warn.Add(WFeatureTooSmall, p.Index, "%s near X%.1f Y%.1f", what, f.At.X, f.At.Y)
+		r.Collapsed++
+	}
+	// a narrow waist splits the offset into several loops
+	loops := offsetContours(p.Points, radius+cfg.FinishAllowance, mode, cfg.Join)
+	if len(loops) == 0 {
+		b, _ := pathBounds([]Path{p})
+		cfg.Warn.Add(WCompCollapsed, p.Index, "offset polygon is degenerate near X%.1f Y%.1f; path skipped",
+			(b.MinX+b.MaxX)/2, (b.MinY+b.MaxY)/2)
+		r.Collapsed++
+		return r
+	}
+	for _, loop := range loops {
+		rough := p
+		rough.Points = orientForCut(loop, mode, cfg.Direction)
+		if cfg.Dogbone != "" {
+			rough.Points = dogbones(rough.Points, radius+cfg.FinishAllowance, mode, cfg.Dogbone)
+		}
+		r.Paths = append(r.Paths, restOf(rough, p, cfg.FinishAllowance, mode, cfg)...)
+	}
 
-			if nextZ <= targetZ {
-				break
+	if cfg.FinishAllowance > 0 {
+		for _, loop := range offsetContours(p.Points, radius, mode, cfg.Join) {
+			finish := p
+			finish.Points = orientForCut(loop, mode, cfg.Direction)
+			if cfg.Dogbone != "" {
+				finish.Points = dogbones(finish.Points, radius, mode, cfg.Dogbone)
 			}
-
-			fmt.Fprintf(w, "G0 Z%.3f\n", cfg.SafeZ)
-			fmt.Fprintf(w, "G0 X%.3f Y%.3f\n", x0, y0)
-			z = cfg.SafeZ
+			finish.Finish = true
+			r.Paths = append(r.Paths, restOf(finish, p, 0, mode, cfg)...)
 		}
+	}
+	return r
+}
 
-		fmt.Fprintf(w, "G0 Z%.3f\n", cfg.SafeZ)
+// orientForCut orders a compensated loop so that, seen from above in
+// machine coordinates with a clockwise spindle, the tool climbs or
+// conventionally mills the wall. Climb milling runs clockwise around an
+// outside profile and counter-clockwise inside a hole. Orientation is
+// measured after the Y flip and any mirroring transforms, so it is the
+// direction the machine will actually move.
+func orientForCut(points []Point, mode, direction string) []Point {
+	if direction != "climb" && direction != "conventional" {
+		return points
+	}
+	wantCCW := mode == "inside"
+	if direction == "conventional" {
+		wantCCW = !wantCCW
 	}
+	if (signedArea(points) > 0) != wantCCW {
+		return reversePoints(points)
+	}
+	return points
+}
 
-	fmt.Fprintln(w, "\nM5  (spindle off, if relevant)")
-	fmt.Fprintln(w, "M2  (program end)")
-	return nil
+// Join is how an offset turns the outside of a corner, where the offset
+// edges move apart. Inside corners always meet where the edges cross.
+type Join struct {
+	Style string  // "miter" (or ""), "round", "arc" (round, cut as G2/G3), "bevel"
+	Limit float64 // miter length over offset beyond which a miter is cut square, 0 = none
 }
 
-func writePoint(pt Point, cfg Config) (float64, float64) {
-	x := pt.X * cfg.Scale
-	y := (cfg.SvgHeight - pt.Y) * cfg.Scale
-	return x, y
+// plain reports whether join is a miter without a limit, the way
+// offsetPolygon joins every corner.
+func (j Join) plain() bool {
+	return (j.Style == "" || j.Style == "miter") && j.Limit == 0
 }
 
-// offsetPolygon offsets a closed polygon by delta in SVG units.
+// offsetPolygon offsets a closed polygon by delta (same units as points).
 // mode is "inside" or "outside" relative to the polygon's interior.
 // points may be closed (first == last) or open; result is closed (first == last).
+// Every corner is mitred, so vertex i of the result belongs to vertex i of
+// the polygon.
 func offsetPolygon(points []Point, delta float64, mode string) []Point {
+	return offsetPolygonJoin(points, delta, mode, Join{})
+}
+
+// offsetPolygonJoin is offsetPolygon with outside corners joined as join
+// says. A round join keeps the tool delta from the corner all the way
+// round; a bevel, or a miter over the limit, is cut off square across the
+// corner, at delta or at the limit, so the tool never comes closer than
+// delta either. Unless join is plain, the result may cross itself where
+// the shape is narrower than the offset; offsetContours cleans that up.
+func offsetPolygonJoin(points []Point, delta float64, mode string, join Join) []Point {
 	if delta == 0 || len(points) < 3 {
 		// Nothing to do
 		cp := make([]Point, len(points))
@@ -532,14 +2268,11 @@ func offsetPolygon(points []Point, delta float64, mode string) []Point {
 		return cp
 	}
 
-	// Remove duplicate closing point if present
-	n0 := len(points)
-	poly := make([]Point, 0, n0)
-	for i, p := range points {
-		if i == n0-1 && almostEqualPoint(p, points[0]) {
-			break
-		}
-		poly = append(poly, p)
+	// Drop repeated points (zero-length edges have no normal) and the
+	// duplicate closing point if present
+	poly := dedupePoints(append([]Point(nil), points...))
+	for len(poly) > 1 && almostEqualPoint(poly[len(poly)-1], poly[0]) {
+		poly = poly[:len(poly)-1]
 	}
 	n := len(poly)
 	if n < 3 {
@@ -549,12 +2282,7 @@ func offsetPolygon(points []Point, delta float64, mode string) []Point {
 	}
 
 	// Signed area to determine orientation
-	area := 0.0
-	for i := 0; i < n; i++ {
-		j := (i + 1) % n
-		area += poly[i].X*poly[j].Y - poly[j].X*poly[i].Y
-	}
-	area *= 0.5
+	area := signedArea(poly)
 	if math.Abs(area) < 1e-9 {
 		// Degenerate; bail out
 		cp := make([]Point, len(poly))
@@ -570,10 +2298,7 @@ func offsetPolygon(points []Point, delta float64, mode string) []Point {
 		p1 := poly[(j+1)%n]
 		ex := p1.X - p0.X
 		ey := p1.Y - p0.Y
-		length := math.Hypot(ex, ey)
-		if length == 0 {
-			length = 1
-		}
+		length := math.Hypot(ex, ey) // never 0 after dedupe
 
 		var nIn Point
 		if area > 0 {
@@ -618,7 +2343,21 @@ func offsetPolygon(points []Point, delta float64, mode string) []Point {
 			continue
 		}
 
+		// outside corner: the next edge turns away from the offset
+		if !join.plain() && (e1.X*n0v.X+e1.Y*n0v.Y)*delta < 0 {
+			result = append(result, joinCorner(pCur, e0, n0v, n1v, delta, join)...)
+			continue
+		}
+
 		t := -cross(Point{X: q0.X - q1.X, Y: q0.Y - q1.Y}, e1) / denom
+		if u := -cross(Point{X: q0.X - q1.X, Y: q0.Y - q1.Y}, e0) / denom; !join.plain() && (t < 0 || u > 1) {
+			// the edges next to a sharp inside corner are too short for
+			// their offsets to meet: go round by the corner instead and
+			// let offsetContours untangle the loop that makes
+			end := Point{X: pCur.X + n0v.X*delta, Y: pCur.Y + n0v.Y*delta}
+			result = append(result, end, pCur, q1)
+			continue
+		}
 		ix := q0.X + e0.X*t
 		iy := q0.Y + e0.Y*t
 		result = append(result, Point{X: ix, Y: iy})
@@ -631,3 +2370,248 @@ func offsetPolygon(points []Point, delta float64, mode string) []Point {
 
 	return result
 }
+
+// offsetContours offsets a closed polygon like offsetPolygonJoin, but returns
+// every loop the offset really has. Where a concave shape is narrower
+// than 2·delta the mitred offset crosses or touches itself; it is
+// untangled with windingLoops, and loops that turned inside out or come
+// closer than delta to the outline are dropped. A shape smaller than the
+// tool gives no loop at all.
+func offsetContours(points []Point, delta float64, mode string, join Join) [][]Point {
+	raw := offsetPolygonJoin(points, delta, mode, join)
+	if len(raw) < 4 {
+		return nil
+	}
+	if delta == 0 {
+		return [][]Point{raw}
+	}
+	want := signedArea(points) > 0
+	tol := math.Abs(delta)*1e-3 + 0.01 // round joins are flattened to 0.01
+	orig := dedupePoints(append([]Point(nil), points...))
+	if len(orig) > 1 && almostEqualPoint(orig[0], orig[len(orig)-1]) {
+		orig = orig[:len(orig)-1]
+	}
+	grid := newSegGrid(orig)
+	var out [][]Point
+	for _, loop := range windingLoops(raw, want) {
+		if len(loop) < 4 || math.Abs(signedArea(loop)) < 1e-9 || (signedArea(loop) > 0) != want {
+			continue
+		}
+		close := false
+		for _, q := range loop {
+			if close = grid.closerThan(q, math.Abs(delta)-tol); close {
+				break
+			}
+		}
+		if close {
+			continue // a leftover of an edge that turned around
+		}
+		out = append(out, loop)
+	}
+	return out
+}
+
+// joinCorner turns the outside of the corner at p, arriving along e0,
+// from offset normal n0 to n1, as join says.
+func joinCorner(p, e0, n0, n1 Point, delta float64, join Join) []Point {
+	if join.Style == "round" || join.Style == "arc" {
+		a0 := math.Atan2(n0.Y, n0.X)
+		sweep := math.Remainder(math.Atan2(n1.Y, n1.X)-a0, 2*math.Pi)
+		if delta < 0 {
+			a0 += math.Pi
+		}
+		return arcPoints(p, math.Abs(delta), a0, sweep, 0.01)
+	}
+	// the miter tip lies along the bisector, 1/cos(half the turn) out
+	bis := Point{X: n0.X + n1.X, Y: n0.Y + n1.Y}
+	bl := math.Hypot(bis.X, bis.Y)
+	ratio := math.Inf(1)
+	if bl > 1e-12 {
+		bis = Point{X: bis.X / bl, Y: bis.Y / bl}
+		ratio = 1 / (bis.X*n0.X + bis.Y*n0.Y)
+	}
+	limit := join.Limit
+	if join.Style == "bevel" {
+		limit = 1
+	}
+	if ratio <= limit {
+		return []Point{{X: p.X + bis.X*ratio*delta, Y: p.Y + bis.Y*ratio*delta}}
+	}
+	if bl <= 1e-12 {
+		// the path doubles back: square off beyond the tip
+		el := math.Hypot(e0.X, e0.Y)
+		bis = Point{X: e0.X / el, Y: e0.Y / el}
+	}
+	// cut across the bisector at limit·delta, where it meets both offset
+	// edges
+	c := Point{X: p.X + bis.X*limit*delta, Y: p.Y + bis.Y*limit*delta}
+	var out []Point
+	for _, n := range []Point{n0, n1} {
+		// slide along the offset edge through q until it meets the cut
+		q := Point{X: p.X + n.X*delta, Y: p.Y + n.Y*delta}
+		e := Point{X: -n.Y, Y: n.X}
+		den := e.X*bis.X + e.Y*bis.Y
+		if math.Abs(den) < 1e-12 {
+			out = append(out, c)
+			continue
+		}
+		t := ((c.X-q.X)*bis.X + (c.Y-q.Y)*bis.Y) / den
+		out = append(out, Point{X: q.X + e.X*t, Y: q.Y + e.Y*t})
+	}
+	return out
+}
+
+// narrowFeature is a stretch of an outline the tool cannot follow.
+type narrowFeature struct {
+	Slot bool  // a slot end narrower than the tool; else a tight inside radius
+	At   Point // middle of the stretch, machine coordinates
+}
+
+// narrowFeatures finds where offsetting points by delta turns edges
+// around: there the raw offset loops back over itself and the tool could
+// not reach the wall. A run of such edges between two
+// antiparallel walls less than 2·delta apart is the end of a slot too
+// narrow for the tool; otherwise it is a curve tighter than the tool
+// radius.
+func narrowFeatures(points []Point, delta float64, mode string) []narrowFeature {
+	offset := offsetPolygon(points, delta, mode)
+	poly := dedupePoints(append([]Point(nil), points...))
+	for len(poly) > 1 && almostEqualPoint(poly[len(poly)-1], poly[0]) {
+		poly = poly[:len(poly)-1]
+	}
+	n := len(poly)
+	if n < 3 || len(offset) != n+1 {
+		return nil
+	}
+	edge := func(i int) Point {
+		a, b := poly[i%n], poly[(i+1)%n]
+		return Point{X: b.X - a.X, Y: b.Y - a.Y}
+	}
+	flipped := make([]bool, n)
+	all := true
+	for i := 0; i < n; i++ {
+		e := edge(i)
+		flipped[i] = e.X*(offset[i+1].X-offset[i].X)+e.Y*(offset[i+1].Y-offset[i].Y) < 0
+		all = all && flipped[i]
+	}
+	if all {
+		return nil // turned inside out as a whole: no offset loop is left
+	}
+	start := 0
+	for flipped[start] {
+		start++ // begin outside a run, so none wraps around
+	}
+	var out []narrowFeature
+	for k := 0; k < n; k++ {
+		i := (start + k) % n
+		if !flipped[i] || flipped[(i+n-1)%n] {
+			continue
+		}
+		run := 1
+		for flipped[(i+run)%n] {
+			run++
+		}
+		mid := (i + run/2) % n
+		f := narrowFeature{At: poly[mid]}
+		if run%2 == 1 {
+			f.At = lerp(poly[mid], poly[(mid+1)%n], 0.5)
+		}
+		before, after := edge(i+n-1), edge(i+run)
+		lb, la := math.Hypot(before.X, before.Y), math.Hypot(after.X, after.Y)
+		if (before.X*after.X+before.Y*after.Y)/(lb*la) < -0.9 {
+			f.Slot = distPointToLine(poly[i], poly[(i+run)%n], poly[(i+run+1)%n]) < 2*delta
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// offsetPolyline offsets an open polyline by delta to one side of its
+// direction of travel, side "left" or "right" (seen from above in
+// machine coordinates). Corners turning towards the offset side are
+// mitred; corners turning away get an arc around the vertex, so the
+// tool never comes closer than delta to the line.
+func offsetPolyline(points []Point, delta float64, side string) []Point {
+	pts := dedupePoints(append([]Point(nil), points...))
+	if delta == 0 || len(pts) < 2 {
+		return pts
+	}
+	sign := 1.0
+	if side == "right" {
+		sign = -1
+	}
+	normal := func(a, b Point) Point {
+		l := math.Hypot(b.X-a.X, b.Y-a.Y) // never 0 after dedupe
+		return Point{X: -(b.Y - a.Y) / l * sign, Y: (b.X - a.X) / l * sign}
+	}
+	shift := func(p, n Point) Point {
+		return Point{X: p.X + n.X*delta, Y: p.Y + n.Y*delta}
+	}
+
+	out := []Point{shift(pts[0], normal(pts[0], pts[1]))}
+	for i := 1; i < len(pts)-1; i++ {
+		a, p, b := pts[i-1], pts[i], pts[i+1]
+		e0 := Point{X: p.X - a.X, Y: p.Y - a.Y}
+		e1 := Point{X: b.X - p.X, Y: b.Y - p.Y}
+		n0, n1 := normal(a, p), normal(p, b)
+		turn := cross(e0, e1)
+		if math.Abs(turn) < 1e-9*math.Hypot(e0.X, e0.Y)*math.Hypot(e1.X, e1.Y) &&
+			e0.X*e1.X+e0.Y*e1.Y > 0 {
+			out = append(out, shift(p, n1)) // straight on
+			continue
+		}
+		if turn*sign > 0 {
+			// inside corner: where the two offset edges meet
+			q0, q1 := shift(a, n0), shift(p, n1)
+			t := -cross(Point{X: q0.X - q1.X, Y: q0.Y - q1.Y}, e1) / turn
+			out = append(out, Point{X: q0.X + e0.X*t, Y: q0.Y + e0.Y*t})
+			continue
+		}
+		// outside corner: swing around the vertex, away from the line
+		a0 := math.Atan2(n0.Y, n0.X)
+		sweep := math.Atan2(n1.Y, n1.X) - a0
+		if sign > 0 && sweep > 0 {
+			sweep -= 2 * math.Pi
+		} else if sign < 0 && sweep < 0 {
+			sweep += 2 * math.Pi
+		}
+		out = append(out, arcPoints(p, delta, a0, sweep, 0.01)...)
+	}
+	n := len(pts)
+	return append(out, shift(pts[n-1], normal(pts[n-2], pts[n-1])))
+}
+
+// sidePaths offsets every open path by the tool radius to the side of
+// travel cfg.Compensation names, or marks it for G41/G42 under controller
+// compensation. It runs before mirroring, which flips
+// the handedness of the drawing, and ordering, which may reverse a path,
+// so the side is the one the path was drawn with.
+func sidePaths(paths []Path, cfg Config) []Path {
+	for i, p := range paths {
+		if p.Closed || p.Depths != nil || len(p.Points) < 2 {
+			continue
+		}
+		if cfg.CompMode == "controller" {
+			paths[i].CompSide = cfg.Compensation
+			continue
+		}
+		paths[i].Points = offsetPolyline(p.Points, cfg.ToolDia/2, cfg.Compensation)
+		paths[i].Comp = cfg.Compensation
+	}
+	return paths
+}
+
+// sideMode is the inside/outside offset that puts a closed path's kerf on
+// the given side of travel: left of a counter-clockwise loop is inside.
+func sideMode(points []Point, side string) string {
+	if (signedArea(points) > 0) == (side == "left") {
+		return "inside"
+	}
+	return "outside"
+}
+
+// sideComp reports whether -comp names a side of travel rather than of
+// the shape.
+func (cfg Config) sideComp() bool {
+	return cfg.Compensation == "left" || cfg.Compensation == "right"
+}
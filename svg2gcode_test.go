@@ -0,0 +1,56 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenizePathData(t *testing.T) {
+	for _, tc := range []struct {
+		d    string
+		want []string
+	}{
+		{"M10,20 L30 40", []string{"M", "10", "20", "L", "30", "40"}},
+		// a second point ends a number, and starts the next
+		{"M20.5.5", []string{"M", "20.5", ".5"}},
+		{"l.5.5.5", []string{"l", ".5", ".5", ".5"}},
+		// a sign starts a number, even after an exponent's digits
+		{"L30-1e1", []string{"L", "30", "-1e1"}},
+		{"l-5-5", []string{"l", "-5", "-5"}},
+		{"L1e-3-2", []string{"L", "1e-3", "-2"}},
+		{"L2E+1+3", []string{"L", "2E+1", "+3"}},
+		{"L1.e2 .5E-1", []string{"L", "1.e2", ".5E-1"}},
+		// an e without digits is not an exponent, so it is passed on
+		{"L1e 2", []string{"L", "1", "e", "2"}},
+		{"L1e-x", []string{"L", "1", "e", "-", "x"}},
+		// arc flags are single digits and may run into the next number
+		{"a5 5 0 0110 10", []string{"a", "5", "5", "0", "0", "1", "10", "10"}},
+		{"A5,5,0,1,0,20,0 5 5 0 1120 0", []string{"A", "5", "5", "0", "1", "0", "20", "0", "5", "5", "0", "1", "1", "20", "0"}},
+		// every command letter is split out, supported or not
+		{"M0 0Q1 1 2 2T3 3", []string{"M", "0", "0", "Q", "1", "1", "2", "2", "T", "3", "3"}},
+		{"\tM\n0,,0\r", []string{"M", "0", "0"}},
+	} {
+		if got := tokenizePathData(tc.d); !slices.Equal(got, tc.want) {
+			t.Errorf("tokenizePathData(%q) = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestParseSimplePathNumbers(t *testing.T) {
+	subs, err := parseSimplePath("M20.5.5L30-1e1l-5-5h1e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Point{{20.5, 0.5}, {30, -10}, {25, -15}, {35, -15}}
+	if len(subs) != 1 || !slices.Equal(subs[0].Points, want) {
+		t.Fatalf("got %+v, want one subpath through %v", subs, want)
+	}
+	for _, d := range []string{"M0 0 L1e 2", "M0 0 L1 2x", "M0 0 L1"} {
+		if _, err := parseSimplePath(d); err == nil {
+			t.Errorf("parseSimplePath(%q) accepted bad data", d)
+		}
+	}
+	if hasUnsupportedCommands("M1e2 1E-2 L2e1 3") {
+		t.Error("exponents taken for unsupported commands")
+	}
+}
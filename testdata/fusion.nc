@@ -0,0 +1,70 @@
+(Generated by svg2gcode)
+G21  (units in mm)
+G90  (absolute coordinates)
+G0 Z5.000
+
+; Path 1 stroke="#000000"
+G0 X5.000 Y5.000
+G1 Z-1.000 F120.000
+G1 X75.000 Y5.000 F300.000
+G1 X75.000 Y45.000 F300.000
+G1 X5.000 Y45.000 F300.000
+G1 X5.000 Y5.000 F300.000
+G1 Z-2.000 F120.000
+G1 X5.000 Y45.000 F300.000
+G1 X75.000 Y45.000 F300.000
+G1 X75.000 Y5.000 F300.000
+G1 X5.000 Y5.000 F300.000
+G1 Z-3.000 F120.000
+G1 X75.000 Y5.000 F300.000
+G1 X75.000 Y45.000 F300.000
+G1 X5.000 Y45.000 F300.000
+G1 X5.000 Y5.000 F300.000
+
+; Path 2 stroke="#000000"
+; helical bore, diameter 6.000 mm
+G0 Z5.000
+G0 X16.500 Y15.000
+G1 Z0.000 F120.000
+G3 X16.500 Y15.000 Z-1.000 I-1.500 J0.000 F300.000
+G3 X16.500 Y15.000 Z-2.000 I-1.500 J0.000 F300.000
+G3 X16.500 Y15.000 Z-3.000 I-1.500 J0.000 F300.000
+G3 X16.500 Y15.000 Z-3.000 I-1.500 J0.000 F300.000
+G1 X15.000 Y15.000 F300.000
+
+; Path 3 stroke="#000000"
+; helical bore, diameter 6.000 mm
+G0 Z5.000
+G0 X66.500 Y15.000
+G1 Z0.000 F120.000
+G3 X66.500 Y15.000 Z-1.000 I-1.500 J0.000 F300.000
+G3 X66.500 Y15.000 Z-2.000 I-1.500 J0.000 F300.000
+G3 X66.500 Y15.000 Z-3.000 I-1.500 J0.000 F300.000
+G3 X66.500 Y15.000 Z-3.000 I-1.500 J0.000 F300.000
+G1 X65.000 Y15.000 F300.000
+
+; Path 4 stroke="#000000"
+; helical bore, diameter 6.000 mm
+G0 Z5.000
+G0 X66.500 Y35.000
+G1 Z0.000 F120.000
+G3 X66.500 Y35.000 Z-1.000 I-1.500 J0.000 F300.000
+G3 X66.500 Y35.000 Z-2.000 I-1.500 J0.000 F300.000
+G3 X66.500 Y35.000 Z-3.000 I-1.500 J0.000 F300.000
+G3 X66.500 Y35.000 Z-3.000 I-1.500 J0.000 F300.000
+G1 X65.000 Y35.000 F300.000
+
+; Path 5 stroke="#000000"
+; helical bore, diameter 6.000 mm
+G0 Z5.000
+G0 X16.500 Y35.000
+G1 Z0.000 F120.000
+G3 X16.500 Y35.000 Z-1.000 I-1.500 J0.000 F300.000
+G3 X16.500 Y35.000 Z-2.000 I-1.500 J0.000 F300.000
+G3 X16.500 Y35.000 Z-3.000 I-1.500 J0.000 F300.000
+G3 X16.500 Y35.000 Z-3.000 I-1.500 J0.000 F300.000
+G1 X15.000 Y35.000 F300.000
+G0 Z5.000
+
+M5  (spindle off, if relevant)
+M2  (program end)
@@ -0,0 +1,130 @@
+(Generated by svg2gcode)
+G21  (units in mm)
+G90  (absolute coordinates)
+G0 Z5.000
+
+; Path 1 stroke="#ff0000"
+G0 X15.000 Y30.000
+G1 Z-1.000 F120.000
+G1 X25.000 Y40.000 F300.000
+G1 X35.000 Y30.000 F300.000
+G1 X45.000 Y40.000 F300.000
+G1 X55.000 Y30.000 F300.000
+G1 Z-2.000 F120.000
+G1 X45.000 Y40.000 F300.000
+G1 X35.000 Y30.000 F300.000
+G1 X25.000 Y40.000 F300.000
+G1 X15.000 Y30.000 F300.000
+
+; Path 2 stroke="#000000"
+G0 Z5.000
+G0 X76.000 Y49.000
+G1 Z-1.000 F120.000
+G1 X109.000 Y49.000 F300.000
+G1 X109.000 Y11.000 F300.000
+G1 X76.000 Y11.000 F300.000
+G1 X76.000 Y49.000 F300.000
+G0 Z5.000
+G1 Z-2.000 F120.000
+G1 X109.000 Y49.000 F300.000
+G1 X109.000 Y11.000 F300.000
+G1 X76.000 Y11.000 F300.000
+G1 X76.000 Y49.000 F300.000
+
+; Path 3 stroke="#000000"
+G0 Z5.000
+G0 X85.449 Y41.000
+G1 Z-1.000 F120.000
+G1 X84.198 Y40.871 F300.000
+G1 X82.980 Y40.487 F300.000
+G1 X81.877 Y39.882 F300.000
+G1 X80.915 Y39.085 F300.000
+G1 X80.118 Y38.123 F300.000
+G1 X79.513 Y37.020 F300.000
+G1 X79.129 Y35.802 F300.000
+G1 X79.000 Y34.551 F300.000
+G1 X79.000 Y25.449 F300.000
+G1 X79.129 Y24.198 F300.000
+G1 X79.513 Y22.980 F300.000
+G1 X80.118 Y21.877 F300.000
+G1 X80.915 Y20.915 F300.000
+G1 X81.877 Y20.118 F300.000
+G1 X82.980 Y19.513 F300.000
+G1 X84.198 Y19.129 F300.000
+G1 X85.449 Y19.000 F300.000
+G1 X99.551 Y19.000 F300.000
+G1 X100.802 Y19.129 F300.000
+G1 X102.020 Y19.513 F300.000
+G1 X103.123 Y20.118 F300.000
+G1 X104.085 Y20.915 F300.000
+G1 X104.882 Y21.877 F300.000
+G1 X105.487 Y22.980 F300.000
+G1 X105.871 Y24.198 F300.000
+G1 X106.000 Y25.449 F300.000
+G1 X106.000 Y34.551 F300.000
+G1 X105.871 Y35.802 F300.000
+G1 X105.487 Y37.020 F300.000
+G1 X104.882 Y38.123 F300.000
+G1 X104.085 Y39.085 F300.000
+G1 X103.123 Y39.882 F300.000
+G1 X102.020 Y40.487 F300.000
+G1 X100.802 Y40.871 F300.000
+G1 X99.551 Y41.000 F300.000
+G1 X85.449 Y41.000 F300.000
+G0 Z5.000
+G1 Z-2.000 F120.000
+G1 X84.198 Y40.871 F300.000
+G1 X82.980 Y40.487 F300.000
+G1 X81.877 Y39.882 F300.000
+G1 X80.915 Y39.085 F300.000
+G1 X80.118 Y38.123 F300.000
+G1 X79.513 Y37.020 F300.000
+G1 X79.129 Y35.802 F300.000
+G1 X79.000 Y34.551 F300.000
+G1 X79.000 Y25.449 F300.000
+G1 X79.129 Y24.198 F300.000
+G1 X79.513 Y22.980 F300.000
+G1 X80.118 Y21.877 F300.000
+G1 X80.915 Y20.915 F300.000
+G1 X81.877 Y20.118 F300.000
+G1 X82.980 Y19.513 F300.000
+G1 X84.198 Y19.129 F300.000
+G1 X85.449 Y19.000 F300.000
+G1 X99.551 Y19.000 F300.000
+G1 X100.802 Y19.129 F300.000
+G1 X102.020 Y19.513 F300.000
+G1 X103.123 Y20.118 F300.000
+G1 X104.085 Y20.915 F300.000
+G1 X104.882 Y21.877 F300.000
+G1 X105.487 Y22.980 F300.000
+G1 X105.871 Y24.198 F300.000
+G1 X106.000 Y25.449 F300.000
+G1 X106.000 Y34.551 F300.000
+G1 X105.871 Y35.802 F300.000
+G1 X105.487 Y37.020 F300.000
+G1 X104.882 Y38.123 F300.000
+G1 X104.085 Y39.085 F300.000
+G1 X103.123 Y39.882 F300.000
+G1 X102.020 Y40.487 F300.000
+G1 X100.802 Y40.871 F300.000
+G1 X99.551 Y41.000 F300.000
+G1 X85.449 Y41.000 F300.000
+
+; Path 4 stroke="#000000"
+G0 Z5.000
+G0 X11.000 Y49.000
+G1 Z-1.000 F120.000
+G1 X59.000 Y49.000 F300.000
+G1 X59.000 Y11.000 F300.000
+G1 X11.000 Y11.000 F300.000
+G1 X11.000 Y49.000 F300.000
+G0 Z5.000
+G1 Z-2.000 F120.000
+G1 X59.000 Y49.000 F300.000
+G1 X59.000 Y11.000 F300.000
+G1 X11.000 Y11.000 F300.000
+G1 X11.000 Y49.000 F300.000
+G0 Z5.000
+
+M5  (spindle off, if relevant)
+M2  (program end)
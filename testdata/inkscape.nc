@@ -0,0 +1,180 @@
+(Generated by svg2gcode)
+G21  (units in mm)
+G90  (absolute coordinates)
+G0 Z5.000
+
+; Path 1 stroke="#000000"
+G0 X8.500 Y71.500
+G1 Z-0.500 F120.000
+G1 X70.076 Y71.500 F300.000
+G1 X72.311 Y71.273 F300.000
+G1 X74.471 Y70.599 F300.000
+G1 X76.425 Y69.534 F300.000
+G1 X78.128 Y68.128 F300.000
+G1 X79.534 Y66.425 F300.000
+G1 X80.599 Y64.471 F300.000
+G1 X81.273 Y62.311 F300.000
+G1 X81.500 Y60.076 F300.000
+G1 X81.500 Y18.500 F300.000
+G1 X8.500 Y18.500 F300.000
+G1 X8.500 Y71.500 F300.000
+G0 Z5.000
+G1 Z-1.000 F120.000
+G1 X70.076 Y71.500 F300.000
+G1 X72.311 Y71.273 F300.000
+G1 X74.471 Y70.599 F300.000
+G1 X76.425 Y69.534 F300.000
+G1 X78.128 Y68.128 F300.000
+G1 X79.534 Y66.425 F300.000
+G1 X80.599 Y64.471 F300.000
+G1 X81.273 Y62.311 F300.000
+G1 X81.500 Y60.076 F300.000
+G1 X81.500 Y18.500 F300.000
+G1 X8.500 Y18.500 F300.000
+G1 X8.500 Y71.500 F300.000
+
+; Path 2 stroke="#000000"
+G0 Z5.000
+G0 X32.507 Y40.000
+G1 Z-0.500 F120.000
+G1 X32.363 Y38.535 F300.000
+G1 X31.936 Y37.127 F300.000
+G1 X31.242 Y35.829 F300.000
+G1 X30.308 Y34.692 F300.000
+G1 X29.171 Y33.758 F300.000
+G1 X27.873 Y33.064 F300.000
+G1 X26.465 Y32.637 F300.000
+G1 X25.000 Y32.493 F300.000
+G1 X23.535 Y32.637 F300.000
+G1 X22.127 Y33.064 F300.000
+G1 X20.829 Y33.758 F300.000
+G1 X19.692 Y34.692 F300.000
+G1 X18.758 Y35.829 F300.000
+G1 X18.064 Y37.127 F300.000
+G1 X17.637 Y38.535 F300.000
+G1 X17.493 Y40.000 F300.000
+G1 X17.637 Y41.465 F300.000
+G1 X18.064 Y42.873 F300.000
+G1 X18.758 Y44.171 F300.000
+G1 X19.692 Y45.308 F300.000
+G1 X20.829 Y46.242 F300.000
+G1 X22.127 Y46.936 F300.000
+G1 X23.535 Y47.363 F300.000
+G1 X25.000 Y47.507 F300.000
+G1 X26.465 Y47.363 F300.000
+G1 X27.873 Y46.936 F300.000
+G1 X29.171 Y46.242 F300.000
+G1 X30.308 Y45.308 F300.000
+G1 X31.242 Y44.171 F300.000
+G1 X31.936 Y42.873 F300.000
+G1 X32.363 Y41.465 F300.000
+G1 X32.507 Y40.000 F300.000
+G0 Z5.000
+G1 Z-1.000 F120.000
+G1 X32.363 Y38.535 F300.000
+G1 X31.936 Y37.127 F300.000
+G1 X31.242 Y35.829 F300.000
+G1 X30.308 Y34.692 F300.000
+G1 X29.171 Y33.758 F300.000
+G1 X27.873 Y33.064 F300.000
+G1 X26.465 Y32.637 F300.000
+G1 X25.000 Y32.493 F300.000
+G1 X23.535 Y32.637 F300.000
+G1 X22.127 Y33.064 F300.000
+G1 X20.829 Y33.758 F300.000
+G1 X19.692 Y34.692 F300.000
+G1 X18.758 Y35.829 F300.000
+G1 X18.064 Y37.127 F300.000
+G1 X17.637 Y38.535 F300.000
+G1 X17.493 Y40.000 F300.000
+G1 X17.637 Y41.465 F300.000
+G1 X18.064 Y42.873 F300.000
+G1 X18.758 Y44.171 F300.000
+G1 X19.692 Y45.308 F300.000
+G1 X20.829 Y46.242 F300.000
+G1 X22.127 Y46.936 F300.000
+G1 X23.535 Y47.363 F300.000
+G1 X25.000 Y47.507 F300.000
+G1 X26.465 Y47.363 F300.000
+G1 X27.873 Y46.936 F300.000
+G1 X29.171 Y46.242 F300.000
+G1 X30.308 Y45.308 F300.000
+G1 X31.242 Y44.171 F300.000
+G1 X31.936 Y42.873 F300.000
+G1 X32.363 Y41.465 F300.000
+G1 X32.507 Y40.000 F300.000
+
+; Path 3 stroke="#000000"
+G0 Z5.000
+G0 X50.000 Y47.508
+G1 Z-0.500 F120.000
+G1 X51.508 Y47.355 F300.000
+G1 X52.917 Y46.914 F300.000
+G1 X54.193 Y46.220 F300.000
+G1 X55.303 Y45.303 F300.000
+G1 X56.220 Y44.193 F300.000
+G1 X56.914 Y42.917 F300.000
+G1 X57.355 Y41.508 F300.000
+G1 X57.508 Y40.000 F300.000
+G1 X57.355 Y38.492 F300.000
+G1 X56.914 Y37.083 F300.000
+G1 X56.220 Y35.807 F300.000
+G1 X55.303 Y34.697 F300.000
+G1 X54.193 Y33.780 F300.000
+G1 X52.917 Y33.086 F300.000
+G1 X51.508 Y32.645 F300.000
+G1 X50.000 Y32.492 F300.000
+G1 X48.492 Y32.645 F300.000
+G1 X47.083 Y33.086 F300.000
+G1 X45.807 Y33.780 F300.000
+G1 X44.697 Y34.697 F300.000
+G1 X43.780 Y35.807 F300.000
+G1 X43.086 Y37.083 F300.000
+G1 X42.645 Y38.492 F300.000
+G1 X42.492 Y40.000 F300.000
+G1 X42.645 Y41.508 F300.000
+G1 X43.086 Y42.917 F300.000
+G1 X43.780 Y44.193 F300.000
+G1 X44.697 Y45.303 F300.000
+G1 X45.807 Y46.220 F300.000
+G1 X47.083 Y46.914 F300.000
+G1 X48.492 Y47.355 F300.000
+G1 X50.000 Y47.508 F300.000
+G0 Z5.000
+G1 Z-1.000 F120.000
+G1 X51.508 Y47.355 F300.000
+G1 X52.917 Y46.914 F300.000
+G1 X54.193 Y46.220 F300.000
+G1 X55.303 Y45.303 F300.000
+G1 X56.220 Y44.193 F300.000
+G1 X56.914 Y42.917 F300.000
+G1 X57.355 Y41.508 F300.000
+G1 X57.508 Y40.000 F300.000
+G1 X57.355 Y38.492 F300.000
+G1 X56.914 Y37.083 F300.000
+G1 X56.220 Y35.807 F300.000
+G1 X55.303 Y34.697 F300.000
+G1 X54.193 Y33.780 F300.000
+G1 X52.917 Y33.086 F300.000
+G1 X51.508 Y32.645 F300.000
+G1 X50.000 Y32.492 F300.000
+G1 X48.492 Y32.645 F300.000
+G1 X47.083 Y33.086 F300.000
+G1 X45.807 Y33.780 F300.000
+G1 X44.697 Y34.697 F300.000
+G1 X43.780 Y35.807 F300.000
+G1 X43.086 Y37.083 F300.000
+G1 X42.645 Y38.492 F300.000
+G1 X42.492 Y40.000 F300.000
+G1 X42.645 Y41.508 F300.000
+G1 X43.086 Y42.917 F300.000
+G1 X43.780 Y44.193 F300.000
+G1 X44.697 Y45.303 F300.000
+G1 X45.807 Y46.220 F300.000
+G1 X47.083 Y46.914 F300.000
+G1 X48.492 Y47.355 F300.000
+G1 X50.000 Y47.508 F300.000
+G0 Z5.000
+
+M5  (spindle off, if relevant)
+M2  (program end)
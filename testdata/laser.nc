@@ -0,0 +1,69 @@
+(Generated by svg2gcode)
+G21  (units in mm)
+G90  (absolute coordinates)
+M4 S0  (laser on, power set by each move)
+
+; Path 1 stroke=""
+; image
+; raster from a 8 x 4 pixel image at 51 dpi
+G0 X10.000 Y18.750
+G1 X12.000 Y18.750 F300.000 S0
+G1 X13.000 Y18.750 F300.000 S247.059
+G1 X14.000 Y18.750 F300.000 S498.039
+G1 X15.000 Y18.750 F300.000 S749.02
+G1 X16.000 Y18.750 F300.000 S1000
+G1 X18.000 Y18.750 F300.000 S0
+G0 X18.000 Y18.250
+G1 X16.000 Y18.250 F300.000 S0
+G1 X15.000 Y18.250 F300.000 S1000
+G1 X14.000 Y18.250 F300.000 S749.02
+G1 X13.000 Y18.250 F300.000 S498.039
+G1 X12.000 Y18.250 F300.000 S247.059
+G1 X10.000 Y18.250 F300.000 S0
+G0 X10.000 Y17.750
+G1 X12.000 Y17.750 F300.000 S0
+G1 X13.000 Y17.750 F300.000 S247.059
+G1 X14.000 Y17.750 F300.000 S498.039
+G1 X15.000 Y17.750 F300.000 S749.02
+G1 X16.000 Y17.750 F300.000 S1000
+G1 X18.000 Y17.750 F300.000 S0
+G0 X18.000 Y17.250
+G1 X16.000 Y17.250 F300.000 S0
+G1 X15.000 Y17.250 F300.000 S1000
+G1 X14.000 Y17.250 F300.000 S749.02
+G1 X13.000 Y17.250 F300.000 S498.039
+G1 X12.000 Y17.250 F300.000 S247.059
+G1 X10.000 Y17.250 F300.000 S0
+G0 X10.000 Y16.750
+G1 X12.000 Y16.750 F300.000 S0
+G1 X13.000 Y16.750 F300.000 S247.059
+G1 X14.000 Y16.750 F300.000 S498.039
+G1 X15.000 Y16.750 F300.000 S749.02
+G1 X16.000 Y16.750 F300.000 S1000
+G1 X18.000 Y16.750 F300.000 S0
+G0 X18.000 Y16.250
+G1 X16.000 Y16.250 F300.000 S0
+G1 X15.000 Y16.250 F300.000 S1000
+G1 X14.000 Y16.250 F300.000 S749.02
+G1 X13.000 Y16.250 F300.000 S498.039
+G1 X12.000 Y16.250 F300.000 S247.059
+G1 X10.000 Y16.250 F300.000 S0
+
+; Path 2 stroke="#000000"
+G0 X2.000 Y28.000
+G1 X38.000 Y28.000 F300.000 S1000
+G1 X38.000 Y2.000 F300.000 S1000
+G1 X2.000 Y2.000 F300.000 S1000
+G1 X2.000 Y28.000 F300.000 S1000
+G1 X38.000 Y28.000 F300.000 S1000
+G1 X38.000 Y2.000 F300.000 S1000
+G1 X2.000 Y2.000 F300.000 S1000
+G1 X2.000 Y28.000 F300.000 S1000
+
+; Path 3 stroke="#ff0000"
+G0 X22.000 Y20.000
+G1 X34.000 Y20.000 F300.000 S1000
+G1 X34.000 Y10.000 F300.000 S1000
+
+M5  (spindle off, if relevant)
+M2  (program end)
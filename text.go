@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontSet resolves SVG font-family names to loaded fonts.
+type FontSet struct {
+	Default  *sfnt.Font            // used when no family matches; may be nil
+	ByFamily map[string]*sfnt.Font // keyed by lower-case family name
+
+	Stroke *HersheyFont // when set, all text is engraved single-stroke with it
+}
+
+// loadFonts loads the -font file and every TrueType/OpenType font in the
+// -font-dir directory. Either may be empty.
+func loadFonts(defaultPath, dir string) (*FontSet, error) {
+	fs := &FontSet{ByFamily: map[string]*sfnt.Font{}}
+	if defaultPath != "" {
+		f, err := loadFont(defaultPath)
+		if err != nil {
+			return nil, err
+		}
+		fs.Default = f
+		fs.add(f)
+	}
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".ttf", ".otf":
+			default:
+				continue
+			}
+			f, err := loadFont(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			fs.add(f)
+		}
+	}
+	return fs, nil
+}
+
+func loadFont(path string) (*sfnt.Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (fs *FontSet) add(f *sfnt.Font) {
+	name, err := f.Name(nil, sfnt.NameIDFamily)
+	if err != nil || name == "" {
+		return
+	}
+	key := strings.ToLower(name)
+	old, ok := fs.ByFamily[key]
+	// -font always wins; otherwise prefer the upright weight of a family
+	// over bold or italic files that share its name
+	if !ok || (old != fs.Default && !isRegular(old) && isRegular(f)) {
+		fs.ByFamily[key] = f
+	}
+}
+
+func isRegular(f *sfnt.Font) bool {
+	sub, _ := f.Name(nil, sfnt.NameIDSubfamily)
+	switch strings.ToLower(sub) {
+	case "regular", "book", "normal", "roman":
+		return true
+	}
+	return false
+}
+
+// lookup picks the first family in a CSS font-family list that is
+// loaded, falling back to the default font.
+func (fs *FontSet) lookup(families string) *sfnt.Font {
+	if fs == nil {
+		return nil
+	}
+	for _, fam := range strings.Split(families, ",") {
+		fam = strings.ToLower(strings.Trim(strings.TrimSpace(fam), `"'`))
+		if f, ok := fs.ByFamily[fam]; ok {
+			return f
+		}
+	}
+	return fs.Default
+}
+
+// textOutlines converts a run of text to closed glyph outlines in SVG user
+// units. (x, y) is the baseline anchor point; anchor is the SVG
+// text-anchor (start, middle, end).
+func textOutlines(f *sfnt.Font, s string, x, y, size float64, anchor string) ([][]Point, error) {
+	var buf sfnt.Buffer
+	// Load at one pixel per font unit so no precision is lost, then scale.
+	ppem := fixed.I(int(f.UnitsPerEm()))
+	scale := size / float64(f.UnitsPerEm()) / 64
+	flatness := min(0.1, size/200)
+
+	type placed struct {
+		idx sfnt.GlyphIndex
+		x   fixed.Int26_6
+	}
+	var glyphs []placed
+	var pen fixed.Int26_6
+	prev := sfnt.GlyphIndex(0)
+	for _, r := range s {
+		idx, err := f.GlyphIndex(&buf, r)
+		if err != nil {
+			return nil, err
+		}
+		if prev != 0 {
+			if k, err := f.Kern(&buf, prev, idx, ppem, font.HintingNone); err == nil {
+				pen += k
+			}
+		}
+		glyphs = append(glyphs, placed{idx, pen})
+		adv, err := f.GlyphAdvance(&buf, idx, ppem, font.HintingNone)
+		if err != nil {
+			return nil, err
+		}
+		pen += adv
+		prev = idx
+	}
+
+	switch anchor {
+	case "middle":
+		x -= float64(pen) * scale / 2
+	case "end":
+		x -= float64(pen) * scale
+	}
+
+	var out [][]Point
+	for _, g := range glyphs {
+		segs, err := f.LoadGlyph(&buf, g.idx, ppem, nil)
+		if err != nil {
+			return nil, err
+		}
+		pt := func(p fixed.Point26_6) Point {
+			// sfnt outlines already have Y increasing downwards, like SVG
+			return Point{X: x + float64(g.x+p.X)*scale, Y: y + float64(p.Y)*scale}
+		}
+		var cur []Point
+		closeContour := func() {
+			if len(cur) > 2 {
+				if !almostEqualPoint(cur[0], cur[len(cur)-1]) {
+					cur = append(cur, cur[0])
+				}
+				out = append(out, dedupePoints(cur))
+			}
+			cur = nil
+		}
+		for _, seg := range segs {
+			switch seg.Op {
+			case sfnt.SegmentOpMoveTo:
+				closeContour()
+				cur = []Point{pt(seg.Args[0])}
+			case sfnt.SegmentOpLineTo:
+				cur = append(cur, pt(seg.Args[0]))
+			case sfnt.SegmentOpQuadTo:
+				p0, q, p2 := cur[len(cur)-1], pt(seg.Args[0]), pt(seg.Args[1])
+				c1 := lerp(p0, q, 2.0/3)
+				c2 := lerp(p2, q, 2.0/3)
+				flattenCubicBezier(p0, c1, c2, p2, flatness, &cur)
+			case sfnt.SegmentOpCubeTo:
+				p0 := cur[len(cur)-1]
+				flattenCubicBezier(p0, pt(seg.Args[0]), pt(seg.Args[1]), pt(seg.Args[2]), flatness, &cur)
+			}
+		}
+		closeContour()
+	}
+	return out, nil
+}
+
+// styleProp returns a property from an inline style attribute.
+func styleProp(style, name string) string {
+	for _, p := range strings.Split(style, ";") {
+		k, v, ok := strings.Cut(p, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), name) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// parseFontSize reads a font-size in user units. px and unitless values
+// are user units; pt is converted at 96 dpi.
+func parseFontSize(s string) float64 {
+	s = strings.TrimSpace(s)
+	factor := 1.0
+	if v, ok := strings.CutSuffix(s, "px"); ok {
+		s = v
+	} else if v, ok := strings.CutSuffix(s, "pt"); ok {
+		s, factor = v, 96.0/72
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return v * factor
+}
+
+// firstCoord reads the first number of an x/y attribute, which SVG allows
+// to be a per-character list.
+func firstCoord(s string) float64 {
+	f := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(f) == 0 {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(f[0], 64)
+	return v
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// MoveKind says how a Move is emitted.
+type MoveKind int
+
+const (
+	MoveRapid   MoveKind = iota // G0
+	MoveFeed                    // G1
+	MoveComment                 // ; text
+	MoveRaw                     // text emitted verbatim (M-codes, blank lines)
+	MoveArcCW                   // G2, centre at I/J relative to the start
+	MoveArcCCW                  // G3
+)
+
+// Axis flags record which words a motion move sets.
+const (
+	AxisX = 1 << iota
+	AxisY
+	AxisZ
+	AxisS // laser power
+)
+
+// Move is one line of the planned program. X, Y and Z always hold the
+// full machine position after the move, even for axes the move does not
+// set, so later stages can inspect the program without tracking state.
+type Move struct {
+	Kind    MoveKind
+	Axes    int
+	X, Y, Z float64
+	I, J    float64 // arc centre offset from the start point, arcs only
+	F       float64 // feed rate for MoveFeed and arcs, mm/min
+	S       float64 // laser power (S word) of a move with AxisS
+	Text    string  // MoveComment / MoveRaw
+	Path    int     // Path.Index this move belongs to, 0 = none
+	Op      string  // operation that produced the move: "cut", "score", "finish", "bore", "drill", "slot", "vcarve", "raster"
+}
+
+// isMotion reports whether the move moves the machine.
+func (m Move) isMotion() bool {
+	return m.Kind == MoveRapid || m.Kind == MoveFeed || m.isArc()
+}
+
+func (m Move) isArc() bool {
+	return m.Kind == MoveArcCW || m.Kind == MoveArcCCW
+}
+
+// arcGeometry returns the centre, radius and unsigned sweep (radians) of
+// an arc move starting at from. Coincident start and end points make a
+// full circle.
+func arcGeometry(from, m Move) (c Point, r, sweep float64) {
+	c = Point{X: from.X + m.I, Y: from.Y + m.J}
+	r = math.Hypot(m.I, m.J)
+	a0 := math.Atan2(from.Y-c.Y, from.X-c.X)
+	a1 := math.Atan2(m.Y-c.Y, m.X-c.X)
+	if m.Kind == MoveArcCW {
+		a0, a1 = a1, a0
+	}
+	sweep = math.Mod(a1-a0+4*math.Pi, 2*math.Pi)
+	if sweep < 1e-9 {
+		sweep = 2 * math.Pi
+	}
+	return c, r, sweep
+}
+
+// Program builds a list of moves while tracking the machine position.
+// The XY position is unknown until the first XY move, and both XY and Z
+// become unknown again after raw G-code, which may move the machine or
+// pause for the operator to jog it.
+type Program struct {
+	Moves []Move
+
+	x, y, z float64
+	xyKnown bool
+	zKnown  bool
+	path    int
+	op      string
+}
+
+func newProgram(startZ float64) *Program {
+	return &Program{z: startZ, zKnown: true}
+}
+
+func (p *Program) add(kind MoveKind, axes int, f float64) {
+	p.Moves = append(p.Moves, Move{Kind: kind, Axes: axes, X: p.x, Y: p.y, Z: p.z, F: f, Path: p.path, Op: p.op})
+}
+
+func (p *Program) RapidXY(x, y float64) {
+	p.x, p.y, p.xyKnown = x, y, true
+	p.add(MoveRapid, AxisX|AxisY, 0)
+}
+
+func (p *Program) RapidZ(z float64) {
+	p.z, p.zKnown = z, true
+	p.add(MoveRapid, AxisZ, 0)
+}
+
+func (p *Program) FeedXY(x, y, f float64) {
+	p.x, p.y, p.xyKnown = x, y, true
+	p.add(MoveFeed, AxisX|AxisY, f)
+}
+
+func (p *Program) FeedZ(z, f float64) {
+	p.z, p.zKnown = z, true
+	p.add(MoveFeed, AxisZ, f)
+}
+
+func (p *Program) FeedXYZ(x, y, z, f float64) {
+	p.x, p.y, p.z = x, y, z
+	p.xyKnown, p.zKnown = true, true
+	p.add(MoveFeed, AxisX|AxisY|AxisZ, f)
+}
+
+// FeedXYS feeds to (x, y) with the laser at power s.
+func (p *Program) FeedXYS(x, y, f, s float64) {
+	p.x, p.y, p.xyKnown = x, y, true
+	p.add(MoveFeed, AxisX|AxisY|AxisS, f)
+	p.Moves[len(p.Moves)-1].S = s
+}
+
+// ArcXY feeds along an arc around the centre (cx, cy) to (x, y), moving
+// Z linearly to z on the way, which makes a helix when z differs.
+func (p *Program) ArcXY(cw bool, x, y, z, cx, cy, f float64) {
+	kind := MoveArcCCW
+	if cw {
+		kind = MoveArcCW
+	}
+	i, j := cx-p.x, cy-p.y
+	p.x, p.y, p.z = x, y, z
+	p.xyKnown, p.zKnown = true, true
+	p.Moves = append(p.Moves, Move{Kind: kind, Axes: AxisX | AxisY | AxisZ, X: x, Y: y, Z: z, I: i, J: j, F: f, Path: p.path, Op: p.op})
+}
+
+func (p *Program) Comment(text string) {
+	p.Moves = append(p.Moves, Move{Kind: MoveComment, Text: text, X: p.x, Y: p.y, Z: p.z, Path: p.path})
+}
+
+// Raw adds a line of G-code as is. Anything but a blank line or a
+// comment makes the position unknown, so the next hop goes to SafeZ and
+// rapids to its target even when the tracked position is already there.
+func (p *Program) Raw(text string) {
+	p.Moves = append(p.Moves, Move{Kind: MoveRaw, Text: text, X: p.x, Y: p.y, Z: p.z, Path: p.path})
+	if t := strings.TrimSpace(text); t != "" && t[0] != '(' && t[0] != ';' {
+		p.xyKnown, p.zKnown = false, false
+	}
+}
+
+// passDepths returns the Z level of every depth pass, stepping down from
+// the stock top (Z0) by step until targetZ. step <= 0 means one pass.
+func passDepths(targetZ, step float64) []float64 {
+	step = math.Abs(step)
+	if step <= 0 {
+		return []float64{targetZ}
+	}
+	var zs []float64
+	z := 0.0
+	for {
+		z -= step
+		if z <= targetZ+1e-9 {
+			return append(zs, targetZ)
+		}
+		zs = append(zs, z)
+	}
+}
+
+// depthFeed scales feed linearly with depth so that a pass at targetZ runs
+// at feed*factor and a pass at the surface at the full feed. factor <= 0
+// or >= 1 leaves the feed alone.
+func depthFeed(feed, z, targetZ, factor float64) float64 {
+	if factor <= 0 || factor >= 1 || targetZ >= 0 {
+		return feed
+	}
+	frac := math.Min(1, z/targetZ)
+	return feed * (1 - (1-factor)*frac)
+}
+
+// retractZ is the height the tool lifts to after cutting: RetractZ when
+// set, otherwise SafeZ.
+func (cfg Config) retractZ() float64 {
+	if cfg.RetractZ > 0 {
+		return cfg.RetractZ
+	}
+	return cfg.SafeZ
+}
+
+// hop retracts and rapids to (x, y), leaving the tool there ready to
+// plunge. Every move between cuts goes through hop, so the order is always
+// retract, rapid XY, plunge, with no Z or XY move that goes nowhere.
+// Hops of at most RetractDist stay at the retract height; longer ones, or
+// all of them without -retract-z, go up to SafeZ, so the tool never
+// crosses far over stock or fixtures at the lower height. While the
+// position is unknown hop always lifts and rapids.
+func hop(prog *Program, x, y float64, cfg Config) {
+	z := cfg.SafeZ
+	d := math.Hypot(x-prog.x, y-prog.y)
+	if cfg.RetractZ > 0 && prog.xyKnown && prog.zKnown && d <= cfg.RetractDist {
+		z = cfg.RetractZ
+	}
+	if prog.z < z || !prog.zKnown {
+		prog.RapidZ(z)
+	}
+	if d > 0 || !prog.xyKnown {
+		prog.RapidXY(x, y)
+	}
+	if prog.z > z {
+		// coming from higher up: drop to the retract height over the
+		// target rather than plunging all the way at the plunge feed
+		prog.RapidZ(z)
+	}
+}
+
+// liftSafe raises the tool to SafeZ unless it is there already.
+func liftSafe(prog *Program, cfg Config) {
+	if prog.z < cfg.SafeZ {
+		prog.RapidZ(cfg.SafeZ)
+	}
+}
+
+// postPath lifts the tool out of the cut and runs the -post-path hook,
+// if there is one.
+func postPath(prog *Program, cfg Config) {
+	if cfg.Hooks.PostPath == "" {
+		return
+	}
+	if prog.z < cfg.retractZ() {
+		prog.RapidZ(cfg.retractZ())
+	}
+	inject(prog, cfg.Hooks.PostPath)
+}
+
+// feedAlong cuts from pts[0], where the tool is, along the rest of pts
+// at z. With -join arc the round corners compensation added are cut as
+// G2/G3 arcs instead of the short lines they were flattened into.
+func feedAlong(prog *Program, pts []Point, z, f float64, cfg Config) {
+	var radii []float64
+	if cfg.Join.Style == "arc" && cfg.ToolDia > 0 {
+		radii = []float64{cfg.ToolDia / 2, cfg.ToolDia/2 + cfg.FinishAllowance}
+	}
+	for i := 1; i < len(pts); i++ {
+		if radii != nil {
+			if j, a := arcRun(pts, i-1, radii, 0.01); j > i-1 {
+				prog.ArcXY(a.Sweep < 0, pts[j].X, pts[j].Y, z, a.Center.X, a.Center.Y, f)
+				i = j
+				continue
+			}
+		}
+		prog.FeedXY(pts[i].X, pts[i].Y, f)
+	}
+}
+
+// planPaths turns prepared machine-space paths into cutting moves.
+func planPaths(prog *Program, paths []Path, cfg Config) {
+	defaultWCS := cfg.WCS
+	if defaultWCS == "" {
+		defaultWCS = "G54" // power-on default on every common controller
+	}
+	wcs := defaultWCS
+	stop := "M0"
+	if cfg.OptionalStop {
+		stop = "M1"
+	}
+	entry := cfg.Entry
+	if entry == nil {
+		entry = straightEntry{}
+	}
+	// operations in cutting order, and the drawing paths in each, for
+	// the section headers when -operations grouped the job
+	var ops []string
+	opPaths := map[string]map[int]bool{}
+	for _, p := range paths {
+		if p.Operation == "" || len(p.Points) == 0 {
+			continue
+		}
+		if opPaths[p.Operation] == nil {
+			ops = append(ops, p.Operation)
+			opPaths[p.Operation] = map[int]bool{}
+		}
+		opPaths[p.Operation][p.Index] = true
+	}
+	if cfg.ResumeFrom > 1 {
+		prog.Comment(cfg.Msg.T("resume", cfg.ResumeFrom))
+	}
+	prevOp := ""
+	prevStroke := ""
+	first := true
+	for idx, p := range paths {
+		if len(p.Points) == 0 || idx+1 < cfg.ResumeFrom {
+			continue
+		}
+		if !first {
+			postPath(prog, cfg)
+		}
+		prog.path = p.Index
+		prog.Raw("")
+		if p.Operation != prevOp {
+			prevOp = p.Operation
+			prog.Comment(cfg.Msg.T("operation", slices.Index(ops, p.Operation)+1, len(ops),
+				p.Operation, len(opPaths[p.Operation])))
+		}
+
+		// Pauses happen at safe Z, before moving to the next path.
+		rule := p.rule(cfg)
+		if rule.Pause != "" && (first || p.Stroke != prevStroke) {
+			liftSafe(prog, cfg)
+			prog.Raw(fmt.Sprintf("%s  (%s)", stop, commentSafe(rule.Pause)))
+		} else if cfg.PauseBetweenPaths && !first {
+			liftSafe(prog, cfg)
+			prog.Raw(fmt.Sprintf("%s  (%s)", stop, cfg.Msg.T("pause")))
+		}
+		prevStroke = p.Stroke
+		first = false
+
+		prog.Comment(cfg.Msg.T("path", idx+1, p.Stroke))
+		if p.Label != "" {
+			prog.Comment(commentSafe(p.Label))
+		}
+
+		prog.op = rule.Op
+		want := rule.WCS
+		if want == "" {
+			want = defaultWCS
+		}
+		if want != wcs {
+			// switch fixtures at safe height, then re-establish it there
+			wcs = want
+			liftSafe(prog, cfg)
+			prog.Raw(fmt.Sprintf("%s  (%s)", wcs, cfg.Msg.T("wcs")))
+			if !cfg.Laser.Enabled {
+				prog.RapidZ(cfg.SafeZ)
+			}
+		}
+		inject(prog, cfg.Hooks.PrePath)
+		if p.Power != nil {
+			planRaster(prog, p, cfg)
+			continue
+		}
+		if cfg.Laser.Enabled {
+			planBeam(prog, p, rule, cfg)
+			continue
+		}
+		if p.Hole != nil {
+			planBore(prog, *p.Hole, rule.targetZ(cfg), cfg)
+			continue
+		}
+		if p.Slot != nil {
+			planSlot(prog, *p.Slot, p.Points[0], rule.targetZ(cfg), cfg)
+			continue
+		}
+		if p.Feeds != nil {
+			planPosted(prog, p, cfg)
+			continue
+		}
+		if p.Image != nil {
+			planRelief(prog, p, cfg)
+			continue
+		}
+		if p.Depths != nil {
+			planVCarve(prog, p, cfg)
+			continue
+		}
+
+		feed, plunge := rule.feeds(cfg)
+		var depths []float64
+		if p.Finish {
+			prog.op = "finish"
+			// roughing already cleared the depth; one light pass at full depth
+			depths = []float64{rule.targetZ(cfg)}
+			if cfg.FinishFeed > 0 {
+				feed = cfg.FinishFeed
+			}
+			prog.Comment(cfg.Msg.T("finish"))
+		} else if rule.Op == "score" {
+			// scores are shallow by nature: one pass unless the rule
+			// asks for a step-down of its own
+			z := rule.targetZ(cfg)
+			depths = passDepths(z, rule.StepDown)
+			if rule.DepthPct > 0 {
+				prog.Comment(cfg.Msg.T("score_pct", -z, rule.DepthPct))
+			} else {
+				prog.Comment(cfg.Msg.T("score", -z))
+			}
+		} else {
+			step := cfg.StepDown
+			if rule.StepDown > 0 {
+				step = rule.StepDown
+			}
+			depths = passDepths(rule.targetZ(cfg), step)
+		}
+
+		x0, y0 := p.Points[0].X, p.Points[0].Y
+		comp := p.CompSide != "" && len(p.Points) >= 2
+		var leadOut Point
+		if comp {
+			// every pass starts and ends in the waste, off the path
+			var leadIn Point
+			leadIn, leadOut = compLeads(p.Points, p.CompSide, cfg.CompLead)
+			x0, y0 = leadIn.X, leadIn.Y
+		}
+		hop(prog, x0, y0, cfg)
+
+		target := depths[len(depths)-1]
+		if cfg.SpringPass && prog.op != "score" {
+			// same depth again to take off what tool deflection left
+			depths = append(depths, target)
+		}
+		pts := p.Points
+		for n, z := range depths {
+			if n > 0 {
+				if cfg.PingPong && !p.Closed && !comp {
+					// already at the far end: step down there and cut back
+					pts = reversePoints(pts)
+				} else {
+					hop(prog, x0, y0, cfg)
+				}
+			}
+			if cfg.SpringPass && n == len(depths)-1 && n > 0 {
+				prog.Comment(cfg.Msg.T("spring"))
+			}
+			f := feed
+			if prog.op == "cut" {
+				f = depthFeed(feed, z, target, cfg.FeedDepthFactor)
+			}
+			if comp {
+				planCompPass(prog, pts, p.CompSide, leadOut, z, plunge, f, cfg)
+				continue
+			}
+			prev := 0.0
+			if n > 0 {
+				prev = depths[n-1]
+			}
+			entry.Enter(prog, Entry{
+				Points: pts, Closed: p.Closed,
+				Z: z, Prev: prev, Target: target, First: n == 0,
+				Plunge: plunge, Feed: f, Comp: p.Comp,
+			})
+			feedAlong(prog, pts, z, f, cfg)
+		}
+		// the next hop lifts the tool as far as it needs to go
+	}
+	if !first {
+		postPath(prog, cfg)
+	}
+	prog.path = 0
+	prog.op = ""
+	liftSafe(prog, cfg)
+}
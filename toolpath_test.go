@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io"
+	"math"
+	"testing"
+)
+
+func testConfig() Config {
+	return Config{
+		SafeZ: 5, CutDepth: -2, StepDown: 1, CutFeed: 300, PlungeFeed: 100,
+		Scale: 1, Compensation: "none", Units: "mm",
+		Warn: &Warnings{Out: io.Discard},
+	}
+}
+
+func testPaths() []Path {
+	return []Path{
+		{Index: 1, Closed: true, Points: []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 0, Y: 0}}},
+		{Index: 2, Points: []Point{{X: 12, Y: 0}, {X: 20, Y: 5}}},
+		{Index: 3, Points: []Point{{X: 80, Y: 80}, {X: 90, Y: 80}}},
+	}
+}
+
+// checkOrdering fails unless every move between cuts runs retract,
+// rapid XY, plunge: rapids in XY only at or above the retract height,
+// plunges only where a path or pass starts, and no move that goes nowhere.
+// The XY position is unknown until the first XY move and after raw G-code,
+// so a plunge needs an XY move first.
+func checkOrdering(t *testing.T, moves []Move, paths []Path, cfg Config) {
+	t.Helper()
+	starts := map[Point]bool{}
+	for _, p := range paths {
+		starts[p.Points[0]] = true
+	}
+	from := Move{Z: cfg.SafeZ}
+	known := false
+	for i, m := range moves {
+		if m.Kind == MoveRaw && m.Text != "" {
+			known = false
+		}
+		if !m.isMotion() {
+			continue
+		}
+		switch {
+		case m.Axes&(AxisX|AxisY) == 0 && m.Z < from.Z && !known:
+			t.Errorf("move %d plunges before an XY move to X%g Y%g", i, m.X, m.Y)
+		case known && m.X == from.X && m.Y == from.Y && m.Z == from.Z:
+			t.Errorf("move %d goes nowhere: %+v", i, m)
+		case m.Kind == MoveRapid && m.Axes&(AxisX|AxisY) != 0:
+			if from.Z < cfg.retractZ()-1e-9 {
+				t.Errorf("move %d rapids in XY at Z%g, below the retract height", i, from.Z)
+			}
+			if !known && from.Z < cfg.SafeZ-1e-9 {
+				t.Errorf("move %d rapids to an unknown position at Z%g, below safe Z", i, from.Z)
+			}
+			if m.Axes&AxisZ != 0 {
+				t.Errorf("move %d rapids in XY and Z at once", i)
+			}
+		case m.Kind == MoveFeed && m.Axes == AxisZ && m.Z < from.Z:
+			if !starts[Point{X: m.X, Y: m.Y}] {
+				t.Errorf("move %d plunges at X%g Y%g, not the start of a path", i, m.X, m.Y)
+			}
+		}
+		if m.Axes&(AxisX|AxisY) != 0 {
+			known = true
+		}
+		from = m
+	}
+}
+
+func TestPlanPathsRetractRapidPlunge(t *testing.T) {
+	cfg := testConfig()
+	paths := testPaths()
+	prog := newProgram(cfg.SafeZ)
+	planPaths(prog, paths, cfg)
+	checkOrdering(t, prog.Moves, paths, cfg)
+
+	// the job ends with one lift to safe Z and nothing after it
+	var motions []Move
+	for _, m := range prog.Moves {
+		if m.isMotion() {
+			motions = append(motions, m)
+		}
+	}
+	last, prev := motions[len(motions)-1], motions[len(motions)-2]
+	if last.Kind != MoveRapid || last.Axes != AxisZ || last.Z != cfg.SafeZ {
+		t.Fatalf("job ends with %+v, want a rapid to Z%g", last, cfg.SafeZ)
+	}
+	if prev.Z > 0 {
+		t.Fatalf("tool lifts twice at the end: %+v then %+v", prev, last)
+	}
+}
+
+func TestPlanPathsRetractHeight(t *testing.T) {
+	cfg := testConfig()
+	cfg.RetractZ, cfg.RetractDist = 1, 20
+	paths := testPaths()
+	prog := newProgram(cfg.SafeZ)
+	planPaths(prog, paths, cfg)
+	checkOrdering(t, prog.Moves, paths, cfg)
+
+	// the height the first XY rapid to each point travels at; later ones
+	// are the short hops back between passes
+	at := map[Point]float64{}
+	z := cfg.SafeZ
+	for _, m := range prog.Moves {
+		to := Point{X: m.X, Y: m.Y}
+		if _, seen := at[to]; !seen && m.Kind == MoveRapid && m.Axes&(AxisX|AxisY) != 0 {
+			at[to] = z
+		}
+		if m.isMotion() {
+			z = m.Z
+		}
+	}
+	for _, tc := range []struct {
+		to   Point
+		want float64
+	}{
+		{paths[1].Points[0], cfg.RetractZ}, // 2 mm from the square: stays low
+		{paths[2].Points[0], cfg.SafeZ},    // far away: goes up to safe Z
+	} {
+		got, ok := at[tc.to]
+		if !ok {
+			t.Errorf("no rapid to X%g Y%g", tc.to.X, tc.to.Y)
+		} else if math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("rapid to X%g Y%g at Z%g, want Z%g", tc.to.X, tc.to.Y, got, tc.want)
+		}
+	}
+}
+
+// After raw G-code the tracked position means nothing: a path that starts
+// where the last one ended still gets a rapid to its start from safe Z.
+func TestPlanPathsUnknownPosition(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(cfg *Config)
+	}{
+		{"start", func(cfg *Config) {}},
+		{"pre-path hook", func(cfg *Config) { cfg.Hooks.PrePath = "G0 X50 Y50" }},
+		{"wcs change", func(cfg *Config) { cfg.ColorMap = ColorMap{"#ff0000": {Op: "cut", WCS: "G55"}} }},
+		{"retract height", func(cfg *Config) { cfg.RetractZ, cfg.RetractDist, cfg.Hooks.PrePath = 1, 20, "M8" }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			tc.setup(&cfg)
+			// the second path starts where the first ends, at X0 Y0
+			paths := []Path{
+				{Index: 1, Points: []Point{{X: 10, Y: 0}, {X: 0, Y: 0}}},
+				{Index: 2, Stroke: "#ff0000", Points: []Point{{X: 0, Y: 0}, {X: 0, Y: 10}}},
+			}
+			if tc.name == "start" {
+				paths = paths[1:]
+			}
+			prog := newProgram(cfg.SafeZ)
+			planPaths(prog, paths, cfg)
+			checkOrdering(t, prog.Moves, paths, cfg)
+
+			var rapid bool
+			for _, m := range prog.Moves {
+				if m.Path != paths[len(paths)-1].Index {
+					continue
+				}
+				if m.Kind == MoveRapid && m.Axes&(AxisX|AxisY) != 0 && m.X == 0 && m.Y == 0 && m.Z == cfg.SafeZ {
+					rapid = true
+				}
+			}
+			if !rapid {
+				t.Errorf("no rapid to X0 Y0 at safe Z before the last path")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import "math"
+
+// Trochoid configures trochoidal slotting: instead of ploughing a full
+// width slot, the tool circles along the path, taking a thin chip on
+// each loop. The slot comes out Width wide (more than the tool) and the
+// loops advance Step along the path per revolution.
+type Trochoid struct {
+	Width float64 // finished slot width, mm; > tool diameter
+	Step  float64 // advance per loop, mm
+}
+
+// loopRadius is the radius the tool centre circles at.
+func (t Trochoid) loopRadius(toolDia float64) float64 {
+	return (t.Width - toolDia) / 2
+}
+
+// trochoidPaths replaces full-width cuts with trochoidal loops: open and
+// uncompensated paths (slots) and fill lines (pockets, which fillPaths
+// already inset by half the slot width). Profiles, holes, v-carves,
+// finish passes and scores keep their plain moves.
+func trochoidPaths(paths []Path, cfg Config) []Path {
+	r := cfg.Trochoid.loopRadius(cfg.ToolDia)
+	ccw := cfg.Direction != "conventional" // climb with a clockwise spindle
+	for i, p := range paths {
+		if p.Hole != nil || p.Slot != nil || p.Depths != nil || p.Power != nil || p.Finish || len(p.Points) < 2 {
+			continue
+		}
+		if p.Closed && cfg.Compensation != "none" && !p.Filled {
+			continue
+		}
+		if !p.Closed && !p.Filled && cfg.sideComp() {
+			continue // offset to one side: a profile, not a slot
+		}
+		if cfg.ColorMap.rule(p.Stroke).Op == "score" {
+			continue
+		}
+		paths[i].Points = trochoid(p.Points, r, cfg.Trochoid.Step, ccw)
+	}
+	return paths
+}
+
+// trochoid follows pts with loops of radius r around it, advancing step
+// per loop. The result starts and ends on the path, so the plunge and
+// the next pass begin where they would without loops.
+func trochoid(pts []Point, r, step float64, ccw bool) []Point {
+	total := polylineLength(pts)
+	if total < 1e-9 || r <= 0 || step <= 0 {
+		return pts
+	}
+	loops := math.Max(1, math.Ceil(total/step))
+	// chord error of at most 0.01 mm, and never fewer than 12 per loop
+	perLoop := 12.0
+	if r > 0.01 {
+		perLoop = math.Max(perLoop, math.Ceil(math.Pi/math.Acos(1-0.01/r)))
+	}
+	n := int(loops * perLoop)
+
+	sign := 1.0
+	if !ccw {
+		sign = -1
+	}
+	out := []Point{pts[0]}
+	seg, segStart := 1, 0.0 // walk the polyline once as s increases
+	for k := 0; k <= n; k++ {
+		s := total * float64(k) / float64(n)
+		for seg < len(pts)-1 && segStart+dist(pts[seg-1], pts[seg]) < s {
+			segStart += dist(pts[seg-1], pts[seg])
+			seg++
+		}
+		a, b := pts[seg-1], pts[seg]
+		l := dist(a, b)
+		t := Point{X: 1}
+		c := a
+		if l > 1e-12 {
+			t = Point{X: (b.X - a.X) / l, Y: (b.Y - a.Y) / l}
+			f := math.Min(1, (s-segStart)/l)
+			c = lerp(a, b, f)
+		}
+		nrm := Point{X: -t.Y, Y: t.X}
+		// start each loop on the right of the path and circle ccw/cw
+		th := 2 * math.Pi * loops * float64(k) / float64(n)
+		sin, cos := math.Sin(th), math.Cos(th)
+		out = append(out, Point{
+			X: c.X + r*(t.X*sin*sign-nrm.X*cos),
+			Y: c.Y + r*(t.Y*sin*sign-nrm.Y*cos),
+		})
+	}
+	return append(out, pts[len(pts)-1])
+}
+
+func dist(a, b Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
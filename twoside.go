@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Two-sided jobs come from one drawing: the features on the back are
+// drawn on their own layers or colors, as seen from the front. -side
+// front cuts everything else; -side back cuts only those, mirrored
+// about the middle of the stock so they line up once the stock is
+// turned over about -flip-axis. Alignment pins drilled on that axis
+// from the front land in the same holes after the flip.
+
+// TwoSided is the -side setup. A zero Side is a one-sided job.
+type TwoSided struct {
+	Side     string       // "front" or "back"
+	Back     Construction // colors and layers that are cut from the back
+	Axis     string       // "x": turned over left to right, "y": top to bottom
+	PinDia   float64      // alignment pin holes, mm; 0 = none
+	PinInset float64      // pin distance from the stock edges, mm
+}
+
+// twoSidedPaths keeps the paths of the side being cut, mirrors the back
+// into place on the stock and adds the alignment pins to the front.
+func twoSidedPaths(paths []Path, cfg Config) ([]Path, error) {
+	ts := cfg.TwoSided
+	if ts.Side == "" {
+		return paths, nil
+	}
+	index, shape := 0, 0
+	for _, p := range paths {
+		index, shape = max(index, p.Index), max(shape, p.Shape)
+	}
+	back := ts.Side == "back"
+	out := slices.DeleteFunc(paths, func(p Path) bool {
+		return ts.Back.excludes(p) != back // on the list: a back-side path
+	})
+	if back {
+		if err := flipPaths(out, ts.Axis, cfg.Stock.W/2, cfg.Stock.H/2); err != nil {
+			return nil, err
+		}
+	}
+	if ts.PinDia <= 0 || back {
+		return out, nil
+	}
+
+	var pins []Point
+	switch ts.Axis {
+	case "x":
+		pins = []Point{{X: cfg.Stock.W / 2, Y: ts.PinInset}, {X: cfg.Stock.W / 2, Y: cfg.Stock.H - ts.PinInset}}
+	case "y":
+		pins = []Point{{X: ts.PinInset, Y: cfg.Stock.H / 2}, {X: cfg.Stock.W - ts.PinInset, Y: cfg.Stock.H / 2}}
+	default:
+		return nil, fmt.Errorf("invalid flip axis %q (must be x, y)", ts.Axis)
+	}
+	holes := make([]Path, 0, len(pins))
+	for _, c := range pins {
+		index++
+		shape++
+		r := ts.PinDia / 2
+		holes = append(holes, Path{
+			Points: circlePoints(c, r, 0.01),
+			Closed: true,
+			Index:  index,
+			Shape:  shape,
+			Label:  "alignment pin",
+			Hole:   &Hole{Center: c, R: r},
+		})
+	}
+	// first, so the dowels can go in before anything is cut loose
+	return append(holes, out...), nil
+}
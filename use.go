@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A <use> draws a copy of another element, often a <symbol> from <defs>,
+// and a nested <svg> or <symbol> sets up a viewport of its own. The parser
+// reads one stream of tokens; a <use> splices the element it refers to
+// into that stream, inside a group that carries the <use>'s position,
+// transform and style, with a <symbol> read as the nested <svg> it
+// stands for.
+
+// maxUseDepth bounds <use> inside <use>, which also stops a <use> that
+// refers to its own ancestor.
+const maxUseDepth = 32
+
+// maxUses bounds the <use> instances drawn in one document: a few groups
+// that each use the one before twice would otherwise multiply without
+// end.
+const maxUses = 100000
+
+// tokenMux reads tokens from a stack of sources, the innermost first, so
+// a spliced element is read through before the document goes on.
+type tokenMux struct {
+	srcs []xml.TokenReader
+}
+
+func (m *tokenMux) Token() (xml.Token, error) {
+	for len(m.srcs) > 0 {
+		tok, err := m.srcs[len(m.srcs)-1].Token()
+		if err == io.EOF {
+			m.srcs = m.srcs[:len(m.srcs)-1]
+			continue
+		}
+		return tok, err
+	}
+	return nil, io.EOF
+}
+
+func (m *tokenMux) push(src xml.TokenReader) {
+	m.srcs = append(m.srcs, src)
+}
+
+// tokenList is a fixed run of tokens.
+type tokenList []xml.Token
+
+func (l *tokenList) Token() (xml.Token, error) {
+	if len(*l) == 0 {
+		return nil, io.EOF
+	}
+	tok := (*l)[0]
+	*l = (*l)[1:]
+	return tok, nil
+}
+
+// indexIDs maps every id in the document to the byte range of its
+// element, so a <use> can read it again wherever it is. The first of
+// duplicate ids wins.
+func indexIDs(data []byte) map[string][2]int64 {
+	ids := map[string][2]int64{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	type open struct {
+		id    string
+		start int64
+	}
+	var stack []open
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return ids // a broken document is reported by the real parse
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			id := ""
+			for _, a := range t.Attr {
+				if a.Name.Local == "id" && a.Name.Space == "" {
+					id = a.Value
+				}
+			}
+			stack = append(stack, open{id, start})
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return ids
+			}
+			o := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if _, dup := ids[o.id]; o.id != "" && !dup {
+				ids[o.id] = [2]int64{o.start, dec.InputOffset()}
+			}
+		}
+	}
+}
+
+// useRef returns the id a <use> refers to, from href or xlink:href.
+func useRef(attrs []xml.Attr) string {
+	for _, a := range attrs {
+		if a.Name.Local == "href" {
+			return strings.TrimPrefix(strings.TrimSpace(a.Value), "#")
+		}
+	}
+	return ""
+}
+
+// useTokens is what a <use> stands for: a group with the <use>'s own
+// attributes and transform, moved by its x and y, around the element
+// data holds. A <symbol> becomes an <svg>, and both take their viewport
+// size from the <use>'s width and height when it gives them.
+func useTokens(use xml.StartElement, data []byte) xml.TokenReader {
+	var x, y, width, height, transform string
+	g := xml.StartElement{Name: xml.Name{Local: "g"}}
+	for _, a := range use.Attr {
+		switch a.Name.Local {
+		case "x":
+			x = a.Value
+		case "y":
+			y = a.Value
+		case "width":
+			width = a.Value
+		case "height":
+			height = a.Value
+		case "transform":
+			transform = a.Value
+		case "href", "id":
+		default:
+			g.Attr = append(g.Attr, a)
+		}
+	}
+	dx, _ := strconv.ParseFloat(strings.TrimSpace(x), 64)
+	dy, _ := strconv.ParseFloat(strings.TrimSpace(y), 64)
+	transform += " translate(" + strconv.FormatFloat(dx, 'g', -1, 64) + " " + strconv.FormatFloat(dy, 'g', -1, 64) + ")"
+	g.Attr = append(g.Attr, xml.Attr{Name: xml.Name{Local: "transform"}, Value: transform})
+
+	head := tokenList{g}
+	tail := tokenList{g.End()}
+	inner := &viewportRename{dec: xml.NewDecoder(bytes.NewReader(data)), width: width, height: height}
+	return &tokenMux{srcs: []xml.TokenReader{&tail, inner, &head}}
+}
+
+// viewportRename reads a referenced element, turning an outermost
+// <symbol> into an <svg> and giving it the <use>'s width and height.
+type viewportRename struct {
+	dec           *xml.Decoder
+	width, height string
+	depth         int
+	symbol        bool
+}
+
+func (v *viewportRename) Token() (xml.Token, error) {
+	tok, err := v.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case xml.StartElement:
+		v.depth++
+		if v.depth == 1 && (t.Name.Local == "symbol" || t.Name.Local == "svg") {
+			v.symbol = t.Name.Local == "symbol"
+			t.Name.Local = "svg"
+			t.Attr = setAttr(setAttr(t.Attr, "width", v.width), "height", v.height)
+			return t, nil
+		}
+		return t.Copy(), nil
+	case xml.EndElement:
+		v.depth--
+		if v.depth == 0 && v.symbol {
+			t.Name.Local = "svg"
+		}
+		return t, nil
+	}
+	return xml.CopyToken(tok), nil
+}
+
+// setAttr sets an attribute unless value is empty.
+func setAttr(attrs []xml.Attr, name, value string) []xml.Attr {
+	if value == "" {
+		return attrs
+	}
+	for i, a := range attrs {
+		if a.Name.Local == name && a.Name.Space == "" {
+			attrs[i].Value = value
+			return attrs
+		}
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// viewport is the size of the current viewport in user units, for
+// percentages.
+type viewport struct{ W, H float64 }
+
+// viewportTransform maps a nested <svg> (or <symbol> drawn by a <use>)
+// into its parent's user space: its x and y place it, and its viewBox is
+// fitted into its width and height as preserveAspectRatio says, centred
+// and scaled to fit by default. It returns the viewport the contents see.
+func viewportTransform(attrs []xml.Attr, parent viewport) (Transform, viewport) {
+	var x, y, width, height, vb, par string
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "x":
+			x = a.Value
+		case "y":
+			y = a.Value
+		case "width":
+			width = a.Value
+		case "height":
+			height = a.Value
+		case "viewBox":
+			vb = a.Value
+		case "preserveAspectRatio":
+			par = a.Value
+		}
+	}
+	px := parseLength(x, parent.W)
+	py := parseLength(y, parent.H)
+	w, h := parent.W, parent.H // 100% by default
+	if width != "" {
+		w = parseLength(width, parent.W)
+	}
+	if height != "" {
+		h = parseLength(height, parent.H)
+	}
+	t := Transform{A: 1, D: 1, E: px, F: py}
+
+	f := strings.Fields(strings.ReplaceAll(vb, ",", " "))
+	if len(f) != 4 {
+		return t, viewport{w, h}
+	}
+	var box [4]float64
+	for i := range box {
+		box[i], _ = strconv.ParseFloat(f[i], 64)
+	}
+	if box[2] <= 0 || box[3] <= 0 || w <= 0 || h <= 0 {
+		return t, viewport{box[2], box[3]}
+	}
+	sx, sy := w/box[2], h/box[3]
+	fields := strings.Fields(par)
+	align, mode := "xmidymid", "meet"
+	if len(fields) > 0 {
+		align = strings.ToLower(fields[0])
+	}
+	if len(fields) > 1 {
+		mode = strings.ToLower(fields[1])
+	}
+	if align != "none" {
+		s := math.Min(sx, sy)
+		if mode == "slice" {
+			s = math.Max(sx, sy)
+		}
+		sx, sy = s, s
+	}
+	at := func(key string, free float64) float64 {
+		switch {
+		case strings.Contains(align, key+"mid"):
+			return free / 2
+		case strings.Contains(align, key+"max"):
+			return free
+		}
+		return 0
+	}
+	t.A, t.D = sx, sy
+	t.E = px - box[0]*sx + at("x", w-box[2]*sx)
+	t.F = py - box[1]*sy + at("y", h-box[3]*sy)
+	return t, viewport{box[2], box[3]}
+}
+
+// parseLength reads an SVG length in user units: plain or px, the
+// absolute units at 96 per inch, or a percentage of ref.
+func parseLength(s string, ref float64) float64 {
+	s = strings.TrimSpace(s)
+	units := map[string]float64{"px": 1, "mm": 96 / 25.4, "cm": 96 / 2.54, "in": 96, "pt": 96.0 / 72, "pc": 16, "%": ref / 100}
+	scale := 1.0
+	for u, k := range units {
+		if v, ok := strings.CutSuffix(s, u); ok {
+			s, scale = v, k
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return v * scale
+}
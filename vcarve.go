@@ -0,0 +1,144 @@
+package main
+
+import "math"
+
+// vcarvePaths replaces closed outlines with variable-depth V-bit paths.
+//
+// For every point p sampled along the outlines, the largest circle that
+// touches the outline at p and stays inside the shape has its centre on
+// the medial axis. A V-bit whose tip sits at that centre, sunk to
+// radius/tan(half angle), cuts exactly out to p. Walking the centres in
+// outline order therefore carves the shape with sharp inner corners and
+// a depth that follows the local width. Where consecutive centres jump
+// (a branch of the medial axis ends) the walk is split into separate
+// paths so the tool lifts instead of cutting across.
+//
+// Outlines are interpreted even-odd, so letter counters (the hole in an
+// "O") are respected. maxDepth (negative) caps the depth; wide areas are
+// then only carved along their edges.
+func vcarvePaths(paths []Path, angle, maxDepth, step float64) []Path {
+	type sample struct {
+		p, n Point // point on the outline, inward unit normal
+		loop int
+	}
+	var loops []int
+	for i, p := range paths {
+		if p.Closed && len(p.Points) > 2 {
+			loops = append(loops, i)
+		}
+	}
+	if len(loops) == 0 {
+		return paths
+	}
+
+	// inward normals: the left normal points into a CCW loop; every
+	// enclosing loop flips which side is material under even-odd
+	var samples []sample
+	for li, i := range loops {
+		pts := paths[i].Points
+		ccw := signedArea(pts) > 0
+		inside := 0
+		for _, j := range loops {
+			if j != i && pointInPolygon(pts[0], paths[j].Points) {
+				inside++
+			}
+		}
+		flip := !ccw != (inside%2 == 1)
+		for k := 1; k < len(pts); k++ {
+			a, b := pts[k-1], pts[k]
+			l := math.Hypot(b.X-a.X, b.Y-a.Y)
+			if l < 1e-9 {
+				continue
+			}
+			n := Point{X: -(b.Y - a.Y) / l, Y: (b.X - a.X) / l}
+			if flip {
+				n = Point{X: -n.X, Y: -n.Y}
+			}
+			segs := int(math.Ceil(l / step))
+			for s := 0; s < segs; s++ {
+				samples = append(samples, sample{p: lerp(a, b, float64(s)/float64(segs)), n: n, loop: li})
+			}
+		}
+	}
+
+	tanHalf := math.Tan(angle / 2 * math.Pi / 180)
+	rMax := math.Inf(1)
+	if maxDepth < 0 {
+		rMax = -maxDepth * tanHalf
+	}
+
+	// carved[li] holds the pieces replacing loop li
+	carved := make([][]Path, len(loops))
+	var cur *Path
+	first := Point{}
+	pieces := 0
+	flush := func(li int) {
+		if cur != nil && len(cur.Points) > 1 {
+			carved[li] = append(carved[li], *cur)
+		}
+		cur = nil
+	}
+	for k, s := range samples {
+		r := rMax
+		for _, q := range samples {
+			d := Point{X: q.p.X - s.p.X, Y: q.p.Y - s.p.Y}
+			dn := d.X*s.n.X + d.Y*s.n.Y
+			if dn <= 1e-12 {
+				continue
+			}
+			r = math.Min(r, (d.X*d.X+d.Y*d.Y)/(2*dn))
+		}
+		if math.IsInf(r, 1) {
+			r = 0 // outline open to one side; stay on it
+		}
+		c := Point{X: s.p.X + s.n.X*r, Y: s.p.Y + s.n.Y*r}
+		z := -r / tanHalf
+
+		if k == 0 || samples[k-1].loop != s.loop {
+			first, pieces = c, 0
+		} else if cur != nil {
+			if last := cur.Points[len(cur.Points)-1]; math.Hypot(c.X-last.X, c.Y-last.Y) > 3*step {
+				flush(s.loop)
+			}
+		}
+		if cur == nil {
+			src := paths[loops[s.loop]]
+			cur = &Path{Stroke: src.Stroke, Transform: src.Transform, Index: src.Index, Label: src.Label}
+			pieces++
+		}
+		cur.Points = append(cur.Points, c)
+		cur.Depths = append(cur.Depths, z)
+
+		if k == len(samples)-1 || samples[k+1].loop != s.loop {
+			// close the walk when the loop went around without a jump
+			if last := cur.Points[len(cur.Points)-1]; pieces == 1 && math.Hypot(first.X-last.X, first.Y-last.Y) <= 3*step {
+				cur.Points = append(cur.Points, first)
+				cur.Depths = append(cur.Depths, cur.Depths[0])
+			}
+			flush(s.loop)
+		}
+	}
+
+	out := make([]Path, 0, len(paths))
+	li := 0
+	for i, p := range paths {
+		if li < len(loops) && loops[li] == i {
+			out = append(out, carved[li]...)
+			li++
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// planVCarve cuts a variable-depth path in a single pass.
+func planVCarve(prog *Program, p Path, cfg Config) {
+	prog.op = "vcarve"
+	prog.Comment(cfg.Msg.T("vcarve", cfg.VBitAngle))
+	hop(prog, p.Points[0].X, p.Points[0].Y, cfg)
+	prog.FeedZ(p.Depths[0], cfg.PlungeFeed)
+	for i := 1; i < len(p.Points); i++ {
+		prog.FeedXYZ(p.Points[i].X, p.Points[i].Y, p.Depths[i], cfg.CutFeed)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// runVerifier pipes program into the shell command cmdline. The checker's
+// stdout and stderr both go to our stderr so they never mix with G-code on
+// stdout. It returns the checker's exit code (0 = accepted).
+func runVerifier(cmdline string, program []byte) (int, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", cmdline)
+	} else {
+		cmd = exec.Command("sh", "-c", cmdline)
+	}
+	cmd.Stdin = bytes.NewReader(program)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("run verifier: %w", err)
+	}
+	return 0, nil
+}
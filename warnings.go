@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Warning codes are stable: automation may match on them, so never
+// renumber or reuse one. Add new codes at the end of their block.
+const (
+	// input / parsing
+	WUnsupportedCommand = "W001"
+	WNoGeometry         = "W002"
+	WNoViewBox          = "W003"
+	WUnknownHeight      = "W004"
+	WIgnoredOption      = "W005"
+	WNoFont             = "W006"
+	WUnknownReference   = "W007"
+	WSelfIntersecting   = "W008"
+	WConstruction       = "W009"
+
+	// compensation
+	WSkewedTransform = "W010"
+	WCompCollapsed   = "W014"
+	WHoleTooSmall    = "W015"
+	WFeatureTooSmall = "W016"
+
+	// machine
+	WOutOfEnvelope = "W020"
+	WOutOfStock    = "W021"
+)
+
+var warningNames = map[string]string{
+	WUnsupportedCommand: "unsupported-command",
+	WNoGeometry:         "no-geometry",
+	WNoViewBox:          "no-viewbox",
+	WUnknownHeight:      "unknown-height",
+	WIgnoredOption:      "ignored-option",
+	WNoFont:             "no-font",
+	WUnknownReference:   "unknown-reference",
+	WSelfIntersecting:   "self-intersecting",
+	WConstruction:       "construction-skipped",
+	WSkewedTransform:    "skewed-transform",
+	WCompCollapsed:      "comp-collapsed",
+	WHoleTooSmall:       "hole-too-small",
+	WFeatureTooSmall:    "feature-too-small",
+	WOutOfEnvelope:      "out-of-envelope",
+	WOutOfStock:         "out-of-stock",
+}
+
+// Warning is a non-fatal problem found while converting.
+type Warning struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Path    int    `json:"path,omitempty"` // 1-based path index, 0 = whole job
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Path > 0 {
+		return fmt.Sprintf("%s %s: path %d: %s", w.Code, w.Name, w.Path, w.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", w.Code, w.Name, w.Message)
+}
+
+// Warnings collects warnings for one conversion. Each warning is echoed
+// to Out (stderr by default) as it is added. A nil *Warnings discards.
+type Warnings struct {
+	List []Warning
+	Out  io.Writer
+}
+
+func (ws *Warnings) Add(code string, path int, format string, args ...any) {
+	if ws == nil {
+		return
+	}
+	w := Warning{
+		Code:    code,
+		Name:    warningNames[code],
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+	ws.List = append(ws.List, w)
+
+	out := ws.Out
+	if out == nil {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "warning %s\n", w)
+}